@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// buildTypes are the build sections versa init can generate, in config order
+var buildTypes = []string{"php", "go", "frontend", "python"}
+
+// stackTemplate pre-fills deploy.yml with a stack's conventional settings
+type stackTemplate struct {
+	Builds     []string
+	Roots      map[string]string // Build type → root (subdirectory holding its manifest)
+	Ignored    []string
+	Shared     []string
+	RouteFiles []string
+	PostDeploy []string
+}
+
+// stackTemplates are the stacks available to versa init --template
+var stackTemplates = map[string]stackTemplate{
+	"laravel": {
+		Builds:     []string{"php", "frontend"},
+		Roots:      map[string]string{"php": ".", "frontend": "."},
+		Ignored:    []string{".git", "tests", "node_modules", "storage/logs", "storage/framework/cache"},
+		Shared:     []string{".env", "storage"},
+		RouteFiles: []string{"routes/web.php", "routes/api.php"},
+		PostDeploy: []string{"php artisan migrate --force", "php artisan config:cache", "php artisan route:cache", "php artisan view:cache"},
+	},
+	"symfony": {
+		Builds:     []string{"php"},
+		Roots:      map[string]string{"php": "."},
+		Ignored:    []string{".git", "tests", "var/cache", "var/log"},
+		Shared:     []string{".env.local", "var/log"},
+		RouteFiles: []string{"config/routes.yaml"},
+		PostDeploy: []string{"php bin/console doctrine:migrations:migrate --no-interaction", "php bin/console cache:clear --env=prod"},
+	},
+	"go-api": {
+		Builds:  []string{"go"},
+		Roots:   map[string]string{"go": "."},
+		Ignored: []string{".git", "testdata"},
+		Shared:  []string{".env"},
+	},
+	"node": {
+		Builds:  []string{"frontend"},
+		Roots:   map[string]string{"frontend": "."},
+		Ignored: []string{".git", "tests", "node_modules"},
+		Shared:  []string{".env"},
+	},
+}
+
+// templateNames lists the available stack templates, sorted
+func templateNames() []string {
+	names := make([]string, 0, len(stackTemplates))
+	for name := range stackTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupTemplate returns the named stack template, listing the available ones
+// when the name is unknown
+func lookupTemplate(name string) (stackTemplate, error) {
+	tmpl, ok := stackTemplates[name]
+	if !ok {
+		return tmpl, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(templateNames(), ", "))
+	}
+	return tmpl, nil
+}
+
+// initOptions holds the answers used to generate a tailored deploy.yml
+type initOptions struct {
+	Project    string
+	Env        string
+	Host       string
+	User       string
+	KeyPath    string
+	Port       int
+	RemotePath string
+	Builds     []string      // Subset of buildTypes to enable
+	Stack      stackTemplate // Stack conventions (ignored/shared paths, route files, hooks); zero for generic
+}
+
+// defaultInitOptions returns placeholder settings for a non-interactive init
+func defaultInitOptions(project string, stack stackTemplate) initOptions {
+	return initOptions{
+		Project:    project,
+		Env:        "production",
+		Host:       "server.example.com",
+		User:       "deploy",
+		KeyPath:    "~/.ssh/id_rsa",
+		Port:       22,
+		RemotePath: "/var/www/" + project,
+		Builds:     stack.Builds,
+		Stack:      stack,
+	}
+}
+
+// prompter asks questions on out and reads the answers line by line from in
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints the question with its default in brackets and returns the answer,
+// or the default when the answer is empty. Questions without a default are
+// repeated until answered.
+func (p *prompter) ask(question, def string) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+
+		line, err := p.in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+		if answer != "" {
+			return answer, nil
+		}
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("%s is required", strings.ToLower(question))
+		}
+	}
+}
+
+// askInitOptions runs the init wizard. Invalid answers (port, build types) are
+// reported and asked again. A stack template sets the default build types.
+func askInitOptions(in io.Reader, out io.Writer, projectDefault string, stack stackTemplate) (initOptions, error) {
+	p := &prompter{in: bufio.NewReader(in), out: out}
+	opts := initOptions{Stack: stack}
+	var err error
+
+	buildsDefault := "php"
+	if len(stack.Builds) > 0 {
+		buildsDefault = strings.Join(stack.Builds, ",")
+	}
+
+	if opts.Project, err = p.ask("Project name", projectDefault); err != nil {
+		return opts, err
+	}
+	if opts.Env, err = p.ask("Environment name", "production"); err != nil {
+		return opts, err
+	}
+	if opts.Host, err = p.ask("SSH host", ""); err != nil {
+		return opts, err
+	}
+	if opts.User, err = p.ask("SSH user", "deploy"); err != nil {
+		return opts, err
+	}
+	if opts.KeyPath, err = p.ask("SSH key path", "~/.ssh/id_rsa"); err != nil {
+		return opts, err
+	}
+	for {
+		answer, err := p.ask("SSH port", "22")
+		if err != nil {
+			return opts, err
+		}
+		if opts.Port, err = strconv.Atoi(answer); err == nil && opts.Port > 0 && opts.Port <= 65535 {
+			break
+		}
+		fmt.Fprintf(out, "  %q is not a valid port\n", answer)
+	}
+	if opts.RemotePath, err = p.ask("Remote path", "/var/www/"+opts.Project); err != nil {
+		return opts, err
+	}
+	for {
+		answer, err := p.ask("Build types to enable ("+strings.Join(buildTypes, ", ")+")", buildsDefault)
+		if err != nil {
+			return opts, err
+		}
+		if opts.Builds, err = parseBuildTypes(answer); err == nil {
+			break
+		}
+		fmt.Fprintf(out, "  %v\n", err)
+	}
+	return opts, nil
+}
+
+// parseBuildTypes splits a comma/space separated list of build types, rejecting
+// unknown ones and dropping duplicates
+func parseBuildTypes(answer string) ([]string, error) {
+	var builds []string
+	seen := make(map[string]bool)
+	for _, b := range strings.FieldsFunc(strings.ToLower(answer), func(r rune) bool { return r == ',' || r == ' ' }) {
+		known := false
+		for _, t := range buildTypes {
+			known = known || b == t
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown build type %q (use %s)", b, strings.Join(buildTypes, ", "))
+		}
+		if !seen[b] {
+			seen[b] = true
+			builds = append(builds, b)
+		}
+	}
+	if len(builds) == 0 {
+		return nil, fmt.Errorf("enable at least one build type (%s)", strings.Join(buildTypes, ", "))
+	}
+	return builds, nil
+}
+
+// yamlList renders items as an indented YAML list under key, or an empty list
+func yamlList(sb *strings.Builder, indent, key string, items []string) {
+	if len(items) == 0 {
+		fmt.Fprintf(sb, "%s%s: []\n", indent, key)
+		return
+	}
+	fmt.Fprintf(sb, "%s%s:\n", indent, key)
+	for _, item := range items {
+		fmt.Fprintf(sb, "%s  - %q\n", indent, item)
+	}
+}
+
+// renderInitConfig generates a deploy.yml containing only the enabled build sections
+func renderInitConfig(opts initOptions) string {
+	enabled := make(map[string]bool)
+	for _, b := range opts.Builds {
+		enabled[b] = true
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "project: %q\n\nenvironments:\n  %s:\n", opts.Project, opts.Env)
+	fmt.Fprintf(&sb, "    ssh:\n      host: %q\n      user: %q\n      key_path: %q\n      port: %d\n", opts.Host, opts.User, opts.KeyPath, opts.Port)
+	fmt.Fprintf(&sb, "\n    remote_path: %q\n", opts.RemotePath)
+	ignored, shared := opts.Stack.Ignored, opts.Stack.Shared
+	if len(ignored) == 0 {
+		ignored = []string{".git", "tests"}
+	}
+	if len(shared) == 0 {
+		shared = []string{".env"}
+	}
+	sb.WriteString("\n    # Paths to ignore for SHA256 tracking\n")
+	yamlList(&sb, "    ", "ignored_paths", ignored)
+	sb.WriteString("\n    # Paths that persist between releases (symlinked into each release)\n")
+	yamlList(&sb, "    ", "shared_paths", shared)
+	if len(opts.Stack.RouteFiles) > 0 {
+		sb.WriteString("\n    # Files that trigger route cache regeneration\n")
+		yamlList(&sb, "    ", "route_files", opts.Stack.RouteFiles)
+	}
+	writeRoot := func(build string) {
+		if root, ok := opts.Stack.Roots[build]; ok {
+			fmt.Fprintf(&sb, "        root: %q\n", root)
+		}
+	}
+	sb.WriteString("\n    builds:\n")
+	if enabled["php"] {
+		sb.WriteString("      php:\n        enabled: true\n")
+		writeRoot("php")
+		sb.WriteString(`        composer_command: "composer install --no-dev --optimize-autoloader"
+`)
+	}
+	if enabled["go"] {
+		sb.WriteString("      go:\n        enabled: true\n")
+		writeRoot("go")
+		sb.WriteString(`        deploy_path: "bin"
+        target_os: "linux"
+        target_arch: "amd64"
+        binary_name: "app"
+`)
+	}
+	if enabled["frontend"] {
+		sb.WriteString("      frontend:\n        enabled: true\n")
+		writeRoot("frontend")
+		sb.WriteString(`        npm_command: "npm ci"
+        compile_command: "npm run build"
+`)
+	}
+	if enabled["python"] {
+		sb.WriteString("      python:\n        enabled: true\n")
+		writeRoot("python")
+		sb.WriteString(`        python_command: "python3"
+        requirements_file: "requirements.txt"
+        venv_path: ".venv"
+        reusable_paths:
+          - ".venv"
+`)
+	}
+	sb.WriteString("\n    # Hooks to run on remote server after symlink switch (rollback on failure)\n")
+	yamlList(&sb, "    ", "post_deploy", opts.Stack.PostDeploy)
+	return sb.String()
+}
+
+// runTailoredInit writes a deploy.yml for the given stack template, asking for the
+// settings on stdin when interactive and using placeholders otherwise
+func runTailoredInit(templateName string, interactive bool) error {
+	var stack stackTemplate
+	if templateName != "" {
+		var err error
+		if stack, err = lookupTemplate(templateName); err != nil {
+			return err
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+
+	opts := defaultInitOptions(filepath.Base(wd), stack)
+	if interactive {
+		if opts, err = askInitOptions(os.Stdin, os.Stdout, filepath.Base(wd), stack); err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(configPath, []byte(renderInitConfig(opts)), 0644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", configPath, err)
+	}
+
+	fmt.Printf("\n🚀 Initialized versaDeploy! Created %s.\n", configPath)
+	if interactive {
+		fmt.Printf("Review %s and then run: versa deploy %s --initial-deploy\n", configPath, opts.Env)
+	} else {
+		fmt.Printf("Edit %s to match your server details and then run: versa deploy %s --initial-deploy\n", configPath, opts.Env)
+	}
+	return nil
+}
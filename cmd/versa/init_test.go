@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/user/versaDeploy/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAskInitOptions(t *testing.T) {
+	// Defaults everywhere except the required host; the invalid port and build
+	// type are asked again
+	input := strings.Join([]string{"", "staging", "", "example.com", "", "", "abc", "2222", "", "php, vue", "php,frontend"}, "\n") + "\n"
+	var out bytes.Buffer
+
+	opts, err := askInitOptions(strings.NewReader(input), &out, "shop", stackTemplate{})
+	if err != nil {
+		t.Fatalf("wizard failed: %v", err)
+	}
+
+	want := initOptions{
+		Project: "shop", Env: "staging", Host: "example.com", User: "deploy", KeyPath: "~/.ssh/id_rsa",
+		Port: 2222, RemotePath: "/var/www/shop", Builds: []string{"php", "frontend"},
+	}
+	if opts.Project != want.Project || opts.Env != want.Env || opts.Host != want.Host || opts.User != want.User ||
+		opts.KeyPath != want.KeyPath || opts.Port != want.Port || opts.RemotePath != want.RemotePath ||
+		strings.Join(opts.Builds, ",") != strings.Join(want.Builds, ",") {
+		t.Errorf("unexpected options:\n got %+v\nwant %+v", opts, want)
+	}
+	if !strings.Contains(out.String(), "Environment name [production]: ") {
+		t.Errorf("expected defaults in brackets, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), `unknown build type "vue"`) {
+		t.Errorf("expected unknown build type to be reported, got %q", out.String())
+	}
+}
+
+func TestAskInitOptions_RequiredAtEOF(t *testing.T) {
+	if _, err := askInitOptions(strings.NewReader("\n\n"), &bytes.Buffer{}, "shop", stackTemplate{}); err == nil {
+		t.Error("expected an error when the SSH host is never answered")
+	}
+}
+
+func TestRenderInitConfig(t *testing.T) {
+	content := renderInitConfig(initOptions{
+		Project: "shop", Env: "staging", Host: "example.com", User: "deploy", KeyPath: "~/.ssh/id_rsa",
+		Port: 22, RemotePath: "/var/www/shop", Builds: []string{"go", "python"},
+	})
+
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+		t.Fatalf("generated config is not valid YAML: %v\n%s", err, content)
+	}
+	env, ok := cfg.Environments["staging"]
+	if !ok {
+		t.Fatalf("expected environment staging, got %+v", cfg.Environments)
+	}
+	if cfg.Project != "shop" || env.SSH.Host != "example.com" || env.RemotePath != "/var/www/shop" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if !env.Builds.Go.Enabled || !env.Builds.Python.Enabled || env.Builds.PHP.Enabled || env.Builds.Frontend.Enabled {
+		t.Errorf("unexpected builds: %+v", env.Builds)
+	}
+	if strings.Contains(content, "php:") || strings.Contains(content, "frontend:") {
+		t.Errorf("expected only the enabled build sections, got:\n%s", content)
+	}
+}
+
+func TestLookupTemplate(t *testing.T) {
+	for _, name := range []string{"laravel", "symfony", "go-api", "node"} {
+		if _, err := lookupTemplate(name); err != nil {
+			t.Errorf("template %s: %v", name, err)
+		}
+	}
+
+	_, err := lookupTemplate("rails")
+	if err == nil || !strings.Contains(err.Error(), "go-api, laravel, node, symfony") {
+		t.Errorf("expected unknown template error listing the templates, got %v", err)
+	}
+}
+
+func TestRenderInitConfig_Templates(t *testing.T) {
+	for _, name := range templateNames() {
+		stack, _ := lookupTemplate(name)
+		content := renderInitConfig(defaultInitOptions("shop", stack))
+
+		var cfg config.Config
+		if err := yaml.Unmarshal([]byte(content), &cfg); err != nil {
+			t.Fatalf("%s: generated config is not valid YAML: %v\n%s", name, err, content)
+		}
+		env := cfg.Environments["production"]
+		if strings.Join(env.Ignored, ",") != strings.Join(stack.Ignored, ",") ||
+			strings.Join(env.SharedPaths, ",") != strings.Join(stack.Shared, ",") ||
+			strings.Join(env.RouteFiles, ",") != strings.Join(stack.RouteFiles, ",") ||
+			len(env.PostDeploy) != len(stack.PostDeploy) {
+			t.Errorf("%s: config doesn't match the template:\n%s", name, content)
+		}
+		roots := map[string]string{"php": env.Builds.PHP.ProjectRoot, "go": env.Builds.Go.ProjectRoot,
+			"frontend": env.Builds.Frontend.ProjectRoot, "python": env.Builds.Python.ProjectRoot}
+		for build, root := range stack.Roots {
+			if roots[build] != root {
+				t.Errorf("%s: expected %s root %q, got %q", name, build, root, roots[build])
+			}
+		}
+	}
+
+	laravel, _ := lookupTemplate("laravel")
+	var cfg config.Config
+	yaml.Unmarshal([]byte(renderInitConfig(defaultInitOptions("shop", laravel))), &cfg)
+	env := cfg.Environments["production"]
+	if !env.Builds.PHP.Enabled || !env.Builds.Frontend.Enabled || env.Builds.Go.Enabled {
+		t.Errorf("unexpected laravel builds: %+v", env.Builds)
+	}
+	if env.PostDeploy[0].Command != "php artisan migrate --force" {
+		t.Errorf("expected artisan hooks, got %+v", env.PostDeploy)
+	}
+}
+
+func TestAskInitOptions_TemplateDefaults(t *testing.T) {
+	stack, _ := lookupTemplate("laravel")
+	input := strings.Repeat("\n", 2) + "example.com\n" + strings.Repeat("\n", 5)
+	opts, err := askInitOptions(strings.NewReader(input), &bytes.Buffer{}, "shop", stack)
+	if err != nil {
+		t.Fatalf("wizard failed: %v", err)
+	}
+	if strings.Join(opts.Builds, ",") != "php,frontend" || len(opts.Stack.PostDeploy) == 0 {
+		t.Errorf("expected laravel defaults, got %+v", opts)
+	}
+}
@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/user/versaDeploy/internal/changeset"
 	"github.com/user/versaDeploy/internal/config"
 	"github.com/user/versaDeploy/internal/deployer"
 	verserrors "github.com/user/versaDeploy/internal/errors"
+	"github.com/user/versaDeploy/internal/git"
 	"github.com/user/versaDeploy/internal/logger"
 	"github.com/user/versaDeploy/internal/selfupdate"
 	"github.com/user/versaDeploy/internal/ssh"
@@ -25,6 +34,14 @@ var (
 	logFile    string
 	guiMode    bool
 	noGUI      bool
+
+	// repoRoot is set when the config was discovered in a parent directory,
+	// in which case that directory is treated as the repository root.
+	repoRoot string
+
+	// repoPathFlag is --repo-path; it takes precedence over repoRoot and the
+	// working directory.
+	repoPathFlag string
 )
 
 var rootCmd = &cobra.Command{
@@ -41,9 +58,9 @@ var rootCmd = &cobra.Command{
 			return cmd.Help()
 		}
 
-		repoPath, err := os.Getwd()
+		repoPath, err := getRepoPath()
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+			return err
 		}
 
 		var cfg *config.Config
@@ -65,8 +82,19 @@ var rootCmd = &cobra.Command{
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show application version",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("versaDeploy %s\n", version.Version)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		info := version.Get()
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			data, err := json.MarshalIndent(info, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		fmt.Printf("versaDeploy %s\n", info.Version)
+		fmt.Printf("  commit: %s\n  built:  %s\n  go:     %s %s/%s\n", info.Commit, info.Date, info.GoVersion, info.OS, info.Arch)
+		return nil
 	},
 }
 
@@ -95,6 +123,25 @@ var deployCmd = &cobra.Command{
 		initialDeploy, _ := cmd.Flags().GetBool("initial-deploy")
 		force, _ := cmd.Flags().GetBool("force")
 		skipDirtyCheck, _ := cmd.Flags().GetBool("skip-dirty-check")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		commit, _ := cmd.Flags().GetString("commit")
+		overrideWindow, _ := cmd.Flags().GetBool("override-window")
+		strictSize, _ := cmd.Flags().GetBool("strict-size")
+		skipDiskCheck, _ := cmd.Flags().GetBool("skip-disk-check")
+		trace, _ := cmd.Flags().GetBool("trace")
+		buildJobs, _ := cmd.Flags().GetInt("build-jobs")
+		allowDirty, _ := cmd.Flags().GetBool("allow-dirty")
+		shallowClone, _ := cmd.Flags().GetBool("shallow-clone")
+		remotePath, _ := cmd.Flags().GetString("remote-path")
+		message, _ := cmd.Flags().GetString("message")
+		checkRemote, _ := cmd.Flags().GetBool("check-remote")
+		if checkRemote && !dryRun {
+			return fmt.Errorf("--check-remote requires --dry-run")
+		}
+		tempDir, err := tempDirFlag(cmd)
+		if err != nil {
+			return err
+		}
 
 		// Initialize logger
 		log, err := logger.NewLogger(logFile, verbose, debug)
@@ -116,10 +163,10 @@ var deployCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		// Get current working directory as repository path
-		repoPath, err := os.Getwd()
+		// Get repository path (cwd, or the directory of an auto-discovered config)
+		repoPath, err := getRepoPath()
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+			return err
 		}
 
 		// Create deployer
@@ -127,6 +174,23 @@ var deployCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		d.SetConcurrency(concurrency)
+		if err := d.SetCommit(commit); err != nil {
+			return err
+		}
+		if err := d.SetRemotePath(remotePath); err != nil {
+			return err
+		}
+		d.OverrideWindow = overrideWindow
+		d.StrictSize = strictSize
+		d.SkipDiskCheck = skipDiskCheck
+		d.Trace = trace
+		d.TempDir = tempDir
+		d.BuildJobs = buildJobs
+		d.AllowDirty = allowDirty
+		d.ShallowClone = shallowClone
+		d.Message = message
+		d.CheckRemote = checkRemote
 
 		// On initial deploy, confirm before running post_deploy hooks
 		if initialDeploy {
@@ -142,18 +206,104 @@ var deployCmd = &cobra.Command{
 			}
 		}
 
+		d.SameCommitConfirm = func() bool {
+			fmt.Print("     Deploy the same commit again? [y/N]: ")
+			var answer string
+			fmt.Scanln(&answer)
+			return strings.ToLower(strings.TrimSpace(answer)) == "y"
+		}
+
 		// Execute deployment
 		return d.Deploy()
 	},
 }
 
+var deployAllCmd = &cobra.Command{
+	Use:   "deploy-all [environments...]",
+	Short: "Deploy to several environments concurrently",
+	Long:  "Run a full deploy to every given environment at the same time, each with its own log prefix, and print a summary. Example: versa deploy-all staging canary",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		force, _ := cmd.Flags().GetBool("force")
+		skipDirtyCheck, _ := cmd.Flags().GetBool("skip-dirty-check")
+		failFast, _ := cmd.Flags().GetBool("fail-fast")
+		message, _ := cmd.Flags().GetString("message")
+		tempDir, err := tempDirFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		log, err := logger.NewLogger(logFile, verbose, debug)
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer log.Close()
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		repoPath, err := getRepoPath()
+		if err != nil {
+			return err
+		}
+
+		// Create every deployer up front so an unknown environment fails before anything starts
+		deployers := make(map[string]*deployer.Deployer)
+		for _, env := range args {
+			if _, dup := deployers[env]; dup {
+				return fmt.Errorf("environment %s given more than once", env)
+			}
+			d, err := deployer.NewDeployer(cfg, env, repoPath, dryRun, false, force, skipDirtyCheck, log.WithPrefix(env))
+			if err != nil {
+				return err
+			}
+			d.TempDir = tempDir
+			d.Message = message
+			deployers[env] = d
+		}
+
+		results := deployer.RunConcurrent(args, failFast, func(ctx context.Context, env string) error {
+			d := deployers[env]
+			d.SetContext(ctx)
+			return d.Deploy()
+		})
+
+		fmt.Println()
+		fmt.Println("Deployment summary:")
+		failed := 0
+		for _, r := range results {
+			if r.Err != nil {
+				failed++
+				fmt.Printf("  ✗ %-20s %8s  %v\n", r.Env, r.Duration.Round(time.Second), r.Err)
+			} else {
+				fmt.Printf("  ✓ %-20s %8s\n", r.Env, r.Duration.Round(time.Second))
+			}
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d environment(s) failed to deploy", failed, len(results))
+		}
+		return nil
+	},
+}
+
 var rollbackCmd = &cobra.Command{
 	Use:   "rollback [environment]",
-	Short: "Rollback to previous release (or specific version with --to)",
+	Short: "Rollback one release back from current (or to a specific version with --to)",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		env := args[0]
 		targetVersion, _ := cmd.Flags().GetString("to")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 		// Initialize logger
 		log, err := logger.NewLogger(logFile, verbose, debug)
@@ -175,14 +325,14 @@ var rollbackCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		// Get current working directory
-		repoPath, err := os.Getwd()
+		// Get repository path
+		repoPath, err := getRepoPath()
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+			return err
 		}
 
 		// Create deployer
-		d, err := deployer.NewDeployer(cfg, env, repoPath, false, false, false, false, log)
+		d, err := deployer.NewDeployer(cfg, env, repoPath, dryRun, false, false, false, log)
 		if err != nil {
 			return err
 		}
@@ -222,10 +372,10 @@ var statusCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		// Get current working directory
-		repoPath, err := os.Getwd()
+		// Get repository path
+		repoPath, err := getRepoPath()
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+			return err
 		}
 
 		// Create deployer
@@ -308,6 +458,63 @@ var sshTestCmd = &cobra.Command{
 	},
 }
 
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [environment]",
+	Short: "Diagnose common setup problems for an environment",
+	Long:  "Check config validity, SSH key, local build tools, SSH/SFTP access, remote disk space and remote tools (tar, git).",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := args[0]
+
+		log, err := logger.NewLogger(logFile, verbose, debug)
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer log.Close()
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		fmt.Printf("🩺 Running diagnostics for %s...\n\n", env)
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			fmt.Printf("❌ Config: %v\n", err)
+			return fmt.Errorf("config is invalid")
+		}
+		fmt.Printf("✅ Config: %s\n", configPath)
+
+		repoPath, err := getRepoPath()
+		if err != nil {
+			return err
+		}
+
+		d, err := deployer.NewDeployer(cfg, env, repoPath, false, false, false, false, log)
+		if err != nil {
+			return err
+		}
+
+		failed := 0
+		for _, check := range d.Doctor() {
+			mark := "✅"
+			if !check.OK {
+				mark = "❌"
+				failed++
+			}
+			fmt.Printf("%s %s: %s\n", mark, check.Name, check.Detail)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d check(s) failed", failed)
+		}
+		fmt.Println("\n✨ All checks passed!")
+		return nil
+	},
+}
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize a new versaDeploy configuration",
@@ -316,6 +523,12 @@ var initCmd = &cobra.Command{
 			return fmt.Errorf("%s already exists", configPath)
 		}
 
+		interactive, _ := cmd.Flags().GetBool("interactive")
+		templateName, _ := cmd.Flags().GetString("template")
+		if interactive || templateName != "" {
+			return runTailoredInit(templateName, interactive)
+		}
+
 		content := `project: "my-versa-project"
 
 environments:
@@ -465,9 +678,9 @@ var execCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		repoPath, err := os.Getwd()
+		repoPath, err := getRepoPath()
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+			return err
 		}
 
 		d, err := deployer.NewDeployer(cfg, env, repoPath, false, false, false, false, log)
@@ -508,9 +721,9 @@ var hooksCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		repoPath, err := os.Getwd()
+		repoPath, err := getRepoPath()
 		if err != nil {
-			return fmt.Errorf("failed to get current directory: %w", err)
+			return err
 		}
 
 		d, err := deployer.NewDeployer(cfg, env, repoPath, false, false, false, false, log)
@@ -532,14 +745,13 @@ var hooksCmd = &cobra.Command{
 	},
 }
 
-var logsCmd = &cobra.Command{
-	Use:   "logs [environment] [path]",
-	Short: "Tail remote log files in real-time",
-	Long:  "Stream remote log files using tail -f. Default: follows the most common Laravel log. Example: versa logs production /var/log/syslog",
-	Args:  cobra.MinimumNArgs(1),
+var runHookCmd = &cobra.Command{
+	Use:   "run-hook [environment] [name]",
+	Short: "Run a named post_deploy hook on the active release",
+	Long:  "Run a single post_deploy hook, selected by its 'name', against the release 'current' points to. Example: versa run-hook production cache:clear",
+	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		env := args[0]
-		lines, _ := cmd.Flags().GetInt("lines")
+		env, name := args[0], args[1]
 
 		log, err := logger.NewLogger(logFile, verbose, debug)
 		if err != nil {
@@ -558,115 +770,901 @@ var logsCmd = &cobra.Command{
 			return fmt.Errorf("failed to load config: %w", err)
 		}
 
-		envCfg, err := cfg.GetEnvironment(env)
+		repoPath, err := getRepoPath()
 		if err != nil {
 			return err
 		}
 
-		// Determine log path
-		logPath := ""
-		if len(args) > 1 {
-			logPath = args[1]
-		} else {
-			// Default: Laravel storage/logs/laravel.log via current symlink
-			logPath = filepath.ToSlash(filepath.Join(envCfg.RemotePath, "current", "app", "storage", "logs", "laravel.log"))
-		}
-
-		tailCmd := fmt.Sprintf("tail -n %d -f %s", lines, logPath)
-
-		sshClient, err := ssh.NewClient(&envCfg.SSH, log)
+		d, err := deployer.NewDeployer(cfg, env, repoPath, false, false, false, false, log)
 		if err != nil {
 			return err
 		}
-		defer sshClient.Close()
 
-		fmt.Printf("Tailing %s (Ctrl+C to stop)...\n", logPath)
-		return sshClient.ExecuteCommandStreaming(tailCmd, os.Stdout, os.Stderr)
+		return d.RunHookByName(name)
 	},
 }
 
-func getOrSelectConfig(cmd *cobra.Command) (string, error) {
-	// If the user explicitly provided a config flag, use it
-	if cmd.Flags().Changed("config") {
-		return configPath, nil
-	}
+var pruneCmd = &cobra.Command{
+	Use:   "prune [environment]",
+	Short: "Remove old releases on demand",
+	Long:  "Delete all but the --keep newest releases without deploying. The active release is never deleted. Example: versa prune production --keep 3",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := args[0]
+		keep, _ := cmd.Flags().GetInt("keep")
 
-	// If GUI mode is enabled, we don't want to prompt in CLI.
-	// We'll let the TUI handle it later.
-	if guiMode {
-		return configPath, nil
-	}
+		log, err := logger.NewLogger(logFile, verbose, debug)
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer log.Close()
 
-	// Try to discover config files automatically
-	cwd, err := os.Getwd()
-	if err != nil {
-		return configPath, nil
-	}
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
 
-	files, err := config.FindConfigFiles(cwd)
-	if err != nil || len(files) == 0 {
-		// fallback to original default
-		return configPath, nil
-	}
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
 
-	if len(files) == 1 {
-		return files[0], nil
-	}
+		repoPath, err := getRepoPath()
+		if err != nil {
+			return err
+		}
 
-	// If there are multiple configuration files, prompt the user
-	fmt.Println("\nMultiple configuration files found. Please select one:")
-	for i, f := range files {
-		fmt.Printf("[%d] %s\n", i+1, filepath.Base(f))
-	}
-	fmt.Print("Enter number: ")
+		d, err := deployer.NewDeployer(cfg, env, repoPath, false, false, false, false, log)
+		if err != nil {
+			return err
+		}
 
-	var input string
-	_, err = fmt.Scanln(&input)
-	if err != nil {
-		// Just fallback if scanning fails
-		return configPath, nil
-	}
+		return d.Prune(keep)
+	},
+}
 
-	input = strings.TrimSpace(input)
-	idx, err := strconv.Atoi(input)
-	if err != nil || idx < 1 || idx > len(files) {
-		return "", fmt.Errorf("invalid selection")
-	}
+var unlockCmd = &cobra.Command{
+	Use:   "unlock [environment]",
+	Short: "Remove a deployment lock left behind by an interrupted deploy",
+	Long:  "Show who holds the environment's deployment lock (.versa.lock) and remove it after confirmation. Only use it when no deploy is actually running. Example: versa unlock production",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := args[0]
+		yes, _ := cmd.Flags().GetBool("yes")
 
-	fmt.Println()
-	return files[idx-1], nil
-}
+		log, err := logger.NewLogger(logFile, verbose, debug)
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer log.Close()
 
-func init() {
-	rootCmd.PersistentFlags().StringVar(&configPath, "config", "deploy.yml", "Path to configuration file")
-	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Verbose output")
-	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Debug mode")
-	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Log file path")
-	rootCmd.PersistentFlags().BoolVar(&guiMode, "gui", false, "Launch interactive TUI (default behavior; kept for backward compat)")
-	rootCmd.PersistentFlags().BoolVar(&noGUI, "no-gui", false, "Disable TUI and show help")
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
 
-	deployCmd.Flags().Bool("dry-run", false, "Show changes without deploying")
-	deployCmd.Flags().Bool("initial-deploy", false, "Flag for first deployment")
-	deployCmd.Flags().Bool("force", false, "Force redeploy even if no changes detected")
-	deployCmd.Flags().Bool("skip-dirty-check", false, "Skip validation of uncommitted changes")
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
 
-	rollbackCmd.Flags().String("to", "", "Rollback to a specific release version (e.g. 20240101_120000)")
+		repoPath, err := getRepoPath()
+		if err != nil {
+			return err
+		}
 
-	logsCmd.Flags().Int("lines", 50, "Number of initial lines to show before following")
+		d, err := deployer.NewDeployer(cfg, env, repoPath, false, false, false, false, log)
+		if err != nil {
+			return err
+		}
 
-	rootCmd.AddCommand(deployCmd)
-	rootCmd.AddCommand(rollbackCmd)
-	rootCmd.AddCommand(statusCmd)
-	rootCmd.AddCommand(sshTestCmd)
-	rootCmd.AddCommand(initCmd)
-	rootCmd.AddCommand(versionCmd)
-	rootCmd.AddCommand(selfUpdateCmd)
-	rootCmd.AddCommand(execCmd)
-	rootCmd.AddCommand(hooksCmd)
-	rootCmd.AddCommand(logsCmd)
+		var confirm func() bool
+		if !yes {
+			confirm = func() bool {
+				fmt.Println()
+				fmt.Println("  ⚠  Removing the lock while that deploy is still running lets a second")
+				fmt.Println("     deploy start alongside it.")
+				fmt.Print("     Remove the deployment lock? [y/N]: ")
+				var answer string
+				fmt.Scanln(&answer)
+				return strings.ToLower(strings.TrimSpace(answer)) == "y"
+			}
+		}
+		return d.Unlock(confirm)
+	},
 }
 
-func main() {
+var promoteCmd = &cobra.Command{
+	Use:   "promote [from] [to]",
+	Short: "Copy a release from one environment to another and activate it",
+	Long:  "Copy an existing release (the source's current one, or --release) from one environment's server to another's through this machine, then activate it there with the usual shared paths, hooks and health check. Nothing is rebuilt, so both environments run the same artifact. Example: versa promote staging production --release 20260130_100000",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		from, to := args[0], args[1]
+		release, _ := cmd.Flags().GetString("release")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		force, _ := cmd.Flags().GetBool("force")
+		if from == to {
+			return fmt.Errorf("cannot promote %s to itself", from)
+		}
+
+		log, err := logger.NewLogger(logFile, verbose, debug)
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer log.Close()
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		repoPath, err := getRepoPath()
+		if err != nil {
+			return err
+		}
+
+		source, err := deployer.NewDeployer(cfg, from, repoPath, false, false, false, false, log.WithPrefix(from))
+		if err != nil {
+			return err
+		}
+		d, err := deployer.NewDeployer(cfg, to, repoPath, dryRun, false, force, false, log)
+		if err != nil {
+			return err
+		}
+
+		return d.Promote(source, release)
+	},
+}
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [environment] [from-release] [to-release]",
+	Short: "Show what changed between two releases",
+	Long:  "Download the manifests of two releases on the server and print the delta: commit range, files changed per category, which dependencies were installed and, when both releases carry a file list, the added, removed and modified files. Example: versa compare production 20260129_100000 20260130_100000",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env, from, to := args[0], args[1], args[2]
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		// With --json, stdout carries only the JSON document; progress goes to stderr
+		var log *logger.Logger
+		if asJSON {
+			log = logger.NewTUILogger(os.Stderr, verbose, debug)
+		} else {
+			var err error
+			if log, err = logger.NewLogger(logFile, verbose, debug); err != nil {
+				return fmt.Errorf("failed to initialize logger: %w", err)
+			}
+			defer log.Close()
+		}
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		repoPath, err := getRepoPath()
+		if err != nil {
+			return err
+		}
+
+		d, err := deployer.NewDeployer(cfg, env, repoPath, true, false, false, false, log)
+		if err != nil {
+			return err
+		}
+
+		cmp, err := d.CompareReleases(from, to)
+		if err != nil {
+			return err
+		}
+
+		if asJSON {
+			data, err := json.MarshalIndent(cmp, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		printReleaseComparison(cmp)
+		return nil
+	},
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [environment]",
+	Short: "Show what the next deploy would change",
+	Long:  "Compare HEAD against the deploy.lock on the server and list the changed files by category plus which dependencies would be reinstalled. Nothing is built or uploaded. Example: versa diff production --json",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := args[0]
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		// With --json, stdout carries only the JSON document; progress goes to stderr
+		var log *logger.Logger
+		if asJSON {
+			log = logger.NewTUILogger(os.Stderr, verbose, debug)
+		} else {
+			var err error
+			if log, err = logger.NewLogger(logFile, verbose, debug); err != nil {
+				return fmt.Errorf("failed to initialize logger: %w", err)
+			}
+			defer log.Close()
+		}
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		repoPath, err := getRepoPath()
+		if err != nil {
+			return err
+		}
+
+		d, err := deployer.NewDeployer(cfg, env, repoPath, true, false, false, false, log)
+		if err != nil {
+			return err
+		}
+
+		cs, err := d.Diff()
+		if err != nil {
+			return err
+		}
+
+		if asJSON {
+			data, err := json.MarshalIndent(cs, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		printChangeSet(cs)
+		return nil
+	},
+}
+
+// printReleaseComparison prints the commits, applied changes and file differences
+// between two releases
+func printReleaseComparison(cmp *deployer.ReleaseComparison) {
+	from, to := cmp.FromManifest, cmp.ToManifest
+	fmt.Printf("%s → %s\n", cmp.From, cmp.To)
+	commits := fmt.Sprintf("Commits: %s..%s", deployer.ShortHash(from.CommitHash), deployer.ShortHash(to.CommitHash))
+	switch {
+	case from.CommitHash != "" && from.CommitHash == to.CommitHash:
+		commits += " (same commit)"
+	case cmp.Commits >= 0:
+		commits += fmt.Sprintf(" (%d commits)", cmp.Commits)
+	}
+	fmt.Println(commits)
+	if to.Message != "" {
+		fmt.Printf("Message: %s\n", to.Message)
+	}
+
+	// The manifest counts are what each release itself changed relative to its predecessor
+	c := to.ChangesApplied
+	fmt.Printf("%s applied: %d PHP file(s), %d frontend file(s) compiled\n", cmp.To, c.PHPFilesChanged, c.FrontendCompiled)
+	var steps []string
+	for _, step := range []struct {
+		name string
+		ran  bool
+	}{
+		{"composer install", c.ComposerUpdated},
+		{"npm install", c.NPMUpdated},
+		{"go build", c.GoBinaryRebuilt},
+		{"twig cache cleanup", c.TwigCacheCleanup},
+		{"route cache", c.RouteCacheRegenerate},
+	} {
+		if step.ran {
+			steps = append(steps, step.name)
+		}
+	}
+	if len(steps) > 0 {
+		fmt.Printf("Ran: %s\n", strings.Join(steps, ", "))
+	}
+
+	if cmp.FileSource == "" {
+		fmt.Println("No file lists in both releases (set verify_files to ship one); only manifests compared.")
+		return
+	}
+	if len(cmp.Added)+len(cmp.Removed)+len(cmp.Modified) == 0 {
+		fmt.Printf("No file differences (from %s).\n", cmp.FileSource)
+		return
+	}
+	fmt.Printf("File differences (from %s):\n", cmp.FileSource)
+	for _, group := range []struct {
+		name  string
+		files []string
+	}{
+		{"Added", cmp.Added},
+		{"Removed", cmp.Removed},
+		{"Modified", cmp.Modified},
+	} {
+		if len(group.files) == 0 {
+			continue
+		}
+		fmt.Printf("%s (%d):\n", group.name, len(group.files))
+		for _, f := range group.files {
+			fmt.Printf("  %s\n", f)
+		}
+	}
+}
+
+// printChangeSet prints the changed files by category and the dependency changes
+func printChangeSet(cs *changeset.ChangeSet) {
+	if !cs.HasChanges() {
+		fmt.Println("No changes: the server already runs this commit's files.")
+		return
+	}
+
+	for _, group := range []struct {
+		name  string
+		files []string
+	}{
+		{"PHP", cs.PHPFiles},
+		{"Twig", cs.TwigFiles},
+		{"Go", cs.GoFiles},
+		{"Frontend", cs.FrontendFiles},
+		{"Python", cs.PythonFiles},
+		{"Other", cs.OtherFiles},
+		{"Deleted", cs.DeletedFiles},
+	} {
+		if len(group.files) == 0 {
+			continue
+		}
+		fmt.Printf("%s files (%d):\n", group.name, len(group.files))
+		for _, f := range group.files {
+			fmt.Printf("  %s\n", f)
+		}
+	}
+
+	var deps []string
+	for _, dep := range []struct {
+		name    string
+		changed bool
+	}{
+		{"composer", cs.ComposerChanged},
+		{"npm", cs.PackageChanged},
+		{"go modules", cs.GoModChanged},
+		{"python requirements", cs.RequirementsChanged},
+	} {
+		if dep.changed {
+			deps = append(deps, dep.name)
+		}
+	}
+	if len(deps) > 0 {
+		fmt.Printf("Dependencies reinstalled: %s\n", strings.Join(deps, ", "))
+	}
+	if cs.RoutesChanged {
+		fmt.Println("Route files changed: route cache will be regenerated")
+	}
+}
+
+var testBuildCmd = &cobra.Command{
+	Use:   "test-build [environment]",
+	Short: "Build and validate the artifact locally without deploying",
+	Long:  "Run change detection as on a first deploy, build, generate and validate the manifest, and report what was built and the artifact size. Nothing connects to the server and no SSH key is needed, so it works as a CI check. pre_deploy_local hooks only run with --pre-deploy-local. The artifact is removed afterwards unless --keep is set. Example: versa test-build production --strict-size",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := args[0]
+		keep, _ := cmd.Flags().GetBool("keep")
+		localHooks, _ := cmd.Flags().GetBool("pre-deploy-local")
+		skipDirtyCheck, _ := cmd.Flags().GetBool("skip-dirty-check")
+		allowDirty, _ := cmd.Flags().GetBool("allow-dirty")
+		strictSize, _ := cmd.Flags().GetBool("strict-size")
+		buildJobs, _ := cmd.Flags().GetInt("build-jobs")
+		tempDir, err := tempDirFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		log, err := logger.NewLogger(logFile, verbose, debug)
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer log.Close()
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		cfg, err := config.LoadForBuild(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		repoPath, err := getRepoPath()
+		if err != nil {
+			return err
+		}
+
+		d, err := deployer.NewDeployer(cfg, env, repoPath, false, true, false, skipDirtyCheck, log)
+		if err != nil {
+			return err
+		}
+		d.StrictSize = strictSize
+		d.TempDir = tempDir
+		d.BuildJobs = buildJobs
+		d.AllowDirty = allowDirty
+
+		return d.TestBuild(keep, localHooks)
+	},
+}
+
+var pushSecretCmd = &cobra.Command{
+	Use:   "push-secret [environment] [file]",
+	Short: "Upload a local secret file into the remote shared/ directory",
+	Long:  "Upload a local file (e.g. .env) to <remote_path>/shared/ with 0600 permissions so secret_files can link it into every release. Example: versa push-secret production .env",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env, file := args[0], args[1]
+		force, _ := cmd.Flags().GetBool("force")
+
+		log, err := logger.NewLogger(logFile, verbose, debug)
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer log.Close()
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		repoPath, err := getRepoPath()
+		if err != nil {
+			return err
+		}
+
+		d, err := deployer.NewDeployer(cfg, env, repoPath, false, false, false, false, log)
+		if err != nil {
+			return err
+		}
+
+		return d.PushSecret(file, force)
+	},
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the deploy configuration",
+}
+
+var configDumpCmd = &cobra.Command{
+	Use:   "dump [environment]",
+	Short: "Print the effective configuration, with defaults applied and variables interpolated",
+	Long:  "Print the configuration versaDeploy will actually use: environment variables interpolated and defaults (port, ignored paths, services_action, ...) applied. Webhook URLs are redacted; key files are shown by path only.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		env := ""
+		if len(args) > 0 {
+			env = args[0]
+		}
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		out, err := cfg.Dump(env, format)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(out)
+		return err
+	},
+}
+
+var envsCmd = &cobra.Command{
+	Use:   "envs",
+	Short: "List the environments defined in the config",
+	Long:  "Print each environment with its SSH target, remote path and enabled build types. Nothing connects to the server and the SSH keys don't need to exist. Example: versa envs --json | jq -r '.[].name'",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		cfg, err := config.LoadForBuild(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		type envInfo struct {
+			Name       string   `json:"name"`
+			Host       string   `json:"host"`
+			Port       int      `json:"port"`
+			User       string   `json:"user"`
+			RemotePath string   `json:"remote_path"`
+			Builds     []string `json:"builds"`
+		}
+		names := make([]string, 0, len(cfg.Environments))
+		for name := range cfg.Environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		envs := make([]envInfo, 0, len(names))
+		for _, name := range names {
+			env := cfg.Environments[name]
+			envs = append(envs, envInfo{
+				Name:       name,
+				Host:       env.SSH.Host,
+				Port:       env.SSH.Port,
+				User:       env.SSH.User,
+				RemotePath: env.RemotePath,
+				Builds:     env.Builds.EnabledTypes(),
+			})
+		}
+
+		if asJSON {
+			data, err := json.MarshalIndent(envs, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ENVIRONMENT\tHOST\tREMOTE PATH\tBUILDS")
+		for _, e := range envs {
+			host := fmt.Sprintf("%s@%s", e.User, e.Host)
+			if e.Port != 22 {
+				host += fmt.Sprintf(":%d", e.Port)
+			}
+			builds := strings.Join(e.Builds, ", ")
+			if builds == "" {
+				builds = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Name, host, e.RemotePath, builds)
+		}
+		return w.Flush()
+	},
+}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [environment] [path]",
+	Short: "Show or follow remote log files",
+	Long:  "Print the last lines of a remote log file, or stream it with --follow (tail -f). Default: the Laravel log of the active release. Example: versa logs production --file storage/logs/app.log --follow",
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		env := args[0]
+		lines, _ := cmd.Flags().GetInt("lines")
+		file, _ := cmd.Flags().GetString("file")
+		follow, _ := cmd.Flags().GetBool("follow")
+
+		if file != "" && len(args) > 1 {
+			return fmt.Errorf("give the log path either as an argument or with --file, not both")
+		}
+
+		log, err := logger.NewLogger(logFile, verbose, debug)
+		if err != nil {
+			return fmt.Errorf("failed to initialize logger: %w", err)
+		}
+		defer log.Close()
+
+		path, err := getOrSelectConfig(cmd)
+		if err != nil {
+			return err
+		}
+		configPath = path
+
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		envCfg, err := cfg.GetEnvironment(env)
+		if err != nil {
+			return err
+		}
+
+		// Determine log path
+		logPath := ""
+		if len(args) > 1 {
+			logPath = args[1]
+		} else {
+			// Default: Laravel storage/logs/laravel.log via current symlink
+			if file == "" {
+				file = "storage/logs/laravel.log"
+			}
+			logPath = resolveReleasePath(envCfg.RemotePath, file)
+		}
+
+		// Wildcards stay unquoted so patterns like storage/logs/laravel-*.log expand remotely
+		tailCmd := fmt.Sprintf("tail -n %d %s", lines, ssh.ShellQuoteGlob(logPath))
+		if follow {
+			tailCmd = fmt.Sprintf("tail -n %d -f %s", lines, ssh.ShellQuoteGlob(logPath))
+		}
+
+		sshClient, err := ssh.NewClient(&envCfg.SSH, log)
+		if err != nil {
+			return err
+		}
+		defer sshClient.Close()
+
+		if !follow {
+			return sshClient.ExecuteCommandStreaming(tailCmd, os.Stdout, os.Stderr)
+		}
+
+		// Stop tailing cleanly on Ctrl+C instead of killing the process mid-session
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Printf("Tailing %s (Ctrl+C to stop)...\n", logPath)
+		if err := sshClient.ExecuteCommandStreamingContext(ctx, tailCmd, os.Stdout, os.Stderr); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			fmt.Println("\nStopped tailing.")
+		}
+		return nil
+	},
+}
+
+// resolveReleasePath resolves a path on the server: absolute paths are kept, relative
+// ones are taken from the active release's app directory (<remote_path>/current/app).
+func resolveReleasePath(remotePath, p string) string {
+	if strings.HasPrefix(p, "/") {
+		return p
+	}
+	return filepath.ToSlash(filepath.Join(remotePath, "current", "app", p))
+}
+
+func getOrSelectConfig(cmd *cobra.Command) (string, error) {
+	// If the user explicitly provided a config flag, use it (as an absolute path)
+	if cmd.Flags().Changed("config") {
+		absPath, err := filepath.Abs(configPath)
+		if err != nil {
+			return configPath, nil
+		}
+		if verbose {
+			fmt.Printf("Using config: %s\n", absPath)
+		}
+		return absPath, nil
+	}
+
+	// If GUI mode is enabled, we don't want to prompt in CLI.
+	// We'll let the TUI handle it later.
+	if guiMode {
+		return configPath, nil
+	}
+
+	// Try to discover config files automatically, starting from --repo-path when given
+	searchDir := repoPathFlag
+	if searchDir == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return configPath, nil
+		}
+		searchDir = cwd
+	}
+
+	// Walk up like git does, so deploys work from any project subdirectory
+	files, err := config.FindConfigFilesUpward(searchDir)
+	if err != nil || len(files) == 0 {
+		// fallback to original default
+		return configPath, nil
+	}
+
+	if len(files) == 1 {
+		return useDiscoveredConfig(files[0]), nil
+	}
+
+	// If there are multiple configuration files, prompt the user
+	fmt.Println("\nMultiple configuration files found. Please select one:")
+	for i, f := range files {
+		fmt.Printf("[%d] %s\n", i+1, filepath.Base(f))
+	}
+	fmt.Print("Enter number: ")
+
+	var input string
+	_, err = fmt.Scanln(&input)
+	if err != nil {
+		// Just fallback if scanning fails
+		return configPath, nil
+	}
+
+	input = strings.TrimSpace(input)
+	idx, err := strconv.Atoi(input)
+	if err != nil || idx < 1 || idx > len(files) {
+		return "", fmt.Errorf("invalid selection")
+	}
+
+	fmt.Println()
+	return useDiscoveredConfig(files[idx-1]), nil
+}
+
+// useDiscoveredConfig records the directory of an auto-discovered config as the
+// repository root and reports the resolved path in verbose mode.
+func useDiscoveredConfig(path string) string {
+	repoRoot = filepath.Dir(path)
+	if verbose {
+		fmt.Printf("Using config: %s\n", path)
+	}
+	return path
+}
+
+// getRepoPath returns the repository root: --repo-path when set (which must be a git
+// repository), else the directory holding the auto-discovered config when there is
+// one, else the current working directory.
+func getRepoPath() (string, error) {
+	if repoPathFlag != "" {
+		abs, err := filepath.Abs(repoPathFlag)
+		if err != nil {
+			return "", verserrors.New(verserrors.CodeConfigInvalid, fmt.Sprintf("invalid --repo-path %q", repoPathFlag),
+				"Pass the path of the repository to deploy", err)
+		}
+		if err := git.ValidateRepository(abs); err != nil {
+			return "", verserrors.New(verserrors.CodeConfigInvalid, fmt.Sprintf("--repo-path %s is not a git repository", abs),
+				"Point --repo-path at the root of the repository to deploy (the directory containing .git)", err)
+		}
+		return abs, nil
+	}
+	if repoRoot != "" {
+		return repoRoot, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	return wd, nil
+}
+
+// tempDirFlag returns --temp-dir as an absolute path, resolved against the
+// working directory, or "" when the flag is not set
+func tempDirFlag(cmd *cobra.Command) (string, error) {
+	dir, _ := cmd.Flags().GetString("temp-dir")
+	if dir == "" {
+		return "", nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid --temp-dir %q: %w", dir, err)
+	}
+	return abs, nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "deploy.yml", "Path to configuration file")
+	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Verbose output")
+	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Debug mode")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Log file path")
+	rootCmd.PersistentFlags().StringVar(&repoPathFlag, "repo-path", "", "Repository root to deploy from instead of the current directory")
+	rootCmd.PersistentFlags().BoolVar(&guiMode, "gui", false, "Launch interactive TUI (default behavior; kept for backward compat)")
+	rootCmd.PersistentFlags().BoolVar(&noGUI, "no-gui", false, "Disable TUI and show help")
+
+	deployCmd.Flags().Bool("dry-run", false, "Show changes without deploying")
+	deployCmd.Flags().Bool("check-remote", false, "With --dry-run: also check write access and free disk space on the server")
+	deployCmd.Flags().Bool("initial-deploy", false, "Flag for first deployment")
+	deployCmd.Flags().Bool("force", false, "Force redeploy even if no changes detected")
+	deployCmd.Flags().Bool("skip-dirty-check", false, "Skip validation of uncommitted changes")
+	deployCmd.Flags().Bool("allow-dirty", false, "Deploy the working tree as-is, uncommitted changes included (non-reproducible; recorded in the manifest)")
+	deployCmd.Flags().Bool("strict-size", false, "Fail instead of warning when the artifact exceeds max_artifact_size_mb")
+	deployCmd.Flags().Bool("skip-disk-check", false, "Skip the pre-upload free disk space check on the server")
+	deployCmd.Flags().Bool("trace", false, "Time each deploy step (clone, changeset, build per type, compress, upload, extract, hooks) and print a breakdown")
+	deployCmd.Flags().Bool("override-window", false, "Deploy even outside the environment's deploy_windows (logged and recorded in deploy.lock)")
+	deployCmd.Flags().String("commit", "", "Commit SHA to record in deploy.lock and the manifest instead of HEAD (metadata only)")
+	deployCmd.Flags().String("remote-path", "", "Deploy under this absolute path instead of the environment's remote_path (e.g. /tmp/test-app)")
+	deployCmd.Flags().Bool("shallow-clone", false, "Clone only the deployed commit (git clone --depth 1) instead of the full history")
+	deployCmd.Flags().String("temp-dir", "", "Local scratch directory for the clone, artifact and archive chunks instead of the system temp dir")
+	deployCmd.Flags().Int("build-jobs", 0, "Value of {jobs} in composer/npm/compile commands and go build_flags (0 = number of CPUs)")
+	deployCmd.Flags().StringP("message", "m", "", "Note recorded with the release in deploy.lock and the manifest, shown by versa status (e.g. \"hotfix for payment bug\")")
+	deployCmd.Flags().Int("concurrency", 0, "Cap hashing workers, upload streams, parallel build/hook groups and release cleanup (0 = config or defaults)")
+
+	deployAllCmd.Flags().Bool("dry-run", false, "Show changes without deploying")
+	deployAllCmd.Flags().Bool("force", false, "Force redeploy even if no changes detected")
+	deployAllCmd.Flags().Bool("skip-dirty-check", false, "Skip validation of uncommitted changes")
+	deployAllCmd.Flags().String("temp-dir", "", "Local scratch directory for the clone, artifact and archive chunks instead of the system temp dir")
+	deployAllCmd.Flags().StringP("message", "m", "", "Note recorded with the release on every environment, shown by versa status")
+	deployAllCmd.Flags().Bool("fail-fast", false, "Abort the other deploys (before they go live) as soon as one fails")
+
+	promoteCmd.Flags().String("release", "", "Release to promote (default: the source environment's current release)")
+	promoteCmd.Flags().Bool("dry-run", false, "Check the release and print what would be promoted without copying anything")
+	promoteCmd.Flags().Bool("force", false, "Promote even if the target already runs the release's commit")
+
+	unlockCmd.Flags().Bool("yes", false, "Remove the lock without asking for confirmation")
+
+	rollbackCmd.Flags().String("to", "", "Rollback to a specific release version (e.g. 20240101_120000), or oldest / newest")
+	rollbackCmd.Flags().Bool("dry-run", false, "Show which release would become current without switching")
+
+	configDumpCmd.Flags().String("format", "yaml", "Output format: yaml or json")
+
+	envsCmd.Flags().Bool("json", false, "Print the environments as a JSON array")
+
+	logsCmd.Flags().Int("lines", 50, "Number of lines to show (before following, with --follow)")
+	logsCmd.Flags().String("file", "", "Log file to show, relative to the active release's app directory (or absolute)")
+	logsCmd.Flags().Bool("follow", false, "Keep streaming new lines until Ctrl+C")
+
+	pruneCmd.Flags().Int("keep", deployer.ReleasesToKeep, "Number of newest releases to keep (the active release is always kept)")
+
+	compareCmd.Flags().Bool("json", false, "Print the comparison as JSON")
+
+	diffCmd.Flags().Bool("json", false, "Print the changeset as JSON (file lists by category and dependency changes)")
+
+	pushSecretCmd.Flags().Bool("force", false, "Overwrite the shared file if it already exists")
+
+	testBuildCmd.Flags().Bool("keep", false, "Keep the built artifact and archive chunks and print where they are")
+	testBuildCmd.Flags().Bool("pre-deploy-local", false, "Run the pre_deploy_local hooks before building, as a deploy does")
+	testBuildCmd.Flags().Bool("skip-dirty-check", false, "Skip validation of uncommitted changes")
+	testBuildCmd.Flags().Bool("allow-dirty", false, "Build the working tree as-is, uncommitted changes included")
+	testBuildCmd.Flags().Bool("strict-size", false, "Fail instead of warning when the artifact exceeds max_artifact_size_mb")
+	testBuildCmd.Flags().Int("build-jobs", 0, "Value of {jobs} in composer/npm/compile commands and go build_flags (0 = number of CPUs)")
+	testBuildCmd.Flags().String("temp-dir", "", "Local scratch directory for the clone, artifact and archive chunks instead of the system temp dir")
+
+	initCmd.Flags().Bool("interactive", false, "Prompt for project, environment, SSH and build settings and generate a tailored config")
+	initCmd.Flags().String("template", "", "Pre-fill the config for a stack: "+strings.Join(templateNames(), ", "))
+
+	versionCmd.Flags().Bool("json", false, "Print version and build metadata as JSON")
+
+	rootCmd.AddCommand(deployCmd)
+	rootCmd.AddCommand(deployAllCmd)
+	rootCmd.AddCommand(rollbackCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(sshTestCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(selfUpdateCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(hooksCmd)
+	rootCmd.AddCommand(runHookCmd)
+	rootCmd.AddCommand(pushSecretCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(testBuildCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(promoteCmd)
+	rootCmd.AddCommand(unlockCmd)
+	rootCmd.AddCommand(logsCmd)
+	configCmd.AddCommand(configDumpCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(envsCmd)
+}
+
+func main() {
+	selfupdate.CleanupOldBinary()
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, verserrors.FormatError(verserrors.Wrap(err)))
 		os.Exit(1)
@@ -1,8 +1,13 @@
 package main
 
 import (
+	"errors"
 	"os"
+	"os/exec"
+	"strings"
 	"testing"
+
+	verserrors "github.com/user/versaDeploy/internal/errors"
 )
 
 func TestRootCommand(t *testing.T) {
@@ -77,3 +82,35 @@ func TestSSHTestCommand(t *testing.T) {
 		t.Error("expected failure for missing environment argument")
 	}
 }
+
+func TestResolveReleasePath(t *testing.T) {
+	if got := resolveReleasePath("/var/www/app", "storage/logs/app.log"); got != "/var/www/app/current/app/storage/logs/app.log" {
+		t.Errorf("unexpected relative resolution: %s", got)
+	}
+	if got := resolveReleasePath("/var/www/app", "/var/log/syslog"); got != "/var/log/syslog" {
+		t.Errorf("expected absolute path kept, got %s", got)
+	}
+}
+
+func TestGetRepoPath_RepoPathFlag(t *testing.T) {
+	defer func() { repoPathFlag = "" }()
+
+	repoPathFlag = t.TempDir()
+	_, err := getRepoPath()
+	if err == nil || !strings.Contains(err.Error(), "--repo-path") || !errors.Is(err, verserrors.ErrConfigInvalid) {
+		t.Errorf("expected a --repo-path error when it is not a git repository, got %v", err)
+	}
+
+	repoDir := t.TempDir()
+	if out, err := exec.Command("git", "init", repoDir).CombinedOutput(); err != nil {
+		t.Skipf("git not available: %v: %s", err, out)
+	}
+	repoPathFlag = repoDir
+	got, err := getRepoPath()
+	if err != nil {
+		t.Fatalf("getRepoPath() error = %v", err)
+	}
+	if got != repoDir {
+		t.Errorf("getRepoPath() = %s, want %s", got, repoDir)
+	}
+}
@@ -3,12 +3,15 @@ package artifact
 import (
 	"archive/tar"
 	"compress/gzip"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
 	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
@@ -22,6 +25,8 @@ type Manifest struct {
 	CommitHash     string         `json:"commit_hash"`
 	BuildTimestamp time.Time      `json:"build_timestamp"`
 	ChangesApplied ChangesApplied `json:"changes_applied"`
+	DirtyTree      bool           `json:"dirty_tree,omitempty"` // built from uncommitted changes (--allow-dirty)
+	Message        string         `json:"message,omitempty"`    // deploy message (versa deploy -m)
 }
 
 // ChangesApplied tracks what was changed in this release
@@ -35,11 +40,34 @@ type ChangesApplied struct {
 	RouteCacheRegenerate bool `json:"route_cache_regenerate"`
 }
 
+// FileInventoryName is the artifact file listing the SHA256 hash of every shipped file
+const FileInventoryName = "files.json"
+
 // Generator handles artifact generation
 type Generator struct {
 	artifactDir    string
 	releaseVersion string
 	commitHash     string
+
+	// NormalizeModes writes fixed modes (0775 for directories, 0774 for files) into the
+	// archive instead of the real permissions found on disk.
+	NormalizeModes bool
+
+	// Exclude lists glob patterns (path.Match syntax) for files left out of the archive.
+	// Patterns containing a slash match the path relative to app/ ("/.env" matches only
+	// the top-level .env); bare patterns match the file name at any depth.
+	Exclude []string
+
+	// DirtyTree records in the manifest that the release was built from a working
+	// tree with uncommitted changes.
+	DirtyTree bool
+
+	// Message is the deploy message recorded in the manifest
+	Message string
+
+	// Expect lists paths (slash-separated, relative to the artifact root) the build
+	// must have produced. Validate fails when one is missing or empty.
+	Expect []string
 }
 
 // NewGenerator creates a new artifact generator
@@ -57,6 +85,8 @@ func (g *Generator) GenerateManifest(buildResult *builder.BuildResult) error {
 		ReleaseVersion: g.releaseVersion,
 		CommitHash:     g.commitHash,
 		BuildTimestamp: time.Now().UTC(),
+		DirtyTree:      g.DirtyTree,
+		Message:        g.Message,
 		ChangesApplied: ChangesApplied{
 			PHPFilesChanged:      buildResult.PHPFilesChanged,
 			GoBinaryRebuilt:      buildResult.GoBinaryRebuilt,
@@ -81,6 +111,90 @@ func (g *Generator) GenerateManifest(buildResult *builder.BuildResult) error {
 	return nil
 }
 
+// ParseManifest decodes a manifest.json
+func ParseManifest(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// GenerateFileInventory writes files.json, mapping every regular file that will be
+// archived (relative to the artifact root) to its "sha256:<hex>" hash. Files matching
+// Exclude are left out, so it must be called with the same Exclude as the archive step.
+func (g *Generator) GenerateFileInventory() error {
+	files := make(map[string]string)
+	err := filepath.WalkDir(g.artifactDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(g.artifactDir, p)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == "." {
+			return nil
+		}
+		if g.isExcluded(relPath) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || relPath == "manifest.json" || relPath == FileInventoryName {
+			return nil
+		}
+		hash, err := hashFile(p)
+		if err != nil {
+			return err
+		}
+		files[relPath] = hash
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to hash artifact files: %w", err)
+	}
+
+	data, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal file inventory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(g.artifactDir, FileInventoryName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write file inventory: %w", err)
+	}
+	return nil
+}
+
+// ReadFileInventory loads the files.json written by GenerateFileInventory
+func ReadFileInventory(artifactDir string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(artifactDir, FileInventoryName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file inventory: %w", err)
+	}
+	files := make(map[string]string)
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("failed to parse file inventory: %w", err)
+	}
+	return files, nil
+}
+
+// hashFile returns the SHA256 of a file in the same "sha256:<hex>" form as deploy.lock
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", hash.Sum(nil)), nil
+}
+
 // Validate checks that the artifact is complete
 func (g *Generator) Validate() error {
 	// Check manifest exists
@@ -89,20 +203,72 @@ func (g *Generator) Validate() error {
 		return fmt.Errorf("manifest.json not found in artifact")
 	}
 
-	// Validate artifact directory structure
-	requiredDirs := []string{"app", "vendor", "node_modules", "public", "bin"}
-	for _, dir := range requiredDirs {
-		dirPath := filepath.Join(g.artifactDir, dir)
-		if _, err := os.Stat(dirPath); os.IsNotExist(err) {
-			// Directory might not exist if that build type wasn't enabled
-			// This is acceptable
-			continue
+	// Every expected build output must exist and not be empty
+	var missing []string
+	for _, rel := range g.Expect {
+		p := filepath.Join(g.artifactDir, filepath.FromSlash(rel))
+		info, err := os.Stat(p)
+		switch {
+		case err != nil:
+			missing = append(missing, rel)
+		case info.IsDir():
+			if entries, err := os.ReadDir(p); err != nil || len(entries) == 0 {
+				missing = append(missing, rel+" (empty)")
+			}
+		case info.Size() == 0:
+			missing = append(missing, rel+" (empty)")
 		}
 	}
+	if len(missing) > 0 {
+		return fmt.Errorf("artifact validation failed: build produced no %s", strings.Join(missing, ", "))
+	}
 
 	return nil
 }
 
+// isExcluded reports whether relPath (slash-separated, relative to the artifact root)
+// matches an Exclude pattern. Only entries under app/ can be excluded, so manifest.json
+// and other release metadata are always shipped.
+func (g *Generator) isExcluded(relPath string) bool {
+	if len(g.Exclude) == 0 {
+		return false
+	}
+	appRel, ok := strings.CutPrefix(relPath, "app/")
+	if !ok {
+		return false
+	}
+	return MatchesExclude(g.Exclude, appRel)
+}
+
+// MatchesExclude reports whether appRel (slash-separated, relative to app/) matches one
+// of the artifact_exclude patterns. Patterns starting with / are anchored at app/, bare
+// names match the base name at any depth and other patterns match the whole path.
+func MatchesExclude(patterns []string, appRel string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		target := appRel
+		if anchored, ok := strings.CutPrefix(pattern, "/"); ok {
+			pattern = anchored
+		} else if !strings.Contains(pattern, "/") {
+			target = path.Base(appRel)
+		}
+		if matched, _ := path.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// headerMode returns the tar mode for an entry: the real permission bits by default, or
+// the normalized mode when NormalizeModes is set. Windows does not carry Unix permission
+// bits, so the normalized mode is always used there.
+func (g *Generator) headerMode(info os.FileInfo, normalized int64) int64 {
+	if g.NormalizeModes || runtime.GOOS == "windows" {
+		return normalized
+	}
+	return int64(info.Mode().Perm())
+}
+
 // GenerateReleaseVersion creates a timestamp-based release version
 func GenerateReleaseVersion() string {
 	return time.Now().UTC().Format("20060102-150405")
@@ -196,12 +362,41 @@ func (cw *chunkWriter) ChunkPaths() []string {
 	return paths
 }
 
+// SplitFile splits an existing archive into path.001, path.002, … chunks of at
+// most chunkSize bytes, the layout CompressChunked produces
+func SplitFile(path string, chunkSize int64) ([]string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	cw := &chunkWriter{basePath: path, chunkSize: chunkSize}
+	if _, err := io.Copy(cw, in); err != nil {
+		cw.Close()
+		return nil, fmt.Errorf("failed to split %s: %w", filepath.Base(path), err)
+	}
+	if err := cw.Close(); err != nil {
+		return nil, err
+	}
+	return cw.ChunkPaths(), nil
+}
+
 // CompressChunked creates a multi-part .tar.gz archive of the artifact directory
 func (g *Generator) CompressChunked(archivePath string, chunkSize int64) ([]string, error) {
 	// First, count files for progress bar
 	var fileCount int64
 	filepath.WalkDir(g.artifactDir, func(path string, d os.DirEntry, err error) error {
-		if err == nil && !d.IsDir() {
+		if err != nil {
+			return nil
+		}
+		if rel, relErr := filepath.Rel(g.artifactDir, path); relErr == nil && g.isExcluded(filepath.ToSlash(rel)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
 			fileCount++
 		}
 		return nil
@@ -237,6 +432,13 @@ func (g *Generator) CompressChunked(archivePath string, chunkSize int64) ([]stri
 			return nil
 		}
 
+		if g.isExcluded(filepath.ToSlash(relPath)) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		info, err := d.Info()
 		if err != nil {
 			fmt.Printf("[WARN] Skipping (cannot get info): %s - %v\n", relPath, err)
@@ -272,10 +474,10 @@ func (g *Generator) CompressChunked(archivePath string, chunkSize int64) ([]stri
 			header.Size = 0
 		} else if info.IsDir() {
 			header.Typeflag = tar.TypeDir
-			header.Mode = 0775
+			header.Mode = g.headerMode(info, 0775)
 		} else {
 			header.Typeflag = tar.TypeReg
-			header.Mode = 0774
+			header.Mode = g.headerMode(info, 0774)
 		}
 
 		if err := tw.WriteHeader(header); err != nil {
@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/user/versaDeploy/internal/builder"
@@ -156,6 +158,50 @@ func TestGenerator_GenerateManifest(t *testing.T) {
 	}
 }
 
+func TestGenerator_GenerateManifest_DirtyTree(t *testing.T) {
+	artifactDir := t.TempDir()
+	g := NewGenerator(artifactDir, "1.0.0", "abc123")
+
+	if err := g.GenerateManifest(&builder.BuildResult{}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(artifactDir, "manifest.json"))
+	if strings.Contains(string(data), "dirty_tree") {
+		t.Errorf("clean build should not record dirty_tree:\n%s", data)
+	}
+
+	g.DirtyTree = true
+	if err := g.GenerateManifest(&builder.BuildResult{}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(filepath.Join(artifactDir, "manifest.json"))
+	if !strings.Contains(string(data), `"dirty_tree": true`) {
+		t.Errorf("expected dirty_tree in manifest:\n%s", data)
+	}
+}
+
+func TestGenerator_GenerateManifest_Message(t *testing.T) {
+	artifactDir := t.TempDir()
+	g := NewGenerator(artifactDir, "1.0.0", "abc123")
+	g.Message = "hotfix for payment bug"
+
+	if err := g.GenerateManifest(&builder.BuildResult{}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(artifactDir, "manifest.json"))
+	m, err := ParseManifest(data)
+	if err != nil {
+		t.Fatalf("ParseManifest() error = %v", err)
+	}
+	if m.Message != "hotfix for payment bug" || m.CommitHash != "abc123" {
+		t.Errorf("unexpected manifest: %+v", m)
+	}
+
+	if _, err := ParseManifest([]byte("not json")); err == nil {
+		t.Error("ParseManifest should reject invalid JSON")
+	}
+}
+
 func TestGenerator_Validate(t *testing.T) {
 	artifactDir := t.TempDir()
 	g := NewGenerator(artifactDir, "1.0.0", "abc123")
@@ -174,9 +220,205 @@ func TestGenerator_Validate(t *testing.T) {
 	}
 }
 
+func TestGenerator_Validate_Expect(t *testing.T) {
+	artifactDir := t.TempDir()
+	os.WriteFile(filepath.Join(artifactDir, "manifest.json"), []byte("{}"), 0644)
+	os.MkdirAll(filepath.Join(artifactDir, "app", "vendor"), 0755)
+	os.MkdirAll(filepath.Join(artifactDir, "bin"), 0755)
+	os.WriteFile(filepath.Join(artifactDir, "bin", "server"), nil, 0755)
+
+	g := NewGenerator(artifactDir, "1.0.0", "abc123")
+	g.Expect = []string{"app", "app/vendor", "bin/server", "app/public/build"}
+
+	err := g.Validate()
+	if err == nil {
+		t.Fatal("Validate() should fail when expected outputs are missing or empty")
+	}
+	for _, want := range []string{"app/vendor (empty)", "bin/server (empty)", "app/public/build"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should mention %s", err, want)
+		}
+	}
+
+	os.WriteFile(filepath.Join(artifactDir, "app", "vendor", "autoload.php"), []byte("<?php"), 0644)
+	os.WriteFile(filepath.Join(artifactDir, "bin", "server"), []byte("ELF"), 0755)
+	os.MkdirAll(filepath.Join(artifactDir, "app", "public", "build"), 0755)
+	os.WriteFile(filepath.Join(artifactDir, "app", "public", "build", "app.js"), []byte("x"), 0644)
+	if err := g.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
+
 func TestGenerateReleaseVersion(t *testing.T) {
 	v := GenerateReleaseVersion()
 	if len(v) != 15 { // YYYYMMDD-HHMMSS is 8 + 1 + 6 = 15
 		t.Errorf("unexpected version format: %s", v)
 	}
 }
+
+func TestGenerator_CompressPreservesModes(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix permission bits are not available on Windows")
+	}
+
+	artifactDir := t.TempDir()
+	os.MkdirAll(filepath.Join(artifactDir, "app", "bin"), 0755)
+	os.WriteFile(filepath.Join(artifactDir, "app", "bin", "run.sh"), []byte("#!/bin/sh"), 0755)
+	os.WriteFile(filepath.Join(artifactDir, "app", "secret.key"), []byte("key"), 0600)
+	os.Chmod(filepath.Join(artifactDir, "app", "secret.key"), 0600)
+	os.Chmod(filepath.Join(artifactDir, "app", "bin", "run.sh"), 0755)
+
+	readModes := func(g *Generator) map[string]int64 {
+		archivePath := filepath.Join(t.TempDir(), "artifact.tar.gz")
+		if err := g.Compress(archivePath); err != nil {
+			t.Fatalf("Compress() error = %v", err)
+		}
+		f, _ := os.Open(archivePath)
+		defer f.Close()
+		gr, _ := gzip.NewReader(f)
+		defer gr.Close()
+		tr := tar.NewReader(gr)
+
+		modes := make(map[string]int64)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			modes[header.Name] = header.Mode
+		}
+		return modes
+	}
+
+	modes := readModes(NewGenerator(artifactDir, "20260127", "hash123"))
+	if modes["app/bin/run.sh"] != 0755 {
+		t.Errorf("expected run.sh mode 0755, got %o", modes["app/bin/run.sh"])
+	}
+	if modes["app/secret.key"] != 0600 {
+		t.Errorf("expected secret.key mode 0600, got %o", modes["app/secret.key"])
+	}
+
+	normalized := NewGenerator(artifactDir, "20260127", "hash123")
+	normalized.NormalizeModes = true
+	modes = readModes(normalized)
+	if modes["app/secret.key"] != 0774 {
+		t.Errorf("expected normalized file mode 0774, got %o", modes["app/secret.key"])
+	}
+	if modes["app/bin"] != 0775 {
+		t.Errorf("expected normalized dir mode 0775, got %o", modes["app/bin"])
+	}
+}
+
+func TestGenerator_CompressExclude(t *testing.T) {
+	artifactDir := t.TempDir()
+	files := []string{
+		"manifest.json",
+		"app/public/app.js",
+		"app/public/app.js.map",
+		"app/tests/fixtures/big.sql",
+		"app/tests/unit.php",
+		"app/.env",
+		"app/config/.env",
+	}
+	for _, p := range files {
+		fullPath := filepath.Join(artifactDir, filepath.FromSlash(p))
+		os.MkdirAll(filepath.Dir(fullPath), 0755)
+		os.WriteFile(fullPath, []byte("x"), 0644)
+	}
+
+	g := NewGenerator(artifactDir, "20260127", "hash123")
+	g.Exclude = []string{"*.map", "tests/fixtures", "manifest.json", "/.env"}
+	archivePath := filepath.Join(t.TempDir(), "artifact.tar.gz")
+	if err := g.Compress(archivePath); err != nil {
+		t.Fatalf("Compress() error = %v", err)
+	}
+
+	f, _ := os.Open(archivePath)
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	names := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[header.Name] = true
+	}
+
+	for _, want := range []string{"manifest.json", "app/public/app.js", "app/tests/unit.php", "app/config/.env"} {
+		if !names[want] {
+			t.Errorf("expected %s in archive", want)
+		}
+	}
+	for _, unwanted := range []string{"app/public/app.js.map", "app/tests/fixtures", "app/tests/fixtures/big.sql", "app/.env"} {
+		if names[unwanted] {
+			t.Errorf("expected %s to be excluded from archive", unwanted)
+		}
+	}
+}
+
+func TestGenerator_GenerateFileInventory(t *testing.T) {
+	artifactDir := t.TempDir()
+	os.MkdirAll(filepath.Join(artifactDir, "app", "public"), 0755)
+	os.WriteFile(filepath.Join(artifactDir, "manifest.json"), []byte("{}"), 0644)
+	os.WriteFile(filepath.Join(artifactDir, "app", "index.php"), []byte("hello"), 0644)
+	os.WriteFile(filepath.Join(artifactDir, "app", "public", "app.js.map"), []byte("map"), 0644)
+
+	g := NewGenerator(artifactDir, "20260127", "hash123")
+	g.Exclude = []string{"*.map"}
+	if err := g.GenerateFileInventory(); err != nil {
+		t.Fatalf("GenerateFileInventory() error = %v", err)
+	}
+
+	files, err := ReadFileInventory(artifactDir)
+	if err != nil {
+		t.Fatalf("ReadFileInventory() error = %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only app/index.php in inventory, got %v", files)
+	}
+	// sha256("hello")
+	want := "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if files["app/index.php"] != want {
+		t.Errorf("unexpected hash %s", files["app/index.php"])
+	}
+}
+
+func TestSplitFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "release.tar.gz")
+	content := []byte("0123456789abcdefghij-")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	chunks, err := SplitFile(path, 10)
+	if err != nil {
+		t.Fatalf("SplitFile() error = %v", err)
+	}
+	want := []string{path + ".001", path + ".002", path + ".003"}
+	if strings.Join(chunks, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected chunks %v, got %v", want, chunks)
+	}
+
+	var joined []byte
+	for _, p := range chunks {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		joined = append(joined, data...)
+	}
+	if string(joined) != string(content) {
+		t.Errorf("reassembled %q, want %q", joined, content)
+	}
+}
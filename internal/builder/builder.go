@@ -5,10 +5,12 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/user/versaDeploy/internal/builder/lang"
 	"github.com/user/versaDeploy/internal/changeset"
@@ -29,6 +31,7 @@ type BuildResult struct {
 	PipUpdated           bool
 	TwigCacheCleanup     bool
 	RouteCacheRegenerate bool
+	Durations            map[string]time.Duration // Wall time of each language build ("php", "go", "frontend", "python")
 }
 
 // Builder orchestrates all build operations
@@ -39,6 +42,10 @@ type Builder struct {
 	changeset   *changeset.ChangeSet
 	result      *BuildResult
 	log         *logger.Logger
+
+	// Jobs is the value of {jobs} in composer, npm and compile commands (e.g. from
+	// --build-jobs); 0 uses the number of CPUs
+	Jobs int
 }
 
 // NewBuilder creates a new builder
@@ -71,9 +78,13 @@ func (b *Builder) Build() (*BuildResult, error) {
 		Config:      b.config,
 		Changeset:   b.changeset,
 		Log:         b.log,
+		Jobs:        b.Jobs,
 	}
 
 	var g errgroup.Group
+	if b.config.Concurrency > 0 {
+		g.SetLimit(b.config.Concurrency)
+	}
 
 	// Local result holders — written only by their own goroutine, merged after Wait().
 	var (
@@ -86,10 +97,16 @@ func (b *Builder) Build() (*BuildResult, error) {
 		feNPM         bool
 		pyCount       int
 		pyPip         bool
+		phpTime       time.Duration
+		goTime        time.Duration
+		feTime        time.Duration
+		pyTime        time.Duration
 	)
 
 	if b.config.Builds.PHP.Enabled {
 		g.Go(func() error {
+			start := time.Now()
+			defer func() { phpTime = time.Since(start) }()
 			builder := &lang.PHPBuilder{}
 			count, updated, err := builder.Build(buildCtx)
 			if err != nil {
@@ -105,6 +122,8 @@ func (b *Builder) Build() (*BuildResult, error) {
 
 	if b.config.Builds.Go.Enabled {
 		g.Go(func() error {
+			start := time.Now()
+			defer func() { goTime = time.Since(start) }()
 			builder := &lang.GoBuilder{}
 			_, updated, err := builder.Build(buildCtx)
 			if err != nil {
@@ -117,6 +136,8 @@ func (b *Builder) Build() (*BuildResult, error) {
 
 	if b.config.Builds.Frontend.Enabled {
 		g.Go(func() error {
+			start := time.Now()
+			defer func() { feTime = time.Since(start) }()
 			builder := &lang.FrontendBuilder{}
 			count, updated, err := builder.Build(buildCtx)
 			if err != nil {
@@ -130,6 +151,8 @@ func (b *Builder) Build() (*BuildResult, error) {
 
 	if b.config.Builds.Python.Enabled {
 		g.Go(func() error {
+			start := time.Now()
+			defer func() { pyTime = time.Since(start) }()
 			builder := &lang.PythonBuilder{}
 			count, updated, err := builder.Build(buildCtx)
 			if err != nil {
@@ -155,6 +178,19 @@ func (b *Builder) Build() (*BuildResult, error) {
 	b.result.NPMUpdated = feNPM
 	b.result.PythonFilesBuilt = pyCount
 	b.result.PipUpdated = pyPip
+	b.result.Durations = make(map[string]time.Duration)
+	if b.config.Builds.PHP.Enabled {
+		b.result.Durations["php"] = phpTime
+	}
+	if b.config.Builds.Go.Enabled {
+		b.result.Durations["go"] = goTime
+	}
+	if b.config.Builds.Frontend.Enabled {
+		b.result.Durations["frontend"] = feTime
+	}
+	if b.config.Builds.Python.Enabled {
+		b.result.Durations["python"] = pyTime
+	}
 
 	// Step 5: Cleanup ignored paths after builds complete
 	b.log.Info("Cleaning up build-time dependencies...")
@@ -162,6 +198,11 @@ func (b *Builder) Build() (*BuildResult, error) {
 		return nil, fmt.Errorf("failed to cleanup ignored paths: %w", err)
 	}
 
+	// Step 6: artifact_prune runs after ignored paths are removed, on what will ship
+	if err := b.runArtifactPrune(); err != nil {
+		return nil, err
+	}
+
 	return b.result, nil
 }
 
@@ -179,6 +220,9 @@ func (b *Builder) copyEntireRepo() error {
 		dst string
 	}
 
+	excluded := b.copyExcludeSet()
+	skipDirs := b.skipDirSet()
+
 	// Collect files; create directories inline (sequential, preserves order).
 	var files []filePair
 	err := filepath.Walk(b.repoPath, func(path string, info os.FileInfo, err error) error {
@@ -202,6 +246,36 @@ func (b *Builder) copyEntireRepo() error {
 			return nil
 		}
 
+		// Submodule checkouts have a .git file pointing into the superproject's .git
+		if info.Name() == ".git" {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() && skipDirs[info.Name()] {
+			b.log.Debug("   Skipping %s", filepath.ToSlash(relPath))
+			return filepath.SkipDir
+		}
+
+		// Skip copy_exclude entries entirely; short-circuit the walk for directories
+		if isCopyExcluded(excluded, filepath.ToSlash(relPath)) {
+			if info.IsDir() {
+				b.log.Debug("   Skipping excluded directory: %s", filepath.ToSlash(relPath))
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Never flatten symlinks that point outside the repository into the artifact
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, inside := symlinkTargetWithin(b.repoPath, path); target != "" && !inside {
+				b.log.Warn("Skipping symlink %s: target %s is outside the repository", filepath.ToSlash(relPath), target)
+				return nil
+			}
+		}
+
 		dstPath := filepath.Join(appDir, relPath)
 
 		if info.IsDir() {
@@ -254,6 +328,134 @@ func (b *Builder) copyEntireRepo() error {
 	return copyErr
 }
 
+// DefaultSkipDirs are the VCS metadata and editor directories never copied into the
+// artifact unless skip_dirs overrides them. .git is skipped regardless.
+var DefaultSkipDirs = []string{".svn", ".hg", ".bzr", ".idea", ".vscode"}
+
+// skipDirSet returns the directory names skipped at any depth during the copy: the
+// configured skip_dirs, or DefaultSkipDirs when it is not set
+func (b *Builder) skipDirSet() map[string]bool {
+	names := DefaultSkipDirs
+	if b.config != nil && b.config.SkipDirs != nil {
+		names = b.config.SkipDirs
+	}
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[strings.Trim(name, "/")] = true
+	}
+	return set
+}
+
+// copyExcludeSet returns the normalized copy_exclude entries as a set
+func (b *Builder) copyExcludeSet() map[string]struct{} {
+	excluded := make(map[string]struct{})
+	if b.config == nil {
+		return excluded
+	}
+	for _, p := range b.config.CopyExclude {
+		clean := filepath.ToSlash(filepath.Clean(p))
+		if clean == "." || clean == "" {
+			continue
+		}
+		excluded[clean] = struct{}{}
+	}
+	return excluded
+}
+
+// isCopyExcluded reports whether relPath matches a copy_exclude entry. Entries containing
+// a slash match that exact path; bare names (e.g. "node_modules") match at any depth.
+func isCopyExcluded(excluded map[string]struct{}, relPath string) bool {
+	if len(excluded) == 0 {
+		return false
+	}
+	if _, ok := excluded[relPath]; ok {
+		return true
+	}
+	_, ok := excluded[path.Base(relPath)]
+	return ok
+}
+
+// ExpectedPaths lists the outputs (slash-separated, relative to the artifact root) that
+// must exist after a build, based on what the build actually ran: the Go binary when it
+// was rebuilt, a non-empty vendor/ after composer, the PyInstaller binary and the frontend
+// output_dir after a compile. Paths removed by ignored_paths are not expected.
+func ExpectedPaths(env *config.Environment, result *BuildResult) []string {
+	paths := []string{"app"}
+	builds := env.Builds
+
+	if result.GoBinaryRebuilt {
+		paths = append(paths, path.Join(filepath.ToSlash(builds.Go.DeployPath), builds.Go.BinaryName))
+	}
+	if result.ComposerUpdated {
+		paths = append(paths, path.Join("app", filepath.ToSlash(builds.PHP.ProjectRoot), "vendor"))
+	}
+	if result.FrontendCompiled > 0 && builds.Frontend.OutputDir != "" {
+		paths = append(paths, path.Join("app", filepath.ToSlash(builds.Frontend.ProjectRoot), filepath.ToSlash(builds.Frontend.OutputDir)))
+	}
+	if result.PythonFilesBuilt > 0 && builds.Python.BuildBinary {
+		name := builds.Python.BinaryName
+		if runtime.GOOS == "windows" {
+			name += ".exe"
+		}
+		paths = append(paths, path.Join("app", filepath.ToSlash(builds.Python.ProjectRoot), name))
+	}
+
+	expected := paths[:0]
+	for _, p := range paths {
+		if !isIgnored(env.Ignored, p) {
+			expected = append(expected, p)
+		}
+	}
+	return expected
+}
+
+// isIgnored reports whether artifactPath lies under app/ and within an ignored_paths entry
+func isIgnored(ignored []string, artifactPath string) bool {
+	rel, ok := strings.CutPrefix(artifactPath, "app/")
+	if !ok {
+		return false
+	}
+	for _, ig := range ignored {
+		ig = filepath.ToSlash(filepath.Clean(ig))
+		if rel == ig || strings.HasPrefix(rel, ig+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// runArtifactPrune runs the artifact_prune commands locally, one at a time, in the
+// artifact (app/ unless the hook sets dir). Build outputs are already in place, so
+// they can delete whatever should not be shipped. A failing command aborts the build.
+func (b *Builder) runArtifactPrune() error {
+	if len(b.config.ArtifactPrune) == 0 {
+		return nil
+	}
+
+	b.log.Info("Pruning artifact...")
+	for _, hook := range b.config.ArtifactPrune {
+		dir := hook.Dir
+		if dir == "" {
+			dir = "app"
+		}
+		cmds := hook.Parallel
+		if hook.Command != "" {
+			cmds = []string{hook.Command}
+		}
+		for _, cmd := range cmds {
+			b.log.Info("  Prune: %s", cmd)
+			output, err := b.executeCommand(cmd, filepath.Join(b.artifactDir, dir))
+			if err != nil {
+				return fmt.Errorf("artifact_prune command failed: %s: %w\nOutput: %s", cmd, err, strings.TrimSpace(string(output)))
+			}
+			if out := strings.TrimSpace(string(output)); out != "" {
+				b.log.Debug("  Output: %s", out)
+			}
+		}
+	}
+	return nil
+}
+
 // cleanupIgnoredPaths removes ignored paths from artifact after builds complete
 func (b *Builder) cleanupIgnoredPaths() error {
 	appDir := filepath.Join(b.artifactDir, "app")
@@ -307,6 +509,26 @@ func (b *Builder) executeCommand(command, dir string) ([]byte, error) {
 	return cmd.CombinedOutput()
 }
 
+// symlinkTargetWithin resolves the symlink at linkPath and reports whether its final
+// target lies inside root. An empty target is returned for broken symlinks.
+func symlinkTargetWithin(root, linkPath string) (string, bool) {
+	target, err := filepath.EvalSymlinks(linkPath)
+	if err != nil {
+		return "", false
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		realRoot = root
+	}
+
+	rel, err := filepath.Rel(realRoot, target)
+	if err != nil {
+		return target, false
+	}
+	return target, rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // copyFile copies a single file using io.Copy for efficiency and reliability
 func copyFile(src, dst string) error {
 	info, err := os.Lstat(src)
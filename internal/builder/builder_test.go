@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/user/versaDeploy/internal/builder/lang"
@@ -145,6 +147,77 @@ func TestBuilder_BuildPHP_NoComposer(t *testing.T) {
 	}
 }
 
+func TestBuilder_RunArtifactPrune(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("prune commands use POSIX shell syntax")
+	}
+
+	artifactDir := t.TempDir()
+	os.MkdirAll(filepath.Join(artifactDir, "app", "tests"), 0775)
+	os.MkdirAll(filepath.Join(artifactDir, "app", "public", "js"), 0775)
+	os.WriteFile(filepath.Join(artifactDir, "app", "public", "js", "app.js"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(artifactDir, "app", "public", "js", "app.js.map"), []byte("x"), 0644)
+	os.MkdirAll(filepath.Join(artifactDir, "bin"), 0775)
+	os.WriteFile(filepath.Join(artifactDir, "bin", "debug.log"), []byte("x"), 0644)
+
+	cfg := &config.Environment{
+		ArtifactPrune: []config.HookConfig{
+			{Command: "rm -rf tests"},
+			{Parallel: []string{"find . -name '*.map' -delete"}},
+			{Command: "rm debug.log", Dir: "bin"},
+		},
+	}
+	log, _ := logger.NewLogger("", false, false)
+	b := NewBuilder(t.TempDir(), artifactDir, cfg, &changeset.ChangeSet{}, log)
+	if err := b.runArtifactPrune(); err != nil {
+		t.Fatalf("runArtifactPrune() error = %v", err)
+	}
+
+	for _, gone := range []string{"app/tests", "app/public/js/app.js.map", "bin/debug.log"} {
+		if _, err := os.Stat(filepath.Join(artifactDir, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s should have been pruned", gone)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(artifactDir, "app", "public", "js", "app.js")); err != nil {
+		t.Errorf("app.js should be kept: %v", err)
+	}
+
+	cfg.ArtifactPrune = []config.HookConfig{{Command: "echo nope >&2; exit 3"}}
+	err := b.runArtifactPrune()
+	if err == nil || !strings.Contains(err.Error(), "artifact_prune command failed") || !strings.Contains(err.Error(), "nope") {
+		t.Errorf("expected failing prune command error with output, got %v", err)
+	}
+}
+
+func TestExpectedPaths(t *testing.T) {
+	env := &config.Environment{
+		Builds: config.BuildsConfig{
+			PHP:      config.PHPBuildConfig{ProjectRoot: "api"},
+			Go:       config.GoBuildConfig{DeployPath: "bin", BinaryName: "server"},
+			Frontend: config.FrontendBuildConfig{ProjectRoot: "web", OutputDir: "dist"},
+		},
+		Ignored: []string{"web/dist/maps"},
+	}
+
+	got := ExpectedPaths(env, &BuildResult{})
+	if strings.Join(got, ",") != "app" {
+		t.Errorf("nothing built: got %v, want [app]", got)
+	}
+
+	got = ExpectedPaths(env, &BuildResult{GoBinaryRebuilt: true, ComposerUpdated: true, FrontendCompiled: 3})
+	want := "app,bin/server,app/api/vendor,app/web/dist"
+	if strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+
+	// Outputs removed by ignored_paths are not expected
+	env.Ignored = []string{"api/vendor"}
+	got = ExpectedPaths(env, &BuildResult{ComposerUpdated: true})
+	if strings.Join(got, ",") != "app" {
+		t.Errorf("ignored vendor: got %v, want [app]", got)
+	}
+}
+
 func TestBuilder_CleanupIgnoredPaths(t *testing.T) {
 	repoDir := t.TempDir()
 	artifactDir := t.TempDir()
@@ -261,3 +334,199 @@ func TestBuilder_Build_Subdirectories(t *testing.T) {
 		t.Error("api/index.php not found in artifact/app/api")
 	}
 }
+
+func TestBuilder_copyEntireRepo_CopyExclude(t *testing.T) {
+	repoDir := t.TempDir()
+	artifactDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(repoDir, "index.php"), []byte("<?php"), 0644)
+	os.MkdirAll(filepath.Join(repoDir, "node_modules", "left-pad"), 0775)
+	os.WriteFile(filepath.Join(repoDir, "node_modules", "left-pad", "index.js"), []byte("x"), 0644)
+	os.MkdirAll(filepath.Join(repoDir, "frontend", "node_modules", "vue"), 0775)
+	os.WriteFile(filepath.Join(repoDir, "frontend", "node_modules", "vue", "vue.js"), []byte("x"), 0644)
+	os.MkdirAll(filepath.Join(repoDir, "public", "dist"), 0775)
+	os.WriteFile(filepath.Join(repoDir, "public", "dist", "app.js"), []byte("x"), 0644)
+	os.MkdirAll(filepath.Join(repoDir, "dist"), 0775)
+	os.WriteFile(filepath.Join(repoDir, "dist", "keep.js"), []byte("x"), 0644)
+
+	log, _ := logger.NewLogger("", false, false)
+	b := &Builder{
+		repoPath:    repoDir,
+		artifactDir: artifactDir,
+		config:      &config.Environment{CopyExclude: []string{"node_modules", "public/dist"}},
+		log:         log,
+	}
+
+	if err := b.copyEntireRepo(); err != nil {
+		t.Fatalf("copyEntireRepo() error = %v", err)
+	}
+
+	for _, excluded := range []string{"app/node_modules", "app/frontend/node_modules", "app/public/dist"} {
+		if _, err := os.Stat(filepath.Join(artifactDir, excluded)); !os.IsNotExist(err) {
+			t.Errorf("%s should not have been copied", excluded)
+		}
+	}
+	for _, kept := range []string{"app/index.php", "app/dist/keep.js"} {
+		if _, err := os.Stat(filepath.Join(artifactDir, kept)); err != nil {
+			t.Errorf("%s should have been copied: %v", kept, err)
+		}
+	}
+}
+
+func TestBuilder_copyEntireRepo_SkipDirs(t *testing.T) {
+	repoDir := t.TempDir()
+	os.WriteFile(filepath.Join(repoDir, "index.php"), []byte("<?php"), 0644)
+	for _, dir := range []string{".svn", ".idea", "src/.hg"} {
+		os.MkdirAll(filepath.Join(repoDir, dir), 0775)
+		os.WriteFile(filepath.Join(repoDir, dir, "entries"), []byte("x"), 0644)
+	}
+	log, _ := logger.NewLogger("", false, false)
+
+	// Defaults skip VCS and editor directories at any depth
+	artifactDir := t.TempDir()
+	b := &Builder{repoPath: repoDir, artifactDir: artifactDir, config: &config.Environment{}, log: log}
+	if err := b.copyEntireRepo(); err != nil {
+		t.Fatalf("copyEntireRepo() error = %v", err)
+	}
+	for _, skipped := range []string{"app/.svn", "app/.idea", "app/src/.hg"} {
+		if _, err := os.Stat(filepath.Join(artifactDir, skipped)); !os.IsNotExist(err) {
+			t.Errorf("%s should not have been copied", skipped)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(artifactDir, "app/index.php")); err != nil {
+		t.Errorf("index.php should have been copied: %v", err)
+	}
+
+	// An explicit skip_dirs replaces the defaults
+	artifactDir = t.TempDir()
+	b = &Builder{repoPath: repoDir, artifactDir: artifactDir, config: &config.Environment{SkipDirs: []string{".svn"}}, log: log}
+	if err := b.copyEntireRepo(); err != nil {
+		t.Fatalf("copyEntireRepo() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(artifactDir, "app/.svn")); !os.IsNotExist(err) {
+		t.Error(".svn should not have been copied")
+	}
+	if _, err := os.Stat(filepath.Join(artifactDir, "app/.idea/entries")); err != nil {
+		t.Errorf(".idea should have been copied when not in skip_dirs: %v", err)
+	}
+}
+
+func TestBuilder_copyEntireRepo_SymlinkOutsideRepo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require elevated privileges on Windows")
+	}
+
+	repoDir := t.TempDir()
+	artifactDir := t.TempDir()
+	outsideDir := t.TempDir()
+
+	os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("secret"), 0644)
+	os.WriteFile(filepath.Join(repoDir, "real.txt"), []byte("real"), 0644)
+	os.Symlink(filepath.Join(outsideDir, "secret.txt"), filepath.Join(repoDir, "escape.txt"))
+	os.Symlink(outsideDir, filepath.Join(repoDir, "escape-dir"))
+	os.Symlink(filepath.Join(repoDir, "real.txt"), filepath.Join(repoDir, "inside.txt"))
+
+	log, _ := logger.NewLogger("", false, false)
+	b := &Builder{
+		repoPath:    repoDir,
+		artifactDir: artifactDir,
+		log:         log,
+	}
+
+	if err := b.copyEntireRepo(); err != nil {
+		t.Fatalf("copyEntireRepo() error = %v", err)
+	}
+
+	for _, escaped := range []string{"app/escape.txt", "app/escape-dir"} {
+		if _, err := os.Lstat(filepath.Join(artifactDir, escaped)); !os.IsNotExist(err) {
+			t.Errorf("%s points outside the repo and should have been skipped", escaped)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(artifactDir, "app", "inside.txt"))
+	if err != nil {
+		t.Fatalf("symlink inside the repo should be copied: %v", err)
+	}
+	if string(content) != "real" {
+		t.Errorf("expected real, got %s", string(content))
+	}
+}
+
+func TestBuilder_Build_JobsPlaceholder(t *testing.T) {
+	repoDir := t.TempDir()
+	artifactDir := t.TempDir()
+	os.WriteFile(filepath.Join(repoDir, "composer.json"), []byte("{}"), 0644)
+
+	cfg := &config.Environment{
+		Builds: config.BuildsConfig{
+			PHP: config.PHPBuildConfig{
+				Enabled:         true,
+				ComposerCommand: "echo jobs={jobs}> jobs.txt",
+			},
+		},
+	}
+	cs := &changeset.ChangeSet{ComposerChanged: true}
+
+	log, _ := logger.NewLogger("", false, false)
+	b := NewBuilder(repoDir, artifactDir, cfg, cs, log)
+	b.Jobs = 3
+	if _, err := b.Build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(artifactDir, "app", "jobs.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "jobs=3" {
+		t.Errorf("expected {jobs} to expand to 3, got %q", got)
+	}
+}
+
+func TestExpandJobs(t *testing.T) {
+	if got := lang.ExpandJobs("composer install --jobs={jobs}", 4); got != "composer install --jobs=4" {
+		t.Errorf("ExpandJobs() = %q", got)
+	}
+	want := "npm ci --jobs=" + strconv.Itoa(runtime.NumCPU())
+	if got := lang.ExpandJobs("npm ci --jobs={jobs}", 0); got != want {
+		t.Errorf("expected NumCPU when jobs is 0: got %q, want %q", got, want)
+	}
+	if got := lang.ExpandJobs("composer install", 4); got != "composer install" {
+		t.Errorf("expected command without placeholder unchanged, got %q", got)
+	}
+}
+
+func TestBuilder_Build_Env(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	repoDir := t.TempDir()
+	artifactDir := t.TempDir()
+	os.WriteFile(filepath.Join(repoDir, "composer.json"), []byte("{}"), 0644)
+
+	cfg := &config.Environment{
+		Builds: config.BuildsConfig{
+			PHP: config.PHPBuildConfig{
+				Enabled:         true,
+				ComposerCommand: "echo $COMPOSER_MEMORY_LIMIT:$HOME > env.txt",
+				Env:             map[string]string{"COMPOSER_MEMORY_LIMIT": "-1"},
+			},
+		},
+	}
+	cs := &changeset.ChangeSet{ComposerChanged: true}
+
+	log, _ := logger.NewLogger("", false, false)
+	b := NewBuilder(repoDir, artifactDir, cfg, cs, log)
+	if _, err := b.Build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(artifactDir, "app", "env.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The inherited environment is kept alongside the configured variables
+	if got, want := strings.TrimSpace(string(data)), "-1:"+os.Getenv("HOME"); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
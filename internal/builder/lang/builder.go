@@ -1,6 +1,10 @@
 package lang
 
 import (
+	"runtime"
+	"strconv"
+	"strings"
+
 	"github.com/user/versaDeploy/internal/changeset"
 	"github.com/user/versaDeploy/internal/config"
 	"github.com/user/versaDeploy/internal/logger"
@@ -13,6 +17,21 @@ type BuilderContext struct {
 	Config      *config.Environment
 	Changeset   *changeset.ChangeSet
 	Log         *logger.Logger
+	Jobs        int // Value of {jobs} in build commands; 0 uses the number of CPUs
+}
+
+// ExpandJobs replaces the {jobs} placeholder in a build command with jobs, or with
+// the number of CPUs when jobs is 0
+func ExpandJobs(command string, jobs int) string {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	return strings.ReplaceAll(command, "{jobs}", strconv.Itoa(jobs))
+}
+
+// command expands the placeholders a user-configured build command may contain
+func (ctx *BuilderContext) command(command string) string {
+	return ExpandJobs(command, ctx.Jobs)
 }
 
 // LanguageBuilder defines the interface for language-specific build strategies
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 
 	verserrors "github.com/user/versaDeploy/internal/errors"
 )
@@ -30,10 +31,10 @@ func (g *GoBuilder) Build(ctx *BuilderContext) (int, bool, error) {
 	// Prepare build command
 	buildCmd := fmt.Sprintf("GOOS=%s GOARCH=%s go build -o %s", goCfg.TargetOS, goCfg.TargetArch, binaryPath)
 	if goCfg.BuildFlags != "" {
-		buildCmd = fmt.Sprintf("GOOS=%s GOARCH=%s go build %s -o %s", goCfg.TargetOS, goCfg.TargetArch, goCfg.BuildFlags, binaryPath)
+		buildCmd = fmt.Sprintf("GOOS=%s GOARCH=%s go build %s -o %s", goCfg.TargetOS, goCfg.TargetArch, ctx.command(goCfg.BuildFlags), binaryPath)
 	}
 
-	output, err := executeCommand(buildCmd, filepath.Join(ctx.RepoPath, goCfg.ProjectRoot))
+	output, err := executeCommand(buildCmd, filepath.Join(ctx.RepoPath, goCfg.ProjectRoot), goCfg.Env)
 	if err != nil {
 		return 0, false, verserrors.New(verserrors.CodeBuildFailed, "Go build failed", "Check your Go code for compilation errors and ensure all dependencies are resolved.", fmt.Errorf("%w: %s", err, string(output)))
 	}
@@ -46,8 +47,9 @@ func (g *GoBuilder) Build(ctx *BuilderContext) (int, bool, error) {
 	return 0, true, nil
 }
 
-// executeCommand runs a command in a shell based on the current OS
-func executeCommand(command, dir string) ([]byte, error) {
+// executeCommand runs a command in a shell based on the current OS. env is added to the
+// inherited environment, overriding variables of the same name.
+func executeCommand(command, dir string, env map[string]string) ([]byte, error) {
 	var shell, flag string
 	if runtime.GOOS == "windows" {
 		shell = os.Getenv("COMSPEC")
@@ -62,5 +64,24 @@ func executeCommand(command, dir string) ([]byte, error) {
 
 	cmd := exec.Command(shell, flag, command)
 	cmd.Dir = dir
+	cmd.Env = commandEnv(env)
 	return cmd.CombinedOutput()
 }
+
+// commandEnv appends env to the current process environment in a stable order. It
+// returns nil (inherit unchanged) when env is empty.
+func commandEnv(env map[string]string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vars := os.Environ()
+	for _, name := range names {
+		vars = append(vars, name+"="+env[name])
+	}
+	return vars
+}
@@ -31,7 +31,7 @@ func (f *FrontendBuilder) Build(ctx *BuilderContext) (int, bool, error) {
 		ctx.Log.Info("Running npm install...")
 		ctx.Log.Debug("   Working directory: app/%s", ctx.Config.Builds.Frontend.ProjectRoot)
 
-		output, err := executeCommand(ctx.Config.Builds.Frontend.NPMCommand, npmDir)
+		output, err := executeCommand(ctx.command(ctx.Config.Builds.Frontend.NPMCommand), npmDir, ctx.Config.Builds.Frontend.Env)
 		if err != nil {
 			ctx.Log.Debug("NPM output:\n%s", string(output))
 			return 0, false, verserrors.New(verserrors.CodeBuildFailed, "NPM command failed", "Check your package.json and ensure npm/node is installed correctly.", fmt.Errorf("%w: %s", err, string(output)))
@@ -47,7 +47,7 @@ func (f *FrontendBuilder) Build(ctx *BuilderContext) (int, bool, error) {
 			compileDir := filepath.Join(ctx.ArtifactDir, "app", ctx.Config.Builds.Frontend.ProjectRoot)
 			ctx.Log.Debug("   Command: %s", ctx.Config.Builds.Frontend.CompileCommand)
 
-			output, err := executeCommand(ctx.Config.Builds.Frontend.CompileCommand, compileDir)
+			output, err := executeCommand(ctx.command(ctx.Config.Builds.Frontend.CompileCommand), compileDir, ctx.Config.Builds.Frontend.Env)
 			if err != nil {
 				ctx.Log.Debug("Compilation output:\n%s", string(output))
 				return 0, isUpdated, verserrors.New(verserrors.CodeBuildFailed, "Frontend compile failed", "Check your build command.", fmt.Errorf("%w: %s", err, string(output)))
@@ -62,7 +62,7 @@ func (f *FrontendBuilder) Build(ctx *BuilderContext) (int, bool, error) {
 			compileCmd := strings.Replace(ctx.Config.Builds.Frontend.CompileCommand, "{file}", file, -1)
 			compileDir := filepath.Join(ctx.ArtifactDir, "app", ctx.Config.Builds.Frontend.ProjectRoot)
 
-			output, err := executeCommand(compileCmd, compileDir)
+			output, err := executeCommand(ctx.command(compileCmd), compileDir, ctx.Config.Builds.Frontend.Env)
 			if err != nil {
 				ctx.Log.Debug("Compilation output:\n%s", string(output))
 				return filesCompiled, isUpdated, verserrors.New(verserrors.CodeBuildFailed, fmt.Sprintf("Compile failed for %s", file), "Check your custom compiler command and ensure it's correct for this file type.", fmt.Errorf("%w: %s", err, string(output)))
@@ -102,7 +102,7 @@ func (f *FrontendBuilder) cleanupDevDependencies(ctx *BuilderContext) error {
 	ctx.Log.Info("Installing production dependencies...")
 	productionDir := filepath.Join(ctx.ArtifactDir, "app", ctx.Config.Builds.Frontend.ProjectRoot)
 
-	output, err := executeCommand(ctx.Config.Builds.Frontend.ProductionCommand, productionDir)
+	output, err := executeCommand(ctx.command(ctx.Config.Builds.Frontend.ProductionCommand), productionDir, ctx.Config.Builds.Frontend.Env)
 	if err != nil {
 		ctx.Log.Debug("Production install output:\n%s", string(output))
 		return verserrors.New(verserrors.CodeBuildFailed, "Production install failed", "Check your production_command configuration.", fmt.Errorf("%w: %s", err, string(output)))
@@ -19,7 +19,7 @@ func (p *PHPBuilder) Build(ctx *BuilderContext) (int, bool, error) {
 		composerDir := filepath.Join(ctx.ArtifactDir, "app", ctx.Config.Builds.PHP.ProjectRoot)
 		ctx.Log.Debug("   Working directory: app/%s", ctx.Config.Builds.PHP.ProjectRoot)
 
-		output, err := executeCommand(ctx.Config.Builds.PHP.ComposerCommand, composerDir)
+		output, err := executeCommand(ctx.command(ctx.Config.Builds.PHP.ComposerCommand), composerDir, ctx.Config.Builds.PHP.Env)
 		if err != nil {
 			ctx.Log.Debug("Composer output:\n%s", string(output))
 			return 0, false, verserrors.New(verserrors.CodeBuildFailed, "Composer command failed", "Check your composer.json and ensure all dependencies are available locally.", fmt.Errorf("%w: %s", err, string(output)))
@@ -109,7 +109,7 @@ func (p *PythonBuilder) installDependencies(ctx *BuilderContext, appDir string,
 	if installCmd != "" {
 		ctx.Log.Info("Installing Python dependencies with %s...", cfg.PackageManager)
 
-		output, err := executeCommand(installCmd+" "+strings.Join(args, " "), appDir)
+		output, err := executeCommand(installCmd+" "+strings.Join(args, " "), appDir, ctx.Config.Builds.Python.Env)
 		if err != nil {
 			ctx.Log.Debug("Python install output: %s", string(output))
 			return fmt.Errorf("failed to install Python dependencies: %w", err)
@@ -126,7 +126,7 @@ func (p *PythonBuilder) installDependencies(ctx *BuilderContext, appDir string,
 				extraArgs = append(extraArgs, "--extra-index-url", cfg.TorchIndex)
 			}
 
-			output, err := executeCommand(installCmd+" "+strings.Join(extraArgs, " "), appDir)
+			output, err := executeCommand(installCmd+" "+strings.Join(extraArgs, " "), appDir, ctx.Config.Builds.Python.Env)
 			if err != nil {
 				ctx.Log.Debug("Extra requirements install output: %s", string(output))
 				return fmt.Errorf("failed to install extra requirements %s: %w", extraReq, err)
@@ -160,7 +160,7 @@ func (p *PythonBuilder) buildBinary(ctx *BuilderContext, appDir string, cfg conf
 
 	args = append(args, cfg.EntryPoint)
 
-	output, err := executeCommand(pyCmd+" "+strings.Join(args, " "), appDir)
+	output, err := executeCommand(pyCmd+" "+strings.Join(args, " "), appDir, ctx.Config.Builds.Python.Env)
 	if err != nil {
 		ctx.Log.Debug("PyInstaller output: %s", string(output))
 		return fmt.Errorf("failed to build Python binary: %w", err)
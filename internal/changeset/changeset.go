@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,23 +18,26 @@ import (
 
 // ChangeSet represents detected changes
 type ChangeSet struct {
-	PHPFiles            []string
-	TwigFiles           []string
-	GoFiles             []string
-	FrontendFiles       []string
-	PythonFiles         []string
-	ComposerChanged     bool
-	PackageChanged      bool
-	GoModChanged        bool
-	RequirementsChanged bool
-	RoutesChanged       bool
-	OtherFiles          []string          // Files not categorized as PHP, Go, or Frontend
-	AllFileHashes       map[string]string // All current file hashes
-	ComposerHash        string
-	PackageHash         string
-	GoModHash           string
-	RequirementsHash    string
-	Force               bool // If true, ignore change detection and force full build
+	PHPFiles            []string          `json:"php_files"`
+	TwigFiles           []string          `json:"twig_files"`
+	GoFiles             []string          `json:"go_files"`
+	FrontendFiles       []string          `json:"frontend_files"`
+	PythonFiles         []string          `json:"python_files"`
+	ComposerChanged     bool              `json:"composer_changed"`
+	PackageChanged      bool              `json:"package_changed"`
+	GoModChanged        bool              `json:"go_mod_changed"`
+	RequirementsChanged bool              `json:"requirements_changed"`
+	RoutesChanged       bool              `json:"routes_changed"`
+	OtherFiles          []string          `json:"other_files"`   // Files not categorized as PHP, Go, or Frontend
+	DeletedFiles        []string          `json:"deleted_files"` // Files in the previous deploy that no longer exist, sorted
+	AllFileHashes       map[string]string `json:"-"`             // All current file hashes
+	ComposerHash        string            `json:"-"`
+	ComposerLockHash    string            `json:"-"`
+	PackageHash         string            `json:"-"`
+	GoModHash           string            `json:"-"`
+	GoSumHash           string            `json:"-"`
+	RequirementsHash    string            `json:"-"`
+	Force               bool              `json:"force"` // If true, ignore change detection and force full build
 }
 
 // Detector handles change detection
@@ -48,6 +52,9 @@ type Detector struct {
 	pythonRoot       string
 	requirementsFile string
 	previousLock     *state.DeployLock
+
+	// Workers caps the number of concurrent file hashers. 0 uses NumCPU*2.
+	Workers int
 }
 
 // NewDetector creates a new change detector
@@ -83,6 +90,7 @@ func (d *Detector) Detect() (*ChangeSet, error) {
 		GoFiles:       []string{},
 		FrontendFiles: []string{},
 		OtherFiles:    []string{},
+		DeletedFiles:  []string{},
 		AllFileHashes: make(map[string]string),
 	}
 
@@ -132,6 +140,11 @@ func (d *Detector) Detect() (*ChangeSet, error) {
 			if base == "composer.json" || base == "package.json" || base == "composer.lock" || base == "package-lock.json" || base == "pnpm-lock.yaml" || base == "pyproject.toml" || base == "poetry.lock" {
 				isCritical = true
 			}
+		case ".lock":
+			base := filepath.Base(relPath)
+			if base == "composer.lock" || base == "poetry.lock" {
+				isCritical = true
+			}
 		case ".txt":
 			base := filepath.Base(relPath)
 			if base == "requirements.txt" || base == "Pipfile" {
@@ -161,6 +174,9 @@ func (d *Detector) Detect() (*ChangeSet, error) {
 
 	// Concurrent hashing with worker pool
 	numWorkers := runtime.NumCPU() * 2
+	if d.Workers > 0 {
+		numWorkers = d.Workers
+	}
 	if numWorkers > len(filesToHash) {
 		numWorkers = len(filesToHash)
 	}
@@ -255,6 +271,8 @@ func (d *Detector) Detect() (*ChangeSet, error) {
 		}
 	}
 
+	cs.DeletedFiles = d.deletedFiles(cs.AllFileHashes)
+
 	// Check dependency files
 	composerPath := filepath.ToSlash(filepath.Join(d.phpRoot, "composer.json"))
 	composerPath = strings.TrimPrefix(composerPath, "./")
@@ -265,6 +283,15 @@ func (d *Detector) Detect() (*ChangeSet, error) {
 		cs.ComposerChanged = cs.ComposerHash != ""
 	}
 
+	// composer install follows composer.lock, so a lock-only update (composer update)
+	// must reinstall too
+	var lockChanged bool
+	cs.ComposerLockHash, lockChanged = d.lockFileHash(cs.AllFileHashes, d.phpRoot, "composer.lock",
+		func(info state.DeployInfo) string { return info.ComposerLockHash })
+	if lockChanged {
+		cs.ComposerChanged = true
+	}
+
 	packagePath := filepath.ToSlash(filepath.Join(d.frontendRoot, "package.json"))
 	packagePath = strings.TrimPrefix(packagePath, "./")
 	cs.PackageHash = cs.AllFileHashes[packagePath]
@@ -283,6 +310,13 @@ func (d *Detector) Detect() (*ChangeSet, error) {
 		cs.GoModChanged = cs.GoModHash != ""
 	}
 
+	// go.sum pins the dependency versions the binary is built against
+	cs.GoSumHash, lockChanged = d.lockFileHash(cs.AllFileHashes, d.goRoot, "go.sum",
+		func(info state.DeployInfo) string { return info.GoSumHash })
+	if lockChanged {
+		cs.GoModChanged = true
+	}
+
 	// Check Python dependency files
 	requirementsPath := filepath.ToSlash(filepath.Join(d.pythonRoot, d.requirementsFile))
 	requirementsPath = strings.TrimPrefix(requirementsPath, "./")
@@ -306,6 +340,45 @@ func (d *Detector) Detect() (*ChangeSet, error) {
 	return cs, nil
 }
 
+// lockFileHash returns the hash of the dependency lock file name (composer.lock, go.sum)
+// under root and whether it differs from the previous deploy's, read by recorded from its
+// own deploy.lock field. Locks written before that field existed still have the file in
+// file_hashes.
+func (d *Detector) lockFileHash(hashes map[string]string, root, name string, recorded func(state.DeployInfo) string) (string, bool) {
+	path := strings.TrimPrefix(filepath.ToSlash(filepath.Join(root, name)), "./")
+	hash := hashes[path]
+	if hash == "" {
+		return "", false
+	}
+	if d.previousLock == nil {
+		return hash, true
+	}
+	previous := recorded(d.previousLock.LastDeploy)
+	if previous == "" {
+		previous = d.previousLock.LastDeploy.FileHashes[path]
+	}
+	return hash, hash != previous
+}
+
+// deletedFiles returns the files recorded in the previous deploy that are gone now.
+// Files that still exist but are no longer hashed (e.g. newly ignored) don't count.
+func (d *Detector) deletedFiles(current map[string]string) []string {
+	deleted := []string{}
+	if d.previousLock == nil {
+		return deleted
+	}
+	for path := range d.previousLock.LastDeploy.FileHashes {
+		if _, ok := current[path]; ok {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(d.repoPath, filepath.FromSlash(path))); os.IsNotExist(err) {
+			deleted = append(deleted, path)
+		}
+	}
+	sort.Strings(deleted)
+	return deleted
+}
+
 // isFileChanged checks if a file has changed compared to previous deployment
 func (d *Detector) isFileChanged(path, currentHash string) bool {
 	if d.previousLock == nil {
@@ -382,6 +455,7 @@ func (cs *ChangeSet) HasChanges() bool {
 		len(cs.FrontendFiles) > 0 ||
 		len(cs.PythonFiles) > 0 ||
 		len(cs.OtherFiles) > 0 ||
+		len(cs.DeletedFiles) > 0 ||
 		cs.ComposerChanged ||
 		cs.PackageChanged ||
 		cs.GoModChanged ||
@@ -1,6 +1,8 @@
 package changeset
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -148,6 +150,45 @@ func TestDetector_Detect(t *testing.T) {
 	}
 }
 
+func TestDetector_Detect_DeletedFiles(t *testing.T) {
+	repoDir := t.TempDir()
+	os.MkdirAll(filepath.Join(repoDir, "app"), 0775)
+	os.MkdirAll(filepath.Join(repoDir, "ignored"), 0775)
+	os.WriteFile(filepath.Join(repoDir, "app/main.php"), []byte("<?php"), 0644)
+	os.WriteFile(filepath.Join(repoDir, "ignored/notes.txt"), []byte("still here"), 0644)
+
+	detector := NewDetector(repoDir, []string{"ignored"}, nil, "", "", "", "", "requirements.txt", nil)
+	cs, err := detector.Detect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.DeletedFiles) != 0 {
+		t.Errorf("expected no deleted files on first deploy, got %v", cs.DeletedFiles)
+	}
+
+	previousLock := &state.DeployLock{
+		LastDeploy: state.DeployInfo{
+			FileHashes: map[string]string{
+				"app/main.php":      cs.AllFileHashes["app/main.php"],
+				"app/Old.php":       "sha256:old",
+				"app/a/Removed.php": "sha256:old",
+				"ignored/notes.txt": "sha256:old", // now ignored, but still on disk
+			},
+		},
+	}
+	detector = NewDetector(repoDir, []string{"ignored"}, nil, "", "", "", "", "requirements.txt", previousLock)
+	cs2, err := detector.Detect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(cs2.DeletedFiles) != "[app/Old.php app/a/Removed.php]" {
+		t.Errorf("expected sorted deleted files, got %v", cs2.DeletedFiles)
+	}
+	if !cs2.HasChanges() {
+		t.Error("expected deletions to count as changes")
+	}
+}
+
 func TestDetector_Detect_IgnoredButCritical(t *testing.T) {
 	repoDir := t.TempDir()
 
@@ -177,10 +218,12 @@ func TestDetector_Detect_IgnoredButCritical(t *testing.T) {
 func (cs *ChangeSet) AllFileHashesAsLock() *state.DeployLock {
 	return &state.DeployLock{
 		LastDeploy: state.DeployInfo{
-			FileHashes:      cs.AllFileHashes,
-			GoModHash:       cs.GoModHash,
-			ComposerHash:    cs.ComposerHash,
-			PackageJSONHash: cs.PackageHash,
+			FileHashes:       cs.AllFileHashes,
+			GoModHash:        cs.GoModHash,
+			GoSumHash:        cs.GoSumHash,
+			ComposerHash:     cs.ComposerHash,
+			ComposerLockHash: cs.ComposerLockHash,
+			PackageJSONHash:  cs.PackageHash,
 		},
 	}
 }
@@ -199,3 +242,125 @@ func TestDetector_Detect_Fail(t *testing.T) {
 		t.Error("expected error for non-existent repo path")
 	}
 }
+
+func TestDetector_Detect_SingleWorker(t *testing.T) {
+	repoDir := t.TempDir()
+	for i := 0; i < 10; i++ {
+		os.WriteFile(filepath.Join(repoDir, fmt.Sprintf("file%d.php", i)), []byte(fmt.Sprintf("<?php %d", i)), 0644)
+	}
+
+	detector := NewDetector(repoDir, nil, nil, "", "", "", "", "requirements.txt", nil)
+	detector.Workers = 1
+	cs, err := detector.Detect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cs.PHPFiles) != 10 {
+		t.Errorf("expected 10 PHP files with a single worker, got %d", len(cs.PHPFiles))
+	}
+}
+
+func TestChangeSet_JSON(t *testing.T) {
+	cs := &ChangeSet{
+		PHPFiles:        []string{"app/User.php"},
+		ComposerChanged: true,
+		AllFileHashes:   map[string]string{"app/User.php": "abc"},
+		ComposerHash:    "def",
+	}
+	data, err := json.Marshal(cs)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	for _, key := range []string{"php_files", "twig_files", "go_files", "frontend_files", "python_files", "other_files",
+		"composer_changed", "package_changed", "go_mod_changed", "requirements_changed", "routes_changed", "force"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("expected %q in JSON, got %s", key, data)
+		}
+	}
+	for _, key := range []string{"AllFileHashes", "ComposerHash"} {
+		if _, ok := fields[key]; ok {
+			t.Errorf("expected %q to be left out of the JSON, got %s", key, data)
+		}
+	}
+	if fields["composer_changed"] != true {
+		t.Errorf("expected composer_changed true, got %v", fields["composer_changed"])
+	}
+}
+
+func TestDetector_Detect_LockFileChanged(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		files    map[string]string // manifest and lock file, the lock file last
+		phpRoot  string
+		goRoot   string
+		hash     func(cs *ChangeSet) string
+		changed  func(cs *ChangeSet) bool
+		unrecord func(lock *state.DeployLock) // drop the lock file's own deploy.lock field
+	}{
+		{
+			name:     "composer.lock",
+			files:    map[string]string{"api/composer.json": `{"require":{}}`, "api/composer.lock": `{"packages":[]}`},
+			phpRoot:  "api",
+			hash:     func(cs *ChangeSet) string { return cs.ComposerLockHash },
+			changed:  func(cs *ChangeSet) bool { return cs.ComposerChanged },
+			unrecord: func(lock *state.DeployLock) { lock.LastDeploy.ComposerLockHash = "" },
+		},
+		{
+			name:     "go.sum",
+			files:    map[string]string{"app/go.mod": "module example.com/app\n", "app/go.sum": "example.com/dep v1.0.0 h1:a=\n"},
+			goRoot:   "app",
+			hash:     func(cs *ChangeSet) string { return cs.GoSumHash },
+			changed:  func(cs *ChangeSet) bool { return cs.GoModChanged },
+			unrecord: func(lock *state.DeployLock) { lock.LastDeploy.GoSumHash = "" },
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			repoDir := t.TempDir()
+			var lockFile string
+			for path, content := range tt.files {
+				os.MkdirAll(filepath.Join(repoDir, filepath.Dir(path)), 0775)
+				os.WriteFile(filepath.Join(repoDir, path), []byte(content), 0644)
+				if filepath.Base(path) == tt.name {
+					lockFile = path
+				}
+			}
+			detect := func(previous *state.DeployLock) *ChangeSet {
+				t.Helper()
+				cs, err := NewDetector(repoDir, nil, nil, tt.phpRoot, tt.goRoot, "", "", "requirements.txt", previous).Detect()
+				if err != nil {
+					t.Fatal(err)
+				}
+				return cs
+			}
+
+			cs := detect(nil)
+			if tt.hash(cs) == "" || tt.hash(cs) != cs.AllFileHashes[lockFile] {
+				t.Fatalf("expected %s hash, got %q", tt.name, tt.hash(cs))
+			}
+
+			// Nothing changed
+			if tt.changed(detect(cs.AllFileHashesAsLock())) {
+				t.Error("expected no dependency change")
+			}
+
+			// Only the lock file changed (dependency update without touching the manifest)
+			os.WriteFile(filepath.Join(repoDir, lockFile), []byte("updated\n"), 0644)
+			cs3 := detect(cs.AllFileHashesAsLock())
+			if !tt.changed(cs3) {
+				t.Errorf("expected a %s change to count as a dependency change", tt.name)
+			}
+
+			// A lock without the lock file's own field falls back to file_hashes
+			legacy := cs3.AllFileHashesAsLock()
+			tt.unrecord(legacy)
+			if tt.changed(detect(legacy)) {
+				t.Error("expected no dependency change against an older lock with the same file hash")
+			}
+		})
+	}
+}
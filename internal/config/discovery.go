@@ -1,6 +1,7 @@
 package config
 
 import (
+	"os"
 	"path/filepath"
 )
 
@@ -39,3 +40,34 @@ func FindConfigFiles(dir string) ([]string, error) {
 	}
 	return matches, nil
 }
+
+// FindConfigFilesUpward walks from dir towards the filesystem root, the same way git
+// looks for .git, and returns the config files of the first directory that has any.
+// The walk stops at the repository root (a directory containing .git) so a config
+// belonging to an unrelated parent project is never picked up.
+func FindConfigFilesUpward(dir string) ([]string, error) {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		files, err := FindConfigFiles(current)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 {
+			return files, nil
+		}
+
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return nil, nil
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return nil, nil
+		}
+		current = parent
+	}
+}
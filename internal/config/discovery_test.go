@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindConfigFilesUpward(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".git"), 0755)
+	os.WriteFile(filepath.Join(root, "deploy.yml"), []byte("project: x"), 0644)
+
+	nested := filepath.Join(root, "src", "app", "controllers")
+	os.MkdirAll(nested, 0755)
+
+	files, err := FindConfigFilesUpward(nested)
+	if err != nil {
+		t.Fatalf("FindConfigFilesUpward failed: %v", err)
+	}
+	if len(files) != 1 || files[0] != filepath.Join(root, "deploy.yml") {
+		t.Errorf("expected %s, got %v", filepath.Join(root, "deploy.yml"), files)
+	}
+}
+
+func TestFindConfigFilesUpward_NearestWins(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "deploy.yml"), []byte("project: outer"), 0644)
+
+	inner := filepath.Join(root, "service")
+	os.MkdirAll(filepath.Join(inner, "cmd"), 0755)
+	os.WriteFile(filepath.Join(inner, "deploy_staging.yml"), []byte("project: inner"), 0644)
+
+	files, err := FindConfigFilesUpward(filepath.Join(inner, "cmd"))
+	if err != nil {
+		t.Fatalf("FindConfigFilesUpward failed: %v", err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "deploy_staging.yml" {
+		t.Errorf("expected the nearest config, got %v", files)
+	}
+}
+
+func TestFindConfigFilesUpward_StopsAtRepoRoot(t *testing.T) {
+	outer := t.TempDir()
+	os.WriteFile(filepath.Join(outer, "deploy.yml"), []byte("project: unrelated"), 0644)
+
+	repo := filepath.Join(outer, "repo")
+	os.MkdirAll(filepath.Join(repo, ".git"), 0755)
+	os.MkdirAll(filepath.Join(repo, "sub"), 0755)
+
+	files, err := FindConfigFilesUpward(filepath.Join(repo, "sub"))
+	if err != nil {
+		t.Fatalf("FindConfigFilesUpward failed: %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("expected no config outside the repository, got %v", files)
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Dump renders the effective configuration (after environment variable interpolation
+// and defaults) as "yaml" or "json". A non-empty envName limits it to that
+// environment. Webhook URLs, which usually embed a token, are redacted; key files are
+// shown by path only.
+func (c *Config) Dump(envName, format string) ([]byte, error) {
+	out := Config{Project: c.Project, Environments: map[string]Environment{}}
+	for name, env := range c.Environments {
+		if envName != "" && name != envName {
+			continue
+		}
+		env.Notifications.WebhookURL = redactURL(env.Notifications.WebhookURL)
+		out.Environments[name] = env
+	}
+	if envName != "" && len(out.Environments) == 0 {
+		return nil, fmt.Errorf("environment %q not found in config", envName)
+	}
+
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render config: %w", err)
+	}
+
+	switch format {
+	case "", "yaml":
+		return data, nil
+	case "json":
+		// Round-trip through YAML so JSON uses the same snake_case keys as deploy.yml
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to render config: %w", err)
+		}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to render config: %w", err)
+		}
+		return append(data, '\n'), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q: use yaml or json", format)
+	}
+}
+
+// redactURL keeps the scheme and host of a URL and hides the rest
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "<redacted>"
+	}
+	return u.Scheme + "://" + u.Host + "/<redacted>"
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// sshHostEntry holds the settings ~/.ssh/config resolves for a host alias
+type sshHostEntry struct {
+	HostName     string
+	User         string
+	Port         int
+	IdentityFile string
+	ProxyJump    string
+}
+
+// lookupSSHConfig resolves alias against the OpenSSH client config at path. Like
+// ssh, the first value found for each keyword wins, so specific Host blocks must
+// come before wildcard ones. Match blocks and Include are not supported and are
+// skipped. A missing file resolves to an empty entry.
+func lookupSSHConfig(path, alias string) (sshHostEntry, error) {
+	var entry sshHostEntry
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entry, nil
+		}
+		return entry, fmt.Errorf("failed to read ssh config: %w", err)
+	}
+	defer f.Close()
+
+	matching := true // Settings before the first Host line apply to every host
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		keyword, args := splitSSHConfigLine(scanner.Text())
+		if keyword == "" {
+			continue
+		}
+
+		switch keyword {
+		case "host":
+			matching = sshHostMatches(alias, args)
+			continue
+		case "match":
+			matching = false
+			continue
+		}
+		if !matching || len(args) == 0 {
+			continue
+		}
+
+		value := args[0]
+		switch keyword {
+		case "hostname":
+			if entry.HostName == "" {
+				entry.HostName = strings.ReplaceAll(value, "%h", alias)
+			}
+		case "user":
+			if entry.User == "" {
+				entry.User = value
+			}
+		case "port":
+			if entry.Port == 0 {
+				port, err := strconv.Atoi(value)
+				if err != nil || port <= 0 || port > 65535 {
+					return entry, fmt.Errorf("%s:%d: invalid Port %q", path, lineNo, value)
+				}
+				entry.Port = port
+			}
+		case "identityfile":
+			if entry.IdentityFile == "" {
+				entry.IdentityFile = value
+			}
+		case "proxyjump":
+			if entry.ProxyJump == "" {
+				entry.ProxyJump = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return entry, fmt.Errorf("failed to read ssh config: %w", err)
+	}
+	return entry, nil
+}
+
+// splitSSHConfigLine returns the lower-cased keyword and the arguments of an ssh
+// config line, accepting both "Keyword value" and "Keyword=value" forms
+func splitSSHConfigLine(line string) (string, []string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil
+	}
+
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return strings.ToLower(line), nil
+	}
+	keyword := line[:i]
+	rest := strings.TrimLeft(line[i:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+
+	// Split on whitespace, keeping double-quoted arguments (paths with spaces) whole
+	var args []string
+	var current strings.Builder
+	inQuotes, inArg := false, false
+	for _, r := range rest {
+		switch {
+		case r == '"':
+			inQuotes, inArg = !inQuotes, true
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return strings.ToLower(keyword), args
+}
+
+// sshHostMatches reports whether alias matches a Host line's patterns. Patterns
+// support * and ?, and a matching !pattern excludes the host.
+func sshHostMatches(alias string, patterns []string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		if !sshPattern(strings.TrimPrefix(pattern, "!")).MatchString(alias) {
+			continue
+		}
+		if negate {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// sshPattern compiles an ssh_config host pattern into an anchored regexp
+func sshPattern(pattern string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.MustCompile("^(?i:" + quoted + ")$")
+}
+
+// applySSHConfig fills the ssh settings left empty in deploy.yml from the matching
+// ~/.ssh/config entry (or ssh_config_file). Explicit values always win; host
+// becomes the entry's HostName and a ProxyJump chain becomes jump_host.
+func (s *SSHConfig) applySSHConfig() error {
+	path := s.SSHConfigFile
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to locate ~/.ssh/config: %w", err)
+		}
+		path = filepath.Join(home, ".ssh", "config")
+	} else if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to expand home directory: %w", err)
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	entry, err := lookupSSHConfig(path, s.Host)
+	if err != nil {
+		return err
+	}
+
+	if entry.HostName != "" {
+		s.Host = entry.HostName
+	}
+	if s.User == "" {
+		s.User = entry.User
+	}
+	if s.Port == 0 {
+		s.Port = entry.Port
+	}
+	if s.KeyPath == "" {
+		s.KeyPath = entry.IdentityFile
+	}
+	if s.JumpHost == "" && entry.ProxyJump != "" && !strings.EqualFold(entry.ProxyJump, "none") {
+		if s.JumpHost, err = resolveProxyJump(path, entry.ProxyJump); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveProxyJump rewrites each hop of a ProxyJump chain through the ssh config,
+// so bastion aliases turn into the user@hostname:port they stand for
+func resolveProxyJump(path, chain string) (string, error) {
+	var hops []string
+	for _, hop := range strings.Split(chain, ",") {
+		hop = strings.TrimPrefix(strings.TrimSpace(hop), "ssh://")
+		user, host, hasUser := strings.Cut(hop, "@")
+		if !hasUser {
+			user, host = "", hop
+		}
+		alias, port := host, 0
+		if h, p, err := net.SplitHostPort(host); err == nil {
+			alias = h
+			if port, err = strconv.Atoi(p); err != nil {
+				return "", fmt.Errorf("invalid ProxyJump hop %q", hop)
+			}
+		}
+
+		entry, err := lookupSSHConfig(path, alias)
+		if err != nil {
+			return "", err
+		}
+		if entry.HostName != "" {
+			alias = entry.HostName
+		}
+		if user == "" {
+			user = entry.User
+		}
+		if port == 0 {
+			port = entry.Port
+		}
+
+		resolved := alias
+		if port != 0 {
+			resolved = net.JoinHostPort(alias, strconv.Itoa(port))
+		}
+		if user != "" {
+			resolved = user + "@" + resolved
+		}
+		hops = append(hops, resolved)
+	}
+	return strings.Join(hops, ","), nil
+}
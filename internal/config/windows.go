@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // deploy window time zones must resolve on hosts without zoneinfo (e.g. Windows)
+)
+
+// DeployWindowsConfig restricts the times at which deploys may start
+type DeployWindowsConfig struct {
+	Timezone string   `yaml:"timezone"` // IANA time zone the windows are written in (default: local time)
+	Allow    []string `yaml:"allow"`    // Allowed windows, e.g. "mon-thu 09:00-17:00", "fri 09:00-12:00" or "sat"
+}
+
+// deployWindow is a parsed deploy_windows entry: a set of weekdays and a daily
+// [start, end) range in minutes after midnight
+type deployWindow struct {
+	days       [7]bool
+	start, end int
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseDeployWindow parses "<days> [HH:MM-HH:MM]". Days are a comma-separated list of
+// names or ranges (mon-thu, fri-mon wraps over the weekend); without hours the whole day is allowed.
+func parseDeployWindow(spec string) (deployWindow, error) {
+	var w deployWindow
+	fields := strings.Fields(strings.ToLower(spec))
+	if len(fields) == 0 || len(fields) > 2 {
+		return w, fmt.Errorf("invalid window %q: expected '<days> [HH:MM-HH:MM]'", spec)
+	}
+
+	for _, part := range strings.Split(fields[0], ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		first, ok1 := weekdays[from]
+		last, ok2 := weekdays[to]
+		if !ok1 || !ok2 {
+			return w, fmt.Errorf("invalid window %q: unknown day in %q (use mon, tue, wed, thu, fri, sat, sun)", spec, part)
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			w.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+
+	w.start, w.end = 0, 24*60
+	if len(fields) == 2 {
+		from, to, ok := strings.Cut(fields[1], "-")
+		if !ok {
+			return w, fmt.Errorf("invalid window %q: hours must look like 09:00-17:00", spec)
+		}
+		var err error
+		if w.start, err = parseClock(from); err != nil {
+			return w, fmt.Errorf("invalid window %q: %w", spec, err)
+		}
+		if w.end, err = parseClock(to); err != nil {
+			return w, fmt.Errorf("invalid window %q: %w", spec, err)
+		}
+		if w.end <= w.start {
+			return w, fmt.Errorf("invalid window %q: end time must be after start time", spec)
+		}
+	}
+	return w, nil
+}
+
+// parseClock converts "HH:MM" (00:00-24:00) to minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err == nil {
+		return t.Hour()*60 + t.Minute(), nil
+	}
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+}
+
+// contains reports whether t (already in the window's time zone) is inside the window
+func (w deployWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	return w.days[t.Weekday()] && minute >= w.start && minute < w.end
+}
+
+// location resolves Timezone, defaulting to local time
+func (c DeployWindowsConfig) location() (*time.Location, error) {
+	if c.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", c.Timezone)
+	}
+	return loc, nil
+}
+
+// validate checks the time zone and every window
+func (c DeployWindowsConfig) validate() error {
+	if _, err := c.location(); err != nil {
+		return err
+	}
+	for _, spec := range c.Allow {
+		if _, err := parseDeployWindow(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Allows reports whether a deploy may start at t. Every time is allowed when no
+// windows are configured.
+func (c DeployWindowsConfig) Allows(t time.Time) (bool, error) {
+	if len(c.Allow) == 0 {
+		return true, nil
+	}
+	loc, err := c.location()
+	if err != nil {
+		return false, err
+	}
+	t = t.In(loc)
+	for _, spec := range c.Allow {
+		w, err := parseDeployWindow(spec)
+		if err != nil {
+			return false, err
+		}
+		if w.contains(t) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
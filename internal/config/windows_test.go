@@ -0,0 +1,73 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeployWindow(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{"mon-thu 09:00-17:00", false},
+		{"fri", false},
+		{"fri-mon 22:00-24:00", false},
+		{"mon,wed,fri 10:00-11:30", false},
+		{"", true},
+		{"funday 09:00-17:00", true},
+		{"mon 17:00-09:00", true},
+		{"mon 9am-5pm", true},
+		{"mon 09:00-17:00 extra", true},
+	}
+	for _, tt := range tests {
+		if _, err := parseDeployWindow(tt.spec); (err != nil) != tt.wantErr {
+			t.Errorf("parseDeployWindow(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+		}
+	}
+
+	w, _ := parseDeployWindow("fri-mon")
+	for day, want := range map[time.Weekday]bool{time.Friday: true, time.Sunday: true, time.Monday: true, time.Tuesday: false} {
+		if w.days[day] != want {
+			t.Errorf("fri-mon: day %s = %v, want %v", day, w.days[day], want)
+		}
+	}
+}
+
+func TestDeployWindowsConfig_Allows(t *testing.T) {
+	cfg := DeployWindowsConfig{
+		Timezone: "Europe/Madrid",
+		Allow:    []string{"mon-thu 09:00-17:00", "fri 09:00-12:00"},
+	}
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"tuesday morning", time.Date(2026, 10, 13, 10, 0, 0, 0, madrid), true},
+		{"thursday at close", time.Date(2026, 10, 15, 17, 0, 0, 0, madrid), false},
+		{"friday morning", time.Date(2026, 10, 16, 11, 59, 0, 0, madrid), true},
+		{"friday afternoon", time.Date(2026, 10, 16, 15, 0, 0, 0, madrid), false},
+		{"saturday", time.Date(2026, 10, 17, 10, 0, 0, 0, madrid), false},
+		// 08:30 UTC is 10:30 in Madrid (CEST)
+		{"converted from UTC", time.Date(2026, 10, 13, 8, 30, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		got, err := cfg.Allows(tt.at)
+		if err != nil {
+			t.Fatalf("%s: Allows() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Allows() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if ok, _ := (DeployWindowsConfig{}).Allows(time.Now()); !ok {
+		t.Error("expected every time allowed without windows")
+	}
+	if err := (DeployWindowsConfig{Timezone: "Mars/Olympus"}).validate(); err == nil {
+		t.Error("expected error for unknown timezone")
+	}
+}
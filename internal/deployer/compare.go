@@ -0,0 +1,143 @@
+package deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/user/versaDeploy/internal/artifact"
+	verserrors "github.com/user/versaDeploy/internal/errors"
+	"github.com/user/versaDeploy/internal/git"
+	"github.com/user/versaDeploy/internal/ssh"
+	"github.com/user/versaDeploy/internal/state"
+)
+
+// ReleaseComparison is what changed between two releases on a server: their manifests,
+// the commits between them and, when both releases carry a per-file list, which files
+// were added, removed or modified.
+type ReleaseComparison struct {
+	From         string            `json:"from"`
+	To           string            `json:"to"`
+	FromManifest artifact.Manifest `json:"from_manifest"`
+	ToManifest   artifact.Manifest `json:"to_manifest"`
+	// Commits is the number of commits from FromManifest's commit to ToManifest's, or -1
+	// when the local repository doesn't have both
+	Commits int `json:"commits"`
+	// FileSource is where the file lists come from: "files.json" (the shipped files),
+	// "deploy.lock" (the repository files) or "" when a release has neither
+	FileSource string   `json:"file_source,omitempty"`
+	Added      []string `json:"added,omitempty"`
+	Removed    []string `json:"removed,omitempty"`
+	Modified   []string `json:"modified,omitempty"`
+}
+
+// compareFileHashes splits two path→hash maps into added, removed and modified paths
+func compareFileHashes(from, to map[string]string) (added, removed, modified []string) {
+	for p, hash := range to {
+		old, ok := from[p]
+		switch {
+		case !ok:
+			added = append(added, p)
+		case old != hash:
+			modified = append(modified, p)
+		}
+	}
+	for p := range from {
+		if _, ok := to[p]; !ok {
+			removed = append(removed, p)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(modified)
+	return added, removed, modified
+}
+
+// releaseFileHashes reads the per-file hashes of a release from source: its files.json
+// inventory (the shipped files) or its deploy.lock snapshot (the repository files).
+// Returns nil when the release doesn't have that file.
+func releaseFileHashes(sshClient *ssh.Client, releaseDir, source string) map[string]string {
+	data, err := sshClient.ReadRemoteBytes(filepath.ToSlash(filepath.Join(releaseDir, source)), maxLockBytes)
+	if err != nil {
+		return nil
+	}
+	if source == "deploy.lock" {
+		lock, err := state.Parse(data)
+		if err != nil || len(lock.LastDeploy.FileHashes) == 0 {
+			return nil
+		}
+		return lock.LastDeploy.FileHashes
+	}
+	files := make(map[string]string)
+	if json.Unmarshal(data, &files) != nil {
+		return nil
+	}
+	return files
+}
+
+// CompareReleases downloads the manifests (and per-file lists, when present) of two
+// releases and returns their delta. It only reads from the server.
+func (d *Deployer) CompareReleases(from, to string) (*ReleaseComparison, error) {
+	sshClient, err := ssh.NewClient(&d.env.SSH, d.log)
+	if err != nil {
+		return nil, verserrors.Wrap(err)
+	}
+	defer sshClient.Close()
+
+	releasesDir := filepath.ToSlash(filepath.Join(d.env.RemotePath, "releases"))
+	releases, err := sshClient.ListReleases(releasesDir)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(releases))
+	for _, r := range releases {
+		known[r] = true
+	}
+	for _, r := range []string{from, to} {
+		if !known[r] {
+			state.SortReleases(releases)
+			return nil, fmt.Errorf("release %s not found on %s (available: %s)", r, d.envName, strings.Join(releases, ", "))
+		}
+	}
+
+	cmp := &ReleaseComparison{From: from, To: to, Commits: -1}
+	for _, side := range []struct {
+		release  string
+		manifest *artifact.Manifest
+	}{{from, &cmp.FromManifest}, {to, &cmp.ToManifest}} {
+		data, err := sshClient.ReadRemoteBytes(filepath.ToSlash(filepath.Join(releasesDir, side.release, "manifest.json")), 1<<20)
+		if err != nil {
+			return nil, fmt.Errorf("release %s has no readable manifest.json: %w", side.release, err)
+		}
+		m, err := artifact.ParseManifest(data)
+		if err != nil {
+			return nil, fmt.Errorf("release %s: %w", side.release, err)
+		}
+		*side.manifest = *m
+	}
+
+	if cmp.FromManifest.CommitHash != "" && cmp.ToManifest.CommitHash != "" {
+		if n, err := git.CountCommits(d.repoPath, cmp.FromManifest.CommitHash, cmp.ToManifest.CommitHash); err == nil {
+			cmp.Commits = n
+		} else {
+			d.log.Debug("Commit range not available locally: %v", err)
+		}
+	}
+
+	// Both sides must come from the same kind of list for the hashes to be comparable
+	for _, source := range []string{artifact.FileInventoryName, "deploy.lock"} {
+		fromFiles := releaseFileHashes(sshClient, filepath.ToSlash(filepath.Join(releasesDir, from)), source)
+		toFiles := releaseFileHashes(sshClient, filepath.ToSlash(filepath.Join(releasesDir, to)), source)
+		if fromFiles != nil && toFiles != nil {
+			cmp.FileSource = source
+			cmp.Added, cmp.Removed, cmp.Modified = compareFileHashes(fromFiles, toFiles)
+			break
+		}
+	}
+	if cmp.FileSource == "" {
+		d.log.Debug("No per-file lists in both %s and %s; only manifests are compared", from, to)
+	}
+	return cmp, nil
+}
@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -37,11 +43,69 @@ type Deployer struct {
 	initialDeploy  bool
 	force          bool
 	skipDirtyCheck bool
+	commitOverride string
 	log            *logger.Logger
 
+	windowOverridden bool            // set when OverrideWindow was actually needed
+	dirtyTree        bool            // set when AllowDirty deploys uncommitted changes
+	skipLocalHooks   bool            // set by TestBuild unless pre_deploy_local hooks were asked for
+	ctx              context.Context // parent of the deploy_timeout context (see SetContext)
+
 	// PostDeployConfirm is called before post_deploy hooks on an initial deploy.
-	// Return true to run hooks, false to skip them. If nil, hooks always run.
+	// Return true to run them, false to skip them. If nil, they always run.
+	// first_deploy hooks are not affected.
 	PostDeployConfirm func() bool
+
+	// SameCommitConfirm is called when the commit being deployed is already the live
+	// one and only a duplicate release would result. Return true to deploy anyway. If
+	// nil, such deploys are refused unless --force is set.
+	SameCommitConfirm func() bool
+
+	// StrictSize fails the deploy, instead of only warning, when the artifact is
+	// larger than max_artifact_size_mb.
+	StrictSize bool
+
+	// OverrideWindow lets a deploy start outside the environment's deploy_windows.
+	// The override is logged and recorded in deploy.lock.
+	OverrideWindow bool
+
+	// SkipDiskCheck bypasses the pre-upload free disk space check, like the
+	// environment's skip_disk_check.
+	SkipDiskCheck bool
+
+	// Trace times each major step of Deploy and logs the breakdown at the end,
+	// whether the deploy succeeded or not.
+	Trace bool
+
+	// BuildJobs is the value of {jobs} in build commands (e.g. from --build-jobs);
+	// 0 uses the number of CPUs.
+	BuildJobs int
+
+	// AllowDirty deploys the working tree as-is, uncommitted changes included,
+	// instead of refusing to deploy from a dirty tree. The release is recorded as
+	// dirty in its manifest.
+	AllowDirty bool
+
+	// ShallowClone clones only the checked-out commit (git clone --depth 1) instead of
+	// the full history, which is faster for repositories with a large history.
+	ShallowClone bool
+
+	// TempDir overrides the environment's temp_dir as the local scratch space for
+	// the clone, artifact, archive chunks and lock files (e.g. from --temp-dir).
+	TempDir string
+
+	// CheckRemote makes a dry run also check write access and free disk space on the
+	// server, on top of the connection, lock and remote tool checks it always does.
+	CheckRemote bool
+
+	// Message is a free-form note for this deploy (versa deploy -m), recorded in
+	// deploy.lock and the manifest and shown next to the release in status.
+	Message string
+
+	// ServerIndex and PrimaryIndex place this deploy within a multi-server rollout
+	// (0-based). Hooks are filtered by their run_on; both zero is a single-server deploy.
+	ServerIndex  int
+	PrimaryIndex int
 }
 
 // NewDeployer creates a new deployer
@@ -64,17 +128,245 @@ func NewDeployer(cfg *config.Config, envName, repoPath string, dryRun, initialDe
 	}, nil
 }
 
+// SetContext sets a context whose cancellation aborts Deploy at the next step
+// boundary before the release goes live (used by deploy-all --fail-fast)
+func (d *Deployer) SetContext(ctx context.Context) {
+	d.ctx = ctx
+}
+
+// parentContext returns the context set by SetContext, or context.Background()
+func (d *Deployer) parentContext() context.Context {
+	if d.ctx != nil {
+		return d.ctx
+	}
+	return context.Background()
+}
+
+// SetConcurrency overrides the environment's concurrency setting (e.g. from --concurrency).
+// Values <= 0 keep the configured value.
+func (d *Deployer) SetConcurrency(n int) {
+	if n > 0 {
+		d.env.Concurrency = n
+	}
+}
+
+// SetRemotePath overrides the environment's remote_path for this run (e.g. from
+// --remote-path). Releases, shared, current and the locks all live under it.
+func (d *Deployer) SetRemotePath(remotePath string) error {
+	remotePath = strings.TrimSpace(remotePath)
+	if remotePath == "" {
+		return nil
+	}
+	if !strings.HasPrefix(remotePath, "/") && !strings.Contains(remotePath, ":") {
+		return fmt.Errorf("invalid remote path %q: must be an absolute path", remotePath)
+	}
+	d.env.RemotePath = filepath.ToSlash(filepath.Clean(remotePath))
+	return nil
+}
+
+// checkWorkingTree refuses to deploy from a working tree with uncommitted changes,
+// unless skipDirtyCheck (deploy HEAD anyway) or AllowDirty (deploy the changes) is set.
+func (d *Deployer) checkWorkingTree() error {
+	if d.skipDirtyCheck && !d.AllowDirty {
+		d.log.Warn("Skipping clean working directory check (--skip-dirty-check active)")
+		return nil
+	}
+	clean, err := git.IsClean(d.repoPath, d.ownRepoPaths()...)
+	if err != nil {
+		return err
+	}
+	if clean {
+		return nil
+	}
+	if !d.AllowDirty {
+		return verserrors.Wrap(fmt.Errorf("working directory has uncommitted changes (use --skip-dirty-check to deploy HEAD, or --allow-dirty to deploy them)"))
+	}
+	d.dirtyTree = true
+	d.log.Warn("!!! DEPLOYING UNCOMMITTED CHANGES (--allow-dirty): this release does not match any commit and cannot be reproduced !!!")
+	return nil
+}
+
+// cloneOptions returns how the repository is cloned for a build
+func (d *Deployer) cloneOptions() git.CloneOptions {
+	opts := git.CloneOptions{Submodules: d.env.GitSubmodules}
+	if d.ShallowClone {
+		opts.Depth = 1
+	}
+	return opts
+}
+
+// cloneRepo clones the repository into the temp directory, fetching Git LFS content
+// when git_lfs is set and copying the uncommitted changes over when deploying a dirty tree
+func (d *Deployer) cloneRepo() (string, error) {
+	if !d.env.GitSubmodules && git.HasSubmodules(d.repoPath) {
+		d.log.Warn("Repository has submodules (.gitmodules) but git_submodules is off: submodule directories will be deployed empty")
+	}
+	usesLFS := git.UsesLFS(d.repoPath)
+	if !d.env.GitLFS && usesLFS {
+		d.log.Warn("Repository tracks files with Git LFS (.gitattributes) but git_lfs is off: LFS files may be deployed as pointer files")
+	}
+
+	d.log.Info("Cloning repository to temporary directory...")
+	tmpRepo, err := git.CloneIn(d.tempDir(), d.repoPath, d.cloneOptions())
+	if err != nil {
+		return "", err
+	}
+
+	if d.env.GitLFS && usesLFS {
+		d.log.Info("Fetching Git LFS files...")
+		if err := git.LFSPull(tmpRepo); err != nil {
+			os.RemoveAll(tmpRepo)
+			return "", err
+		}
+	}
+
+	if d.dirtyTree {
+		d.log.Info("Copying uncommitted changes over the clone...")
+		if err := git.OverlayWorkingTree(tmpRepo, d.repoPath, d.ownRepoPaths()...); err != nil {
+			os.RemoveAll(tmpRepo)
+			return "", err
+		}
+	}
+	return tmpRepo, nil
+}
+
+// commitSHA matches a full SHA-1 or SHA-256 git object name
+var commitSHA = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// SetCommit overrides the commit hash recorded in deploy.lock and the manifest (e.g. from
+// --commit). It is metadata only: the checked-out commit is still what gets built.
+func (d *Deployer) SetCommit(sha string) error {
+	sha = strings.ToLower(strings.TrimSpace(sha))
+	if sha == "" {
+		return nil
+	}
+	if !commitSHA.MatchString(sha) {
+		return fmt.Errorf("invalid commit %q: expected a full 40 or 64 character hex SHA", sha)
+	}
+	d.commitOverride = sha
+	return nil
+}
+
+// recordedCommit returns the commit to record for a build of builtCommit
+func (d *Deployer) recordedCommit(builtCommit string) string {
+	if d.commitOverride == "" || d.commitOverride == builtCommit {
+		return builtCommit
+	}
+	d.log.Warn("Recording commit %s instead of built commit %s (--commit)", d.commitOverride[:8], builtCommit[:8])
+	return d.commitOverride
+}
+
+// checkDeployWindow refuses to deploy outside deploy_windows unless OverrideWindow is set.
+// Dry runs only warn, since they change nothing.
+func (d *Deployer) checkDeployWindow(now time.Time) error {
+	allowed, err := d.env.DeployWindows.Allows(now)
+	if err != nil {
+		return err
+	}
+	if allowed {
+		return nil
+	}
+
+	windows := strings.Join(d.env.DeployWindows.Allow, ", ")
+	switch {
+	case d.OverrideWindow:
+		d.log.Warn("!!! DEPLOY WINDOW OVERRIDDEN: deploying to %s outside the allowed windows (%s) !!!", d.envName, windows)
+		d.windowOverridden = true
+		return nil
+	case d.dryRun:
+		d.log.Warn("Outside the deploy windows for %s (%s); a real deploy would be refused", d.envName, windows)
+		return nil
+	}
+	return verserrors.New(verserrors.CodeDeploymentFailed,
+		fmt.Sprintf("deploys to %s are only allowed during: %s", d.envName, windows),
+		"Wait for the next deploy window, or pass --override-window if this deploy cannot wait",
+		nil)
+}
+
+// isLiveCommit reports whether commitHash is what the live release was built from.
+// Dirty working tree deploys, the live one or the new one, ship uncommitted changes
+// on top of the commit, so they never match.
+func isLiveCommit(previousLock *state.DeployLock, commitHash string, dirty bool) bool {
+	return previousLock != nil && previousLock.LastDeploy.CommitHash == commitHash && !dirty && !previousLock.LastDeploy.DirtyTree
+}
+
+// checkSameCommit warns when commitHash is already the live commit, which only happens
+// with --force, a changed config or a dependency-only change, and refuses to go on
+// unless --force is set or SameCommitConfirm agrees.
+func (d *Deployer) checkSameCommit(previousLock *state.DeployLock, commitHash string) error {
+	if !isLiveCommit(previousLock, commitHash, d.dirtyTree) {
+		return nil
+	}
+	d.log.Warn("⚠  Commit %s is already live as release %s; deploying it again creates a duplicate release",
+		commitHash[:8], previousLock.LastDeploy.ReleaseDir)
+	if d.force || d.dryRun {
+		return nil
+	}
+	if d.SameCommitConfirm != nil && d.SameCommitConfirm() {
+		return nil
+	}
+	return verserrors.New(verserrors.CodeDeploymentFailed,
+		fmt.Sprintf("Commit %s is already deployed to %s", commitHash[:8], d.envName),
+		"Use --force to deploy the same commit again",
+		nil)
+}
+
+// runSuffix returns a short random hex string that tells apart deploys sharing a
+// release version (which only has second granularity)
+func runSuffix() string {
+	return fmt.Sprintf("%06x", rand.Intn(1<<24))
+}
+
+// defaultUploadStreams is the number of chunks uploaded at once
+const defaultUploadStreams = 4
+
+// uploadStreams returns the number of parallel chunk upload streams: 4, lowered
+// further by --concurrency
+func (d *Deployer) uploadStreams() int {
+	if d.env.Concurrency > 0 && d.env.Concurrency < defaultUploadStreams {
+		return d.env.Concurrency
+	}
+	return defaultUploadStreams
+}
+
+// defaultHookConcurrency caps parallel hook groups when hook_concurrency is not set.
+// Every hook opens its own SSH session, and sshd's default MaxSessions is 10 per
+// connection, some of which the deploy itself keeps open.
+const defaultHookConcurrency = 5
+
+// hookLimit is the number of hooks of a parallel group that may run at once:
+// hook_concurrency (default 5), lowered further by --concurrency
+func (d *Deployer) hookLimit() int {
+	limit := d.env.HookConcurrency
+	if limit == 0 {
+		limit = defaultHookConcurrency
+	}
+	if d.env.Concurrency > 0 && d.env.Concurrency < limit {
+		limit = d.env.Concurrency
+	}
+	return limit
+}
+
+// limitGroup applies the hook concurrency cap to a parallel hook group
+func (d *Deployer) limitGroup(g *errgroup.Group) {
+	g.SetLimit(d.hookLimit())
+}
+
 // Deploy executes the full deployment workflow
 func (d *Deployer) Deploy() (returnErr error) {
 	startTime := time.Now()
 	d.log.Info("Starting deployment to %s", d.envName)
 
+	if err := d.checkDeployWindow(time.Now()); err != nil {
+		return err
+	}
+
 	// Enforce deploy_timeout if configured
 	deployTimeout := d.env.DeployTimeout
 	if deployTimeout <= 0 {
 		deployTimeout = 600 // default 10 minutes
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(deployTimeout)*time.Second)
+	ctx, cancel := context.WithTimeout(d.parentContext(), time.Duration(deployTimeout)*time.Second)
 	defer cancel()
 
 	// Monitor context cancellation in background
@@ -93,6 +385,9 @@ func (d *Deployer) Deploy() (returnErr error) {
 
 	// checkTimeout is a helper to abort if deploy_timeout is exceeded
 	checkTimeout := func() error {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("deployment aborted: cancelled")
+		}
 		if ctx.Err() != nil {
 			return fmt.Errorf("deployment aborted: timeout of %ds exceeded", deployTimeout)
 		}
@@ -106,6 +401,24 @@ func (d *Deployer) Deploy() (returnErr error) {
 		d.sendNotification(releaseVer, commitRef, returnErr, time.Since(startTime))
 	}()
 
+	var trace *tracer
+	if d.Trace || d.env.TimingsFile != "" {
+		trace = newTracer()
+		defer func() {
+			if d.Trace {
+				d.log.Info("Step timings:\n%s", trace.report())
+			}
+			// Only completed deploys are recorded, so failures don't skew the history
+			if d.env.TimingsFile != "" && returnErr == nil && releaseVer != "" {
+				path := d.timingsPath()
+				if err := trace.appendTimings(path, d.envName, releaseVer, startTime); err != nil {
+					d.log.Warn("Failed to record deploy timings in %s: %v", path, err)
+				}
+			}
+		}()
+	}
+	trace.step("validate")
+
 	// Step 0: Validate local tools
 	if err := d.validateLocalTools(); err != nil {
 		return err
@@ -122,21 +435,19 @@ func (d *Deployer) Deploy() (returnErr error) {
 	}
 
 	// Step 2: Check if working directory is clean
-	if !d.skipDirtyCheck {
-		clean, err := git.IsClean(d.repoPath)
-		if err != nil {
-			return err
-		}
-		if !clean {
-			return verserrors.Wrap(fmt.Errorf("working directory has uncommitted changes (use --skip-dirty-check to bypass)"))
-		}
-	} else {
-		d.log.Warn("Skipping clean working directory check (--skip-dirty-check active)")
+	if err := d.checkWorkingTree(); err != nil {
+		return err
 	}
 
 	// Step 3: Clone repository to clean temp directory
-	d.log.Info("Cloning repository to temporary directory...")
-	tmpRepo, err := git.Clone(d.repoPath, "")
+	trace.step("clone")
+	if err := d.checkTempDir(); err != nil {
+		return verserrors.Wrap(err)
+	}
+	if err := d.checkLocalScratchSpace(); err != nil {
+		return err
+	}
+	tmpRepo, err := d.cloneRepo()
 	if err != nil {
 		return err
 	}
@@ -147,29 +458,46 @@ func (d *Deployer) Deploy() (returnErr error) {
 	if err != nil {
 		return err
 	}
+	commitHash = d.recordedCommit(commitHash)
 	commitRef = commitHash
 	d.log.Info("Commit: %s", commitHash[:8])
 
 	// Step 5: Connect to remote server
+	trace.step("connect")
 	d.log.Info("Connecting to %s@%s...", d.env.SSH.User, d.env.SSH.Host)
 	sshClient, err := ssh.NewClient(&d.env.SSH, d.log)
 	if err != nil {
 		return verserrors.Wrap(err)
 	}
 	defer sshClient.Close()
+	d.configureCleanup(sshClient)
+
+	// Step 5.1: Make sure the remote has every tool the deploy relies on
+	if err := d.validateRemoteTools(sshClient); err != nil {
+		return err
+	}
 
 	// Step 5.5: Acquire deployment lock to prevent concurrent deployments
-	lockDirPath := filepath.ToSlash(filepath.Join(d.env.RemotePath, ".versa.lock"))
-	d.log.Debug("Acquiring deployment lock...")
-	if err := sshClient.AcquireLock(lockDirPath); err != nil {
+	// The lock dir is created non-recursively, so remote_path must exist first
+	// (a fresh server on --initial-deploy). A dry run against a fresh server has
+	// nothing to lock.
+	remotePathExists, err := d.ensureRemotePath(sshClient)
+	if err != nil {
 		return err
 	}
-	defer func() {
-		d.log.Debug("Releasing deployment lock...")
-		if err := sshClient.ReleaseLock(lockDirPath); err != nil {
-			d.log.Warn("Failed to release deployment lock: %v", err)
+	lockDirPath := d.lockPath()
+	if remotePathExists {
+		d.log.Debug("Acquiring deployment lock...")
+		if err := sshClient.AcquireLock(lockDirPath, ssh.NewLockHolder(d.envName)); err != nil {
+			return err
 		}
-	}()
+		defer func() {
+			d.log.Debug("Releasing deployment lock...")
+			if err := sshClient.ReleaseLock(lockDirPath); err != nil {
+				d.log.Warn("Failed to release deployment lock: %v", err)
+			}
+		}()
+	}
 
 	// Step 6: Fetch deploy.lock from remote
 	lockPath := filepath.ToSlash(filepath.Join(d.env.RemotePath, "deploy.lock"))
@@ -182,7 +510,7 @@ func (d *Deployer) Deploy() (returnErr error) {
 
 	if exists {
 		d.log.Debug("Fetching deploy.lock from remote...")
-		tmpLockFile := filepath.Join(os.TempDir(), fmt.Sprintf("deploy-%s.lock", d.envName))
+		tmpLockFile := filepath.Join(d.tempDir(), fmt.Sprintf("deploy-%s.lock", d.envName))
 		if err := sshClient.DownloadFile(lockPath, tmpLockFile); err != nil {
 			return err
 		}
@@ -204,9 +532,18 @@ func (d *Deployer) Deploy() (returnErr error) {
 		d.log.Info("First deployment detected (--initial-deploy)")
 	}
 
+	// Step 6.5: Dry-run preflight (--check-remote): would the upload itself work?
+	if d.dryRun && d.CheckRemote {
+		if err := d.checkRemotePreflight(sshClient, tmpRepo, previousLock); err != nil {
+			return err
+		}
+	}
+
 	// Step 7: Calculate changeset
+	trace.step("changeset")
 	d.log.Info("Calculating changes...")
 	detector := changeset.NewDetector(tmpRepo, d.env.Ignored, d.env.RouteFiles, d.env.Builds.PHP.ProjectRoot, d.env.Builds.Go.ProjectRoot, d.env.Builds.Frontend.ProjectRoot, d.env.Builds.Python.ProjectRoot, d.env.Builds.Python.RequirementsFile, previousLock)
+	detector.Workers = d.env.Concurrency
 	cs, err := detector.Detect()
 	if err != nil {
 		return err
@@ -223,60 +560,148 @@ func (d *Deployer) Deploy() (returnErr error) {
 		d.log.Info("Force redeploy requested - bypassing change detection")
 	}
 
-	d.log.Info("Changes detected: %d PHP, %d Twig, %d Go, %d Frontend files",
-		len(cs.PHPFiles), len(cs.TwigFiles), len(cs.GoFiles), len(cs.FrontendFiles))
+	// Step 7.1: The live release already has this commit: a new one would be a duplicate
+	if err := d.checkSameCommit(previousLock, commitHash); err != nil {
+		return err
+	}
+
+	d.log.Info("Changes detected: %d PHP, %d Twig, %d Go, %d Frontend files, %d deleted",
+		len(cs.PHPFiles), len(cs.TwigFiles), len(cs.GoFiles), len(cs.FrontendFiles), len(cs.DeletedFiles))
+	for _, f := range cs.DeletedFiles {
+		d.log.Debug("  Deleted: %s", f)
+	}
+
+	// Step 7.5: Composer-only change with fast_dependency_update: install in the live release
+	if d.env.FastDependencyUpdate && previousLock != nil {
+		if files := composerOnlyChanges(cs, d.env.Builds.PHP); len(files) > 0 {
+			if d.dryRun {
+				d.log.Info("DRY RUN - would update Composer dependencies in the live release (fast_dependency_update)")
+				return nil
+			}
+			releaseVer = previousLock.LastDeploy.ReleaseDir
+			trace.step("fast dependency update")
+			return d.fastDependencyUpdate(sshClient, tmpRepo, commitHash, files, cs)
+		}
+	}
+
+	// Step 7.6: Small plain-file change with in_place: patch the live release
+	if d.env.InPlace {
+		if files := d.inPlaceChanges(cs, previousLock); len(files) > 0 {
+			if d.dryRun {
+				d.log.Info("DRY RUN - would update %d file(s) in the live release in place (in_place)", len(files))
+				return nil
+			}
+			releaseVer = previousLock.LastDeploy.ReleaseDir
+			trace.step("in-place update")
+			return d.inPlaceUpdate(sshClient, tmpRepo, commitHash, files, cs)
+		}
+	}
 
 	if d.dryRun {
 		d.log.Info("DRY RUN - would deploy these changes")
 		return nil
 	}
 
-	// Step 8: Generate release version
+	// Step 8: Generate release version, or reuse the one of an interrupted upload
+	resumePath := d.resumePath()
+	var resume *uploadResume
+	resumeKey := d.resumeKey(commitHash, previousLock)
+	if !d.dirtyTree {
+		resume = loadUploadResume(resumePath, resumeKey)
+	}
+	if resume == nil {
+		d.discardUploadResume(sshClient, resumePath)
+	}
 	releaseVersion := artifact.GenerateReleaseVersion()
+	if resume != nil {
+		releaseVersion = resume.ReleaseVersion
+		d.log.Info("Resuming interrupted upload of release %s", releaseVersion)
+	}
 	releaseVer = releaseVersion
 	d.log.Info("Release version: %s", releaseVersion)
+	// Release versions have second granularity; the suffix keeps the staging dir and
+	// local temp paths of deploys started in the same second apart
+	runID := runSuffix()
 
-	// Step 9: Build artifacts
+	// Step 9: Build artifacts, or reuse the ones an interrupted upload was compressed
+	// from, so the preflight checks and verify_files see what its chunks contain
 	if err := checkTimeout(); err != nil {
 		return err
 	}
-	d.log.Info("Building artifacts...")
-	artifactDir := filepath.Join(os.TempDir(), "versadeploy-artifact-"+releaseVersion)
-	if err := os.MkdirAll(artifactDir, 0775); err != nil {
-		return err
-	}
-	defer os.RemoveAll(artifactDir)
-
-	builder := builder.NewBuilder(tmpRepo, artifactDir, d.env, cs, d.log)
-	buildResult, err := builder.Build()
-	if err != nil {
-		return verserrors.Wrap(err)
+	// Kept, with the chunks and the resume record, while chunks may be sitting
+	// half-uploaded on the server
+	keepChunks := false
+	var artifactDir string
+	var buildResult *builder.BuildResult
+	if resume != nil {
+		artifactDir, buildResult = resume.ArtifactDir, resume.BuildResult
+		d.log.Info("Reusing the artifact built for release %s", releaseVersion)
+	} else {
+		d.log.Info("Building artifacts...")
+		// Temp paths include the environment so concurrent deploys (deploy-all) never collide
+		artifactDir = filepath.Join(d.tempDir(), fmt.Sprintf("versadeploy-artifact-%s-%s-%s", d.envName, releaseVersion, runID))
+		if err := os.MkdirAll(artifactDir, 0775); err != nil {
+			return err
+		}
 	}
+	defer func() {
+		if !keepChunks {
+			os.RemoveAll(artifactDir)
+		}
+	}()
 
-	// Step 10: Generate manifest
-	d.log.Debug("Generating manifest...")
-	gen := artifact.NewGenerator(artifactDir, releaseVersion, commitHash)
-	if err := gen.GenerateManifest(buildResult); err != nil {
-		return err
-	}
+	if resume == nil {
+		trace.step("build")
+		b := builder.NewBuilder(tmpRepo, artifactDir, d.env, cs, d.log)
+		b.Jobs = d.BuildJobs
+		buildResult, err = b.Build()
+		if err != nil {
+			return verserrors.Wrap(err)
+		}
+		trace.end()
+		trace.record(buildSteps(buildResult.Durations)...)
+		d.reportLargestFiles(artifactDir)
+
+		// Step 10: Generate manifest
+		trace.step("manifest")
+		d.log.Debug("Generating manifest...")
+		gen := artifact.NewGenerator(artifactDir, releaseVersion, commitHash)
+		gen.Exclude = d.artifactExclude()
+		gen.DirtyTree = d.dirtyTree
+		gen.Message = d.Message
+		gen.Expect = builder.ExpectedPaths(d.env, buildResult)
+		if err := gen.GenerateManifest(buildResult); err != nil {
+			return err
+		}
+		// Hashing every file is only worth it when verify_files will read files.json
+		if d.env.VerifyFiles != "" {
+			if err := gen.GenerateFileInventory(); err != nil {
+				return err
+			}
+		}
 
-	if err := gen.Validate(); err != nil {
-		return err
+		if err := gen.Validate(); err != nil {
+			return err
+		}
 	}
 
 	// Step 11: Upload artifact
 	if err := checkTimeout(); err != nil {
 		return err
 	}
+	trace.step("disk check")
 	d.log.Info("Uploading artifact to remote server...")
 	releasesDir := filepath.ToSlash(filepath.Join(d.env.RemotePath, "releases"))
-	stagingDir := filepath.ToSlash(filepath.Join(releasesDir, releaseVersion+".staging"))
+	stagingDir := filepath.ToSlash(filepath.Join(releasesDir, releaseVersion+".staging-"+runID))
 	finalDir := filepath.ToSlash(filepath.Join(releasesDir, releaseVersion))
 
 	// Create releases directory if doesn't exist using SFTP
 	if err := sshClient.MkdirAll(releasesDir); err != nil {
 		return err
 	}
+	if err := d.applyDirMode(sshClient, releasesDir); err != nil {
+		return err
+	}
 
 	// Check disk space before upload
 	artifactSize, err := d.calculateDirectorySize(artifactDir)
@@ -284,42 +709,83 @@ func (d *Deployer) Deploy() (returnErr error) {
 		d.log.Warn("Could not calculate artifact size: %v", err)
 	} else {
 		d.log.Debug("Artifact size: %d MB", artifactSize/(1024*1024))
-		if err := sshClient.CheckDiskSpace(releasesDir, artifactSize); err != nil {
+		if err := d.checkArtifactSize(artifactDir, artifactSize); err != nil {
+			return err
+		}
+		if err := d.checkLocalDiskSpace(artifactSize); err != nil {
+			return err
+		}
+		if err := d.checkDiskSpace(sshClient, releasesDir, artifactSize); err != nil {
+			return verserrors.Wrap(err)
+		}
+		if err := d.checkInodes(sshClient, releasesDir, artifactDir); err != nil {
 			return verserrors.Wrap(err)
 		}
 	}
 
 	// Step 10: Compress and upload to staging (Chunked Parallel)
 	archiveName := fmt.Sprintf("%s.tar.gz", releaseVersion)
-	localArchiveBase := filepath.Join(os.TempDir(), archiveName)
-	remoteArchive := filepath.ToSlash(filepath.Join(d.env.RemotePath, archiveName))
-
-	g := artifact.NewGenerator(artifactDir, releaseVersion, commitHash)
-	d.log.Info("Compressing release into chunks...")
-
-	// Use 10MB chunks for parallel upload optimization
-	const chunkSize = 10 * 1024 * 1024
-	chunkPaths, err := g.CompressChunked(localArchiveBase, chunkSize)
-	if err != nil {
-		return fmt.Errorf("failed to compress release: %w", err)
+	// Chunk names must match archiveName on the server, so keep them in a per-run dir;
+	// a resumed upload keeps using the dir of the interrupted run
+	localArchiveDir := filepath.Join(d.tempDir(), fmt.Sprintf("versadeploy-chunks-%s-%s-%s", d.envName, releaseVersion, runID))
+	if resume != nil {
+		localArchiveDir = filepath.Dir(resume.Chunks[0])
+	}
+	if err := os.MkdirAll(localArchiveDir, 0775); err != nil {
+		return err
 	}
 	defer func() {
-		for _, p := range chunkPaths {
-			os.Remove(p)
+		if !keepChunks {
+			os.RemoveAll(localArchiveDir)
+			os.Remove(resumePath)
 		}
 	}()
+	localArchiveBase := filepath.Join(localArchiveDir, archiveName)
+	remoteArchive := filepath.ToSlash(filepath.Join(d.env.RemotePath, archiveName))
+
+	var chunkPaths []string
+	if resume != nil {
+		chunkPaths = resume.Chunks
+	} else {
+		trace.step("compress")
+		g := artifact.NewGenerator(artifactDir, releaseVersion, commitHash)
+		g.NormalizeModes = d.env.NormalizeFileModes
+		g.Exclude = d.artifactExclude()
+		d.log.Info("Compressing release into chunks...")
+
+		// Use 10MB chunks for parallel upload optimization
+		const chunkSize = 10 * 1024 * 1024
+		chunkPaths, err = g.CompressChunked(localArchiveBase, chunkSize)
+		if err != nil {
+			return fmt.Errorf("failed to compress release: %w", err)
+		}
+	}
+	if !d.dirtyTree {
+		record := &uploadResume{ReleaseVersion: releaseVersion, Key: resumeKey, Chunks: chunkPaths, ArtifactDir: artifactDir, BuildResult: buildResult}
+		if err := saveUploadResume(resumePath, record); err != nil {
+			d.log.Warn("Failed to record upload for resuming: %v", err)
+		} else {
+			keepChunks = true
+		}
+	}
 
+	trace.step("upload")
 	d.log.Info("Uploading %d chunks in parallel to remote server...", len(chunkPaths))
-	if err := sshClient.UploadFilesParallel(chunkPaths, d.env.RemotePath, 4); err != nil {
+	if err := sshClient.UploadFilesParallel(chunkPaths, d.env.RemotePath, d.uploadStreams()); err != nil {
+		if keepChunks {
+			d.log.Info("Re-run the deploy to resume the upload; chunks already on the server will be skipped")
+		}
 		return fmt.Errorf("parallel upload failed: %w", err)
 	}
 
 	// Reassemble chunks on the remote server
+	trace.step("extract")
 	d.log.Info("Reassembling artifact on server...")
 	reassembleCmd := fmt.Sprintf("cat %q.* > %q && rm -f %q.*", remoteArchive, remoteArchive, remoteArchive)
 	if _, err := sshClient.ExecuteCommand(reassembleCmd); err != nil {
 		return fmt.Errorf("failed to reassemble artifact on server: %w", err)
 	}
+	keepChunks = false
 
 	// Extract on remote
 	if err := sshClient.ExtractArchive(remoteArchive, stagingDir); err != nil {
@@ -330,16 +796,35 @@ func (d *Deployer) Deploy() (returnErr error) {
 	// Cleanup remote archive
 	sshClient.ExecuteCommand(fmt.Sprintf("rm -f -- %q", remoteArchive))
 
-	if _, err := sshClient.ExecuteCommand(fmt.Sprintf("mv -T -- %q %q", stagingDir, finalDir)); err != nil {
+	if err := d.applyDirMode(sshClient, stagingDir); err != nil {
+		sshClient.ExecuteCommand(fmt.Sprintf("rm -rf -- %q", stagingDir))
+		return err
+	}
+
+	if err := d.verifyExtractedFiles(sshClient, artifactDir, stagingDir); err != nil {
+		sshClient.ExecuteCommand(fmt.Sprintf("rm -rf -- %q", stagingDir))
+		return err
+	}
+
+	if _, err := sshClient.ExecuteCommand(renameDirCmd(sshClient, stagingDir, finalDir)); err != nil {
 		// Cleanup staging on failure
 		sshClient.ExecuteCommand(fmt.Sprintf("rm -rf -- %q", stagingDir))
 		return fmt.Errorf("failed to finalize release: %w", err)
 	}
 
-	// Step 11.5: Handle shared paths
+	// Step 11.5: Handle shared paths and secret files
+	trace.step("link shared & reuse")
 	if err := d.handleSharedPaths(sshClient, finalDir); err != nil {
 		return err
 	}
+	if err := d.handleSecretFiles(sshClient, finalDir); err != nil {
+		return err
+	}
+
+	// Create empty runtime directories the artifact can't carry (ensure_dirs)
+	if err := d.ensureDirs(sshClient, finalDir); err != nil {
+		return err
+	}
 
 	// Step 11.6: Reuse dependencies from previous release if possible
 	if previousLock != nil {
@@ -351,6 +836,8 @@ func (d *Deployer) Deploy() (returnErr error) {
 		if err := d.handlePreservedPaths(sshClient, previousLock.LastDeploy.ReleaseDir, finalDir); err != nil {
 			return err
 		}
+	} else {
+		d.log.Debug("Dependency reuse skipped: no previous release")
 	}
 
 	// Step 11.8: Validate runtime artifacts before activating symlink
@@ -358,13 +845,18 @@ func (d *Deployer) Deploy() (returnErr error) {
 		return err
 	}
 
+	// Step 11.9: Hand the runtime dirs to owner, now that the steps above have created them
+	d.applyOwner(sshClient, finalDir)
+
 	// Step 12: Run pre_deploy_server hooks (non-fatal, before symlink switch)
 	if err := checkTimeout(); err != nil {
 		return err
 	}
+	trace.step("pre_deploy_server hooks")
 	d.executePreDeployServer(sshClient, finalDir)
 
 	// Step 13: Atomic symlink switch
+	trace.step("switch & services")
 	if err := checkTimeout(); err != nil {
 		return err
 	}
@@ -382,61 +874,68 @@ func (d *Deployer) Deploy() (returnErr error) {
 	// Step 13.5: Reload services (PHP-FPM, Apache/Nginx, etc.) to clear caches
 	d.executeServicesReload(sshClient)
 
-	// Step 14: Execute post-deploy hooks (after symlink switch)
-	skipPostDeploy := false
-	if d.initialDeploy && len(d.env.PostDeploy) > 0 && d.PostDeployConfirm != nil {
-		if !d.PostDeployConfirm() {
-			d.log.Info("Post-deploy hooks skipped by user (initial deploy)")
-			skipPostDeploy = true
-		}
+	// Step 13.6: Restart systemd services and verify they are active (rollback on failure)
+	if err := d.restartSystemdServices(sshClient); err != nil {
+		return d.rollbackAfterServiceFailure(sshClient, previousLock, err)
 	}
-	if !skipPostDeploy {
-		if err := d.executePostDeployHooks(sshClient, finalDir, previousLock); err != nil {
-			return err
-		}
+
+	// Step 13.8: Regenerate route/Twig caches flagged by the build (rollback on failure)
+	trace.step("hooks & smoke tests")
+	if err := d.executeCacheCommands(sshClient, finalDir, buildResult, previousLock); err != nil {
+		return err
 	}
 
-	// Step 14.5: Health check (verify app is working after deploy)
-	if err := d.performHealthCheck(previousLock, sshClient); err != nil {
+	// Step 14: Execute first-deploy and post-deploy hooks (after symlink switch)
+	if err := d.executeDeployHooks(sshClient, finalDir, previousLock); err != nil {
 		return err
 	}
 
-	// Step 15: Update deploy.lock
-	d.log.Info("Updating deploy.lock...")
-	newLock := state.New(commitHash, releaseVersion, cs.AllFileHashes, cs.ComposerHash, cs.PackageHash, cs.GoModHash, cs.RequirementsHash)
-	lockData, err := newLock.ToJSON()
-	if err != nil {
+	// Step 14.2: Smoke tests (verification commands, rollback on failure)
+	if err := d.executeSmokeTests(sshClient, finalDir, previousLock); err != nil {
 		return err
 	}
 
-	tmpLockFile := filepath.Join(os.TempDir(), "deploy.lock.new")
-	if err := os.WriteFile(tmpLockFile, lockData, 0644); err != nil {
+	// Step 14.5: Health check (verify app is working after deploy)
+	trace.step("health check")
+	if err := d.performHealthCheck(previousLock, sshClient); err != nil {
 		return err
 	}
-	defer os.Remove(tmpLockFile)
 
-	// Upload deploy.lock directly as a file
-	tmpUploadDir := filepath.Join(os.TempDir(), "lockupload")
-	os.MkdirAll(tmpUploadDir, 0775)
-	defer os.RemoveAll(tmpUploadDir)
+	// Step 14.6: Warm caches
+	trace.step("cache warm")
+	if err := d.warmCaches(previousLock, sshClient); err != nil {
+		return err
+	}
 
-	lockUploadPath := filepath.Join(tmpUploadDir, "deploy.lock")
-	if err := os.WriteFile(lockUploadPath, lockData, 0644); err != nil {
+	// Step 15: Update deploy.lock
+	trace.step("finalize")
+	d.log.Info("Updating deploy.lock...")
+	newLock := d.newDeployLock(commitHash, releaseVersion, cs)
+	lockData, err := newLock.ToJSON()
+	if err != nil {
 		return err
 	}
 
-	if err := sshClient.UploadDirectory(tmpUploadDir, d.env.RemotePath); err != nil {
+	// Written to deploy.lock.tmp and moved into place, so it is never left half-written
+	if err := sshClient.WriteRemoteFileAtomic(lockPath, lockData); err != nil {
 		// Non-fatal, but log it
 		d.log.Error("Failed to upload deploy.lock: %v", err)
 	}
 
+	// Step 15.5: Snapshot deploy.lock into the release so a rollback can restore it
+	d.snapshotReleaseLock(sshClient, finalDir, lockData)
+	d.recordHistory(sshClient, newLock)
+
 	// Step 16: Cleanup old releases
 	d.log.Info("Cleaning up old releases...")
 	if err := sshClient.CleanupOldReleases(releasesDir, ReleasesToKeep); err != nil {
 		// Non-fatal
-		d.log.Error("Failed to cleanup old releases: %v", err)
+		d.log.Warn("Failed to cleanup old releases: %v", err)
 	}
 
+	// Step 16.5: Prune shared paths according to shared_cleanup (non-fatal)
+	d.pruneSharedPaths(sshClient)
+
 	d.log.Success("Deployment successful!")
 	return nil
 }
@@ -448,10 +947,14 @@ func (d *Deployer) Deploy() (returnErr error) {
 type PrebuiltArtifact struct {
 	ReleaseVersion string
 	CommitHash     string
-	ChunkPaths     []string             // local /tmp/*.tar.gz.001, .002, … chunk files
+	DirtyTree      bool                 // built from uncommitted changes (--allow-dirty)
+	ChunkPaths     []string             // local *.tar.gz.001, .002, … chunk files in chunkDir
 	ChangeSet      *changeset.ChangeSet // used for dependency reuse and deploy.lock
+	BuildResult    *builder.BuildResult // route/Twig cache flags for the cache commands
 	artifactDir    string               // owned by Cleanup
+	chunkDir       string               // owned by Cleanup
 	tmpRepo        string               // owned by Cleanup
+	runID          string               // suffix of the staging dir on each target
 }
 
 // Cleanup removes all temporary directories and chunk files created during build.
@@ -461,6 +964,9 @@ func (a *PrebuiltArtifact) Cleanup() {
 	for _, p := range a.ChunkPaths {
 		os.Remove(p)
 	}
+	if a.chunkDir != "" {
+		os.RemoveAll(a.chunkDir)
+	}
 }
 
 // BuildArtifact performs the local build phase (validation, clone, build, compress)
@@ -479,26 +985,27 @@ func (d *Deployer) BuildArtifact() (*PrebuiltArtifact, error) {
 	}
 
 	// Step 1.5: Run pre_deploy_local hooks (abort on failure)
-	if err := d.executePreDeployLocal(); err != nil {
+	if d.skipLocalHooks {
+		if len(d.env.PreDeployLocal) > 0 {
+			d.log.Info("Skipping pre_deploy_local hooks")
+		}
+	} else if err := d.executePreDeployLocal(); err != nil {
 		return nil, err
 	}
 
 	// Step 2: Check if working directory is clean
-	if !d.skipDirtyCheck {
-		clean, err := git.IsClean(d.repoPath)
-		if err != nil {
-			return nil, err
-		}
-		if !clean {
-			return nil, verserrors.Wrap(fmt.Errorf("working directory has uncommitted changes (use --skip-dirty-check to bypass)"))
-		}
-	} else {
-		d.log.Warn("Skipping clean working directory check (--skip-dirty-check active)")
+	if err := d.checkWorkingTree(); err != nil {
+		return nil, err
 	}
 
 	// Step 3: Clone repository to clean temp directory
-	d.log.Info("Cloning repository to temporary directory...")
-	tmpRepo, err := git.Clone(d.repoPath, "")
+	if err := d.checkTempDir(); err != nil {
+		return nil, verserrors.Wrap(err)
+	}
+	if err := d.checkLocalScratchSpace(); err != nil {
+		return nil, err
+	}
+	tmpRepo, err := d.cloneRepo()
 	if err != nil {
 		return nil, err
 	}
@@ -509,15 +1016,17 @@ func (d *Deployer) BuildArtifact() (*PrebuiltArtifact, error) {
 		os.RemoveAll(tmpRepo)
 		return nil, err
 	}
+	commitHash = d.recordedCommit(commitHash)
 	d.log.Info("Commit: %s", commitHash[:8])
 
 	// Step 8: Generate release version
 	releaseVersion := artifact.GenerateReleaseVersion()
 	d.log.Info("Release version: %s", releaseVersion)
+	runID := runSuffix()
 
 	// Step 9: Build artifacts (full build — nil previousLock treats all files as changed)
 	d.log.Info("Building artifacts...")
-	artifactDir := filepath.Join(os.TempDir(), "versadeploy-artifact-"+releaseVersion)
+	artifactDir := filepath.Join(d.tempDir(), fmt.Sprintf("versadeploy-artifact-%s-%s", releaseVersion, runID))
 	if err := os.MkdirAll(artifactDir, 0775); err != nil {
 		os.RemoveAll(tmpRepo)
 		return nil, err
@@ -530,6 +1039,7 @@ func (d *Deployer) BuildArtifact() (*PrebuiltArtifact, error) {
 		d.env.Builds.Python.RequirementsFile,
 		nil, // nil previousLock = full build, all files included
 	)
+	detector.Workers = d.env.Concurrency
 	cs, err := detector.Detect()
 	if err != nil {
 		os.RemoveAll(tmpRepo)
@@ -539,47 +1049,83 @@ func (d *Deployer) BuildArtifact() (*PrebuiltArtifact, error) {
 	cs.Force = true
 
 	b := builder.NewBuilder(tmpRepo, artifactDir, d.env, cs, d.log)
+	b.Jobs = d.BuildJobs
 	buildResult, err := b.Build()
 	if err != nil {
 		os.RemoveAll(tmpRepo)
 		os.RemoveAll(artifactDir)
 		return nil, verserrors.Wrap(err)
 	}
+	d.reportLargestFiles(artifactDir)
 
 	// Step 10: Generate manifest + validate
 	d.log.Debug("Generating manifest...")
 	gen := artifact.NewGenerator(artifactDir, releaseVersion, commitHash)
+	gen.Exclude = d.artifactExclude()
+	gen.DirtyTree = d.dirtyTree
+	gen.Message = d.Message
+	gen.Expect = builder.ExpectedPaths(d.env, buildResult)
 	if err := gen.GenerateManifest(buildResult); err != nil {
 		os.RemoveAll(tmpRepo)
 		os.RemoveAll(artifactDir)
 		return nil, err
 	}
+	if err := gen.GenerateFileInventory(); err != nil {
+		os.RemoveAll(tmpRepo)
+		os.RemoveAll(artifactDir)
+		return nil, err
+	}
 	if err := gen.Validate(); err != nil {
 		os.RemoveAll(tmpRepo)
 		os.RemoveAll(artifactDir)
 		return nil, err
 	}
+	if artifactSize, err := d.calculateDirectorySize(artifactDir); err == nil {
+		if err := d.checkArtifactSize(artifactDir, artifactSize); err != nil {
+			os.RemoveAll(tmpRepo)
+			os.RemoveAll(artifactDir)
+			return nil, err
+		}
+		if err := d.checkLocalDiskSpace(artifactSize); err != nil {
+			os.RemoveAll(tmpRepo)
+			os.RemoveAll(artifactDir)
+			return nil, err
+		}
+	}
 
 	// Compress into chunks
 	archiveName := fmt.Sprintf("%s.tar.gz", releaseVersion)
-	localArchiveBase := filepath.Join(os.TempDir(), archiveName)
+	chunkDir := filepath.Join(d.tempDir(), fmt.Sprintf("versadeploy-chunks-%s-%s", releaseVersion, runID))
+	if err := os.MkdirAll(chunkDir, 0775); err != nil {
+		os.RemoveAll(tmpRepo)
+		os.RemoveAll(artifactDir)
+		return nil, err
+	}
+	localArchiveBase := filepath.Join(chunkDir, archiveName)
 	g2 := artifact.NewGenerator(artifactDir, releaseVersion, commitHash)
+	g2.NormalizeModes = d.env.NormalizeFileModes
+	g2.Exclude = d.artifactExclude()
 	d.log.Info("Compressing release into chunks...")
 	const chunkSize = 10 * 1024 * 1024
 	chunkPaths, err := g2.CompressChunked(localArchiveBase, chunkSize)
 	if err != nil {
 		os.RemoveAll(tmpRepo)
 		os.RemoveAll(artifactDir)
+		os.RemoveAll(chunkDir)
 		return nil, fmt.Errorf("failed to compress release: %w", err)
 	}
 
 	return &PrebuiltArtifact{
 		ReleaseVersion: releaseVersion,
 		CommitHash:     commitHash,
+		DirtyTree:      d.dirtyTree,
 		ChunkPaths:     chunkPaths,
 		ChangeSet:      cs,
+		BuildResult:    buildResult,
 		artifactDir:    artifactDir,
+		chunkDir:       chunkDir,
 		tmpRepo:        tmpRepo,
+		runID:          runID,
 	}, nil
 }
 
@@ -590,11 +1136,15 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 	startTime := time.Now()
 	d.log.Info("Deploying %s to %s...", artifact.ReleaseVersion, d.envName)
 
+	if err := d.checkDeployWindow(time.Now()); err != nil {
+		return err
+	}
+
 	deployTimeout := d.env.DeployTimeout
 	if deployTimeout <= 0 {
 		deployTimeout = 600
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(deployTimeout)*time.Second)
+	ctx, cancel := context.WithTimeout(d.parentContext(), time.Duration(deployTimeout)*time.Second)
 	defer cancel()
 	doneCh := make(chan struct{})
 	defer close(doneCh)
@@ -608,6 +1158,9 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 		}
 	}()
 	checkTimeout := func() error {
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("deployment aborted: cancelled")
+		}
 		if ctx.Err() != nil {
 			return fmt.Errorf("deployment aborted: timeout of %ds exceeded", deployTimeout)
 		}
@@ -630,11 +1183,22 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 		return verserrors.Wrap(err)
 	}
 	defer sshClient.Close()
+	d.configureCleanup(sshClient)
+
+	// Step 5.1: Make sure the remote has every tool the deploy relies on
+	if err := d.validateRemoteTools(sshClient); err != nil {
+		return err
+	}
 
 	// Step 5.5: Acquire deployment lock
-	lockDirPath := filepath.ToSlash(filepath.Join(d.env.RemotePath, ".versa.lock"))
+	// The lock dir is created non-recursively, so remote_path must exist first
+	// (a fresh server on --initial-deploy)
+	if _, err := d.ensureRemotePath(sshClient); err != nil {
+		return err
+	}
+	lockDirPath := d.lockPath()
 	d.log.Debug("Acquiring deployment lock...")
-	if err := sshClient.AcquireLock(lockDirPath); err != nil {
+	if err := sshClient.AcquireLock(lockDirPath, ssh.NewLockHolder(d.envName)); err != nil {
 		return err
 	}
 	defer func() {
@@ -654,7 +1218,7 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 	}
 	if exists {
 		d.log.Debug("Fetching deploy.lock from remote...")
-		tmpLockFile := filepath.Join(os.TempDir(), fmt.Sprintf("deploy-%s-%s.lock", d.envName, artifact.ReleaseVersion))
+		tmpLockFile := filepath.Join(d.tempDir(), fmt.Sprintf("deploy-%s-%s.lock", d.envName, artifact.ReleaseVersion))
 		if err := sshClient.DownloadFile(lockPath, tmpLockFile); err != nil {
 			return err
 		}
@@ -675,9 +1239,13 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 	}
 
 	// Step 7: Skip if server already has this exact commit (unless --force)
-	if previousLock != nil && previousLock.LastDeploy.CommitHash == artifact.CommitHash && !d.force {
-		d.log.Info("Server already at commit %s — skipping", artifact.CommitHash[:8])
-		return nil
+	if isLiveCommit(previousLock, artifact.CommitHash, artifact.DirtyTree) {
+		if !d.force {
+			d.log.Info("Server already at commit %s — skipping", artifact.CommitHash[:8])
+			return nil
+		}
+		d.log.Warn("⚠  Commit %s is already live as release %s; deploying it again creates a duplicate release",
+			artifact.CommitHash[:8], previousLock.LastDeploy.ReleaseDir)
 	}
 
 	// Step 11: Upload artifact chunks
@@ -685,7 +1253,7 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 		return err
 	}
 	releasesDir := filepath.ToSlash(filepath.Join(d.env.RemotePath, "releases"))
-	stagingDir := filepath.ToSlash(filepath.Join(releasesDir, artifact.ReleaseVersion+".staging"))
+	stagingDir := filepath.ToSlash(filepath.Join(releasesDir, artifact.ReleaseVersion+".staging-"+artifact.runID))
 	finalDir := filepath.ToSlash(filepath.Join(releasesDir, artifact.ReleaseVersion))
 	archiveName := fmt.Sprintf("%s.tar.gz", artifact.ReleaseVersion)
 	remoteArchive := filepath.ToSlash(filepath.Join(d.env.RemotePath, archiveName))
@@ -693,6 +1261,9 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 	if err := sshClient.MkdirAll(releasesDir); err != nil {
 		return err
 	}
+	if err := d.applyDirMode(sshClient, releasesDir); err != nil {
+		return err
+	}
 
 	// Disk space check using total chunk size
 	var totalSize int64
@@ -703,13 +1274,16 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 	}
 	if totalSize > 0 {
 		d.log.Debug("Artifact size: %d MB", totalSize/(1024*1024))
-		if err := sshClient.CheckDiskSpace(releasesDir, totalSize); err != nil {
+		if err := d.checkDiskSpace(sshClient, releasesDir, totalSize); err != nil {
+			return verserrors.Wrap(err)
+		}
+		if err := d.checkInodes(sshClient, releasesDir, artifact.artifactDir); err != nil {
 			return verserrors.Wrap(err)
 		}
 	}
 
 	d.log.Info("Uploading %d chunks in parallel to remote server...", len(artifact.ChunkPaths))
-	if err := sshClient.UploadFilesParallel(artifact.ChunkPaths, d.env.RemotePath, 4); err != nil {
+	if err := sshClient.UploadFilesParallel(artifact.ChunkPaths, d.env.RemotePath, d.uploadStreams()); err != nil {
 		return fmt.Errorf("parallel upload failed: %w", err)
 	}
 
@@ -726,15 +1300,31 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 		return err
 	}
 	sshClient.ExecuteCommand(fmt.Sprintf("rm -f -- %q", remoteArchive))
-	if _, err := sshClient.ExecuteCommand(fmt.Sprintf("mv -T -- %q %q", stagingDir, finalDir)); err != nil {
+	if err := d.applyDirMode(sshClient, stagingDir); err != nil {
+		sshClient.ExecuteCommand(fmt.Sprintf("rm -rf -- %q", stagingDir))
+		return err
+	}
+	if err := d.verifyExtractedFiles(sshClient, artifact.artifactDir, stagingDir); err != nil {
+		sshClient.ExecuteCommand(fmt.Sprintf("rm -rf -- %q", stagingDir))
+		return err
+	}
+	if _, err := sshClient.ExecuteCommand(renameDirCmd(sshClient, stagingDir, finalDir)); err != nil {
 		sshClient.ExecuteCommand(fmt.Sprintf("rm -rf -- %q", stagingDir))
 		return fmt.Errorf("failed to finalize release: %w", err)
 	}
 
-	// Step 11.5: Handle shared paths
+	// Step 11.5: Handle shared paths and secret files
 	if err := d.handleSharedPaths(sshClient, finalDir); err != nil {
 		return err
 	}
+	if err := d.handleSecretFiles(sshClient, finalDir); err != nil {
+		return err
+	}
+
+	// Create empty runtime directories the artifact can't carry (ensure_dirs)
+	if err := d.ensureDirs(sshClient, finalDir); err != nil {
+		return err
+	}
 
 	// Step 11.6 & 11.7: Reuse dependencies and preserved paths from previous release
 	if previousLock != nil {
@@ -744,6 +1334,8 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 		if err := d.handlePreservedPaths(sshClient, previousLock.LastDeploy.ReleaseDir, finalDir); err != nil {
 			return err
 		}
+	} else {
+		d.log.Debug("Dependency reuse skipped: no previous release")
 	}
 
 	// Step 11.8: Validate runtime artifacts
@@ -751,6 +1343,9 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 		return err
 	}
 
+	// Step 11.9: Hand the runtime dirs to owner, now that the steps above have created them
+	d.applyOwner(sshClient, finalDir)
+
 	// Step 12: Pre-deploy server hooks
 	if err := checkTimeout(); err != nil {
 		return err
@@ -772,18 +1367,24 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 	// Step 13.5: Reload services
 	d.executeServicesReload(sshClient)
 
-	// Step 14: Post-deploy hooks
-	skipPostDeploy := false
-	if d.initialDeploy && len(d.env.PostDeploy) > 0 && d.PostDeployConfirm != nil {
-		if !d.PostDeployConfirm() {
-			d.log.Info("Post-deploy hooks skipped by user (initial deploy)")
-			skipPostDeploy = true
-		}
+	// Step 13.6: Restart systemd services
+	if err := d.restartSystemdServices(sshClient); err != nil {
+		return d.rollbackAfterServiceFailure(sshClient, previousLock, err)
 	}
-	if !skipPostDeploy {
-		if err := d.executePostDeployHooks(sshClient, finalDir, previousLock); err != nil {
-			return err
-		}
+
+	// Step 13.8: Route/Twig cache commands
+	if err := d.executeCacheCommands(sshClient, finalDir, artifact.BuildResult, previousLock); err != nil {
+		return err
+	}
+
+	// Step 14: First-deploy and post-deploy hooks
+	if err := d.executeDeployHooks(sshClient, finalDir, previousLock); err != nil {
+		return err
+	}
+
+	// Step 14.2: Smoke tests
+	if err := d.executeSmokeTests(sshClient, finalDir, previousLock); err != nil {
+		return err
 	}
 
 	// Step 14.5: Health check
@@ -791,40 +1392,53 @@ func (d *Deployer) DeployWithArtifact(artifact *PrebuiltArtifact) (returnErr err
 		return err
 	}
 
+	// Step 14.6: Warm caches
+	if err := d.warmCaches(previousLock, sshClient); err != nil {
+		return err
+	}
+
 	// Step 15: Update deploy.lock
 	d.log.Info("Updating deploy.lock...")
 	cs := artifact.ChangeSet
-	newLock := state.New(artifact.CommitHash, artifact.ReleaseVersion, cs.AllFileHashes, cs.ComposerHash, cs.PackageHash, cs.GoModHash, cs.RequirementsHash)
+	newLock := d.newDeployLock(artifact.CommitHash, artifact.ReleaseVersion, cs)
+	newLock.LastDeploy.DirtyTree = artifact.DirtyTree
 	lockData, err := newLock.ToJSON()
 	if err != nil {
 		return err
 	}
-	tmpLockNew := filepath.Join(os.TempDir(), fmt.Sprintf("deploy-%s.lock.new", d.envName))
-	if err := os.WriteFile(tmpLockNew, lockData, 0644); err != nil {
-		return err
-	}
-	defer os.Remove(tmpLockNew)
-	tmpUploadDir := filepath.Join(os.TempDir(), fmt.Sprintf("lockupload-%s", d.envName))
-	os.MkdirAll(tmpUploadDir, 0775)
-	defer os.RemoveAll(tmpUploadDir)
-	lockUploadPath := filepath.Join(tmpUploadDir, "deploy.lock")
-	if err := os.WriteFile(lockUploadPath, lockData, 0644); err != nil {
-		return err
-	}
-	if err := sshClient.UploadDirectory(tmpUploadDir, d.env.RemotePath); err != nil {
+	if err := sshClient.WriteRemoteFileAtomic(lockPath, lockData); err != nil {
 		d.log.Error("Failed to upload deploy.lock: %v", err)
 	}
 
+	// Step 15.5: Snapshot deploy.lock into the release
+	d.snapshotReleaseLock(sshClient, finalDir, lockData)
+	d.recordHistory(sshClient, newLock)
+
 	// Step 16: Cleanup old releases
 	d.log.Info("Cleaning up old releases...")
 	if err := sshClient.CleanupOldReleases(releasesDir, ReleasesToKeep); err != nil {
-		d.log.Error("Failed to cleanup old releases: %v", err)
+		d.log.Warn("Failed to cleanup old releases: %v", err)
 	}
 
+	// Step 16.5: Prune shared paths
+	d.pruneSharedPaths(sshClient)
+
 	d.log.Success("Deployment to %s successful!", d.envName)
 	return nil
 }
 
+// newDeployLock builds the deploy.lock recording release as deployed from commitHash
+// with the hashes of cs
+func (d *Deployer) newDeployLock(commitHash, release string, cs *changeset.ChangeSet) *state.DeployLock {
+	lock := state.New(commitHash, release, cs.AllFileHashes, cs.ComposerHash, cs.PackageHash, cs.GoModHash, cs.RequirementsHash)
+	lock.LastDeploy.ComposerLockHash = cs.ComposerLockHash
+	lock.LastDeploy.GoSumHash = cs.GoSumHash
+	lock.LastDeploy.WindowOverride = d.windowOverridden
+	lock.LastDeploy.Message = d.Message
+	lock.LastDeploy.DirtyTree = d.dirtyTree
+	return lock
+}
+
 // rollback attempts to rollback to previous release
 func (d *Deployer) rollback(sshClient *ssh.Client, previousLock *state.DeployLock) error {
 	if previousLock == nil {
@@ -837,14 +1451,36 @@ func (d *Deployer) rollback(sshClient *ssh.Client, previousLock *state.DeployLoc
 	return sshClient.CreateSymlink(relativeTarget, currentSymlink)
 }
 
-func (d *Deployer) runHook(sshClient *ssh.Client, finalDir, hook string, previousLock *state.DeployLock) error {
+// wrapRemoteHook builds the command for a hook run inside the release's app dir. When a hook
+// user is set (per hook or hook_user), the whole 'cd && command' runs under passwordless sudo.
+func (d *Deployer) wrapRemoteHook(appPath, hook, user string) string {
+	if user == "" {
+		user = d.env.HookUser
+	}
+	wrapped := fmt.Sprintf("cd %s && %s", appPath, hook)
+	if user == "" {
+		return wrapped
+	}
+	return fmt.Sprintf("sudo -n -u %s -- sh -c %s", user, ssh.ShellQuote(wrapped))
+}
+
+// hookWorkDir returns the directory a remote hook runs in: dir relative to the
+// release root, or the release's app directory when dir is empty
+func hookWorkDir(releaseDir, dir string) string {
+	if dir == "" {
+		dir = "app"
+	}
+	return filepath.ToSlash(filepath.Join(releaseDir, dir))
+}
+
+func (d *Deployer) runHook(sshClient *ssh.Client, finalDir, hook, dir, user string, previousLock *state.DeployLock) error {
 	hookTimeout := time.Duration(d.env.HookTimeout) * time.Second
 	if hookTimeout <= 0 {
 		hookTimeout = 300 * time.Second
 	}
 
-	appPath := filepath.ToSlash(filepath.Join(finalDir, "app"))
-	wrappedHook := fmt.Sprintf("cd %s && %s", appPath, hook)
+	appPath := hookWorkDir(finalDir, dir)
+	wrappedHook := d.wrapRemoteHook(appPath, hook, user)
 
 	d.log.Info("Executing: %s (in %s)", hook, appPath)
 	output, err := sshClient.ExecuteCommandWithTimeout(wrappedHook, hookTimeout)
@@ -872,19 +1508,76 @@ func (d *Deployer) executePostDeployHooks(sshClient *ssh.Client, finalDir string
 	}
 
 	d.log.Info("Running post-deploy hooks...")
+	return d.executeHookList(sshClient, finalDir, d.env.PostDeploy, rollbackLock)
+}
+
+// executeCacheCommands runs the PHP route_cache_command when the build flagged
+// RouteCacheRegenerate and twig_cache_command when it flagged TwigCacheCleanup, in
+// the PHP root of the new release. A failing command rolls back like a hook.
+func (d *Deployer) executeCacheCommands(sshClient *ssh.Client, finalDir string, result *builder.BuildResult, previousLock *state.DeployLock) error {
+	php := d.env.Builds.PHP
+	if result == nil || !php.Enabled {
+		return nil
+	}
+
+	dir := filepath.ToSlash(filepath.Join("app", php.ProjectRoot))
+	if result.RouteCacheRegenerate && php.RouteCacheCommand != "" {
+		d.log.Info("Routes changed, regenerating route cache...")
+		if err := d.runHook(sshClient, finalDir, php.RouteCacheCommand, dir, "", previousLock); err != nil {
+			return err
+		}
+	}
+	if result.TwigCacheCleanup && php.TwigCacheCommand != "" {
+		d.log.Info("Twig templates changed, clearing Twig cache...")
+		if err := d.runHook(sshClient, finalDir, php.TwigCacheCommand, dir, "", previousLock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeDeployHooks runs the first_deploy hooks, then the post_deploy hooks unless
+// PostDeployConfirm declines them on an initial deploy
+func (d *Deployer) executeDeployHooks(sshClient *ssh.Client, finalDir string, previousLock *state.DeployLock) error {
+	if err := d.executeFirstDeployHooks(sshClient, finalDir, previousLock); err != nil {
+		return err
+	}
+	if d.initialDeploy && len(d.env.PostDeploy) > 0 && d.PostDeployConfirm != nil && !d.PostDeployConfirm() {
+		d.log.Info("Post-deploy hooks skipped by user (initial deploy)")
+		return nil
+	}
+	return d.executePostDeployHooks(sshClient, finalDir, previousLock)
+}
+
+// executeFirstDeployHooks runs first_deploy hooks, only when the server had no
+// deploy.lock yet (an --initial-deploy). They fail the deploy like post_deploy hooks.
+func (d *Deployer) executeFirstDeployHooks(sshClient *ssh.Client, finalDir string, previousLock *state.DeployLock) error {
+	if len(d.env.FirstDeploy) == 0 || previousLock != nil {
+		return nil
+	}
 
-	for _, hookConfig := range d.env.PostDeploy {
+	d.log.Info("Running first-deploy hooks...")
+	return d.executeHookList(sshClient, finalDir, d.env.FirstDeploy, previousLock)
+}
+
+// executeHookList runs hooks in order, running each parallel group concurrently
+func (d *Deployer) executeHookList(sshClient *ssh.Client, finalDir string, hooks []config.HookConfig, rollbackLock *state.DeployLock) error {
+	for _, hookConfig := range hooks {
+		if !d.hookRunsHere(hookConfig) {
+			continue
+		}
 		if hookConfig.Command != "" {
-			if err := d.runHook(sshClient, finalDir, hookConfig.Command, rollbackLock); err != nil {
+			if err := d.runHook(sshClient, finalDir, hookConfig.Command, hookConfig.Dir, hookConfig.User, rollbackLock); err != nil {
 				return err
 			}
 		} else if len(hookConfig.Parallel) > 0 {
 			var g errgroup.Group
+			d.limitGroup(&g)
 			d.log.Info("Executing parallel hook group (%d commands)...", len(hookConfig.Parallel))
 			for _, h := range hookConfig.Parallel {
-				cmd := h // closure capture
+				cmd, dir, user := h, hookConfig.Dir, hookConfig.User // closure capture
 				g.Go(func() error {
-					return d.runHook(sshClient, finalDir, cmd, rollbackLock)
+					return d.runHook(sshClient, finalDir, cmd, dir, user, rollbackLock)
 				})
 			}
 			if err := g.Wait(); err != nil {
@@ -896,6 +1589,73 @@ func (d *Deployer) executePostDeployHooks(sshClient *ssh.Client, finalDir string
 	return nil
 }
 
+// hookRunsHere reports whether a hook runs on this server according to its run_on:
+// "first" (the default, safe for migrations) and "primary" run on one host only,
+// "all" runs everywhere. Skipped hooks are logged.
+func (d *Deployer) hookRunsHere(h config.HookConfig) bool {
+	runs := true
+	switch h.RunOn {
+	case config.RunOnAll:
+	case config.RunOnPrimary:
+		runs = d.ServerIndex == d.PrimaryIndex
+	default:
+		runs = d.ServerIndex == 0
+	}
+	if !runs {
+		label := h.Name
+		if label == "" {
+			label = h.Command
+		}
+		if label == "" {
+			label = strings.Join(h.Parallel, ", ")
+		}
+		runOn := h.RunOn
+		if runOn == "" {
+			runOn = config.RunOnFirst
+		}
+		d.log.Info("Skipping hook %q on this server (run_on: %s)", label, runOn)
+	}
+	return runs
+}
+
+// executeSmokeTests runs smoke_tests one at a time in the new release and always
+// prints their output. A failing (non-zero) test rolls back like a failed hook.
+func (d *Deployer) executeSmokeTests(sshClient *ssh.Client, finalDir string, previousLock *state.DeployLock) error {
+	if len(d.env.SmokeTests) == 0 {
+		return nil
+	}
+
+	timeout := time.Duration(d.env.HookTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 300 * time.Second
+	}
+
+	d.log.Info("Running smoke tests...")
+	for _, test := range d.env.SmokeTests {
+		cmds := test.Parallel
+		if test.Command != "" {
+			cmds = []string{test.Command}
+		}
+		for _, cmd := range cmds {
+			appPath := hookWorkDir(finalDir, test.Dir)
+			d.log.Info("Smoke test: %s (in %s)", cmd, appPath)
+			output, err := sshClient.ExecuteCommandWithTimeout(d.wrapRemoteHook(appPath, cmd, test.User), timeout)
+			if out := strings.TrimSpace(output); out != "" {
+				d.log.Info("%s", out)
+			}
+			if err == nil {
+				continue
+			}
+
+			d.log.Error("Smoke test failed: %s", cmd)
+			return d.rollbackAfterFailure(sshClient, previousLock, "smoke test", fmt.Errorf("%s: %w", cmd, err))
+		}
+	}
+
+	d.log.Success("Smoke tests passed")
+	return nil
+}
+
 // executePreDeployLocal runs pre_deploy_local hooks locally; aborts deploy on failure.
 func (d *Deployer) executePreDeployLocal() error {
 	if len(d.env.PreDeployLocal) == 0 {
@@ -935,16 +1695,20 @@ func (d *Deployer) executePreDeployServer(sshClient *ssh.Client, finalDir string
 
 	d.log.Info("Running pre_deploy_server hooks (non-fatal)...")
 	for _, hookConfig := range d.env.PreDeployServer {
+		if !d.hookRunsHere(hookConfig) {
+			continue
+		}
 		if hookConfig.Command != "" {
-			if err := d.runHook(sshClient, finalDir, hookConfig.Command, nil); err != nil {
+			if err := d.runHook(sshClient, finalDir, hookConfig.Command, hookConfig.Dir, hookConfig.User, nil); err != nil {
 				d.log.Warn("pre_deploy_server hook failed (continuing): %v", err)
 			}
 		} else if len(hookConfig.Parallel) > 0 {
 			var g errgroup.Group
+			d.limitGroup(&g)
 			for _, h := range hookConfig.Parallel {
-				cmd := h
+				cmd, dir, user := h, hookConfig.Dir, hookConfig.User
 				g.Go(func() error {
-					return d.runHook(sshClient, finalDir, cmd, nil)
+					return d.runHook(sshClient, finalDir, cmd, dir, user, nil)
 				})
 			}
 			if err := g.Wait(); err != nil {
@@ -954,6 +1718,203 @@ func (d *Deployer) executePreDeployServer(sshClient *ssh.Client, finalDir string
 	}
 }
 
+// maxLockBytes caps how much of a deploy.lock snapshot is read back from the server
+const maxLockBytes = 64 << 20
+
+// snapshotReleaseLock stores a copy of deploy.lock inside the release dir. Non-fatal.
+func (d *Deployer) snapshotReleaseLock(sshClient *ssh.Client, releaseDir string, lockData []byte) {
+	snapshot := filepath.ToSlash(filepath.Join(releaseDir, "deploy.lock"))
+	if err := sshClient.WriteRemoteBytes(snapshot, lockData); err != nil {
+		d.log.Warn("Failed to snapshot deploy.lock into release: %v", err)
+	}
+}
+
+// recordHistory appends the deploy to <remote_path>/deploy-history.jsonl and trims it to
+// history_limit entries. The file is rewritten atomically while the deployment lock is
+// held, so concurrent deploys never interleave their writes. Non-fatal.
+func (d *Deployer) recordHistory(sshClient *ssh.Client, lock *state.DeployLock) {
+	d.appendHistory(sshClient, state.HistoryEntry{
+		Timestamp:      lock.LastDeploy.Timestamp,
+		ReleaseDir:     lock.LastDeploy.ReleaseDir,
+		CommitHash:     lock.LastDeploy.CommitHash,
+		Message:        lock.LastDeploy.Message,
+		WindowOverride: lock.LastDeploy.WindowOverride,
+	})
+}
+
+// recordRollback appends a rollback to release to the deploy history, so the newest
+// entry keeps matching the live release. The commit comes from the release's deploy.lock
+// snapshot when it has one. Non-fatal.
+func (d *Deployer) recordRollback(sshClient *ssh.Client, release string) {
+	entry := state.HistoryEntry{
+		Timestamp:  time.Now(),
+		Action:     state.HistoryActionRollback,
+		ReleaseDir: release,
+	}
+	snapshot := filepath.ToSlash(filepath.Join(d.env.RemotePath, "releases", release, "deploy.lock"))
+	if data, err := sshClient.ReadRemoteBytes(snapshot, maxLockBytes); err == nil {
+		if lock, err := state.Parse(data); err == nil {
+			entry.CommitHash = lock.LastDeploy.CommitHash
+		}
+	}
+	d.appendHistory(sshClient, entry)
+}
+
+// appendHistory fills in the environment, user and host of entry and appends it to
+// <remote_path>/deploy-history.jsonl, trimmed to history_limit entries
+func (d *Deployer) appendHistory(sshClient *ssh.Client, entry state.HistoryEntry) {
+	historyPath := filepath.ToSlash(filepath.Join(d.env.RemotePath, "deploy-history.jsonl"))
+
+	var existing []byte
+	exists, err := sshClient.FileExists(historyPath)
+	if err == nil && exists {
+		existing, err = sshClient.ReadRemoteBytes(historyPath, maxLockBytes)
+	}
+	if err != nil {
+		// Rewriting without the old entries would lose them
+		d.log.Warn("Failed to read deploy history, not recording release %s: %v", entry.ReleaseDir, err)
+		return
+	}
+
+	holder := ssh.NewLockHolder(d.envName)
+	entry.Environment = d.envName
+	entry.User = holder.User
+	entry.Host = holder.Host
+	limit := d.env.HistoryLimit
+	if limit == 0 {
+		limit = state.DefaultHistoryLimit
+	}
+	data, err := state.AppendHistory(existing, entry, limit)
+	if err == nil {
+		err = sshClient.WriteRemoteFileAtomic(historyPath, data)
+	}
+	if err != nil {
+		d.log.Warn("Failed to record deploy history: %v", err)
+	}
+}
+
+// promoteReleaseLock makes the rolled-back-to release's deploy.lock snapshot the top-level
+// deploy.lock, so the next deploy detects changes against what is actually live.
+func (d *Deployer) promoteReleaseLock(sshClient *ssh.Client, release string) {
+	if err := PromoteReleaseLock(sshClient, d.env.RemotePath, release); err != nil {
+		d.log.Warn("%v; deploy.lock still describes the newest deploy, use --force on the next deploy", err)
+		return
+	}
+	d.log.Info("Restored deploy.lock from release %s", release)
+}
+
+// PromoteReleaseLock copies releases/<release>/deploy.lock over the top-level deploy.lock.
+// Used after any rollback, including the TUI's, which switches the symlink directly.
+func PromoteReleaseLock(sshClient *ssh.Client, remotePath, release string) error {
+	snapshot := filepath.ToSlash(filepath.Join(remotePath, "releases", release, "deploy.lock"))
+	data, err := sshClient.ReadRemoteBytes(snapshot, maxLockBytes)
+	if err != nil {
+		return fmt.Errorf("release %s has no deploy.lock snapshot", release)
+	}
+	if _, err := state.Parse(data); err != nil {
+		return fmt.Errorf("invalid deploy.lock snapshot in %s: %w", release, err)
+	}
+
+	lockPath := filepath.ToSlash(filepath.Join(remotePath, "deploy.lock"))
+	if err := sshClient.WriteRemoteFileAtomic(lockPath, data); err != nil {
+		return fmt.Errorf("failed to restore deploy.lock from %s: %w", release, err)
+	}
+	return nil
+}
+
+// rollbackDepthWarning is how many releases behind the newest a rollback may land
+// before it warns that the target is getting stale
+const rollbackDepthWarning = 3
+
+// previousReleaseOf returns the release just older than current in sorted (newest
+// first), so repeated rollbacks keep stepping back. When current is not among the
+// releases, the newest one is returned.
+func previousReleaseOf(sorted []string, current string) (string, error) {
+	for i, release := range sorted {
+		if release != current {
+			continue
+		}
+		if i == len(sorted)-1 {
+			return "", fmt.Errorf("release %s is already the oldest available release", current)
+		}
+		return sorted[i+1], nil
+	}
+	if len(sorted) == 0 {
+		return "", fmt.Errorf("could not determine previous release")
+	}
+	return sorted[0], nil
+}
+
+// resolveRollbackTarget turns the --to aliases "oldest" and "newest" into a release
+// name from sorted (newest first). Other targets are returned unchanged.
+func resolveRollbackTarget(sorted []string, target string) string {
+	if len(sorted) == 0 {
+		return target
+	}
+	switch target {
+	case "newest":
+		return sorted[0]
+	case "oldest":
+		return sorted[len(sorted)-1]
+	}
+	return target
+}
+
+// rollbackDepth returns how many releases were deployed after target, counted from
+// the deploy history (oldest first) since target's own deploy. Rollback entries are
+// not counted. When target's deploy is not in the history (trimmed, or deployed
+// before history was kept) its position in sorted (newest first) is used instead.
+func rollbackDepth(history []state.HistoryEntry, sorted []string, target string) int {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Action != "" || history[i].ReleaseDir != target {
+			continue
+		}
+		depth := 0
+		for _, entry := range history[i+1:] {
+			if entry.Action == "" {
+				depth++
+			}
+		}
+		return depth
+	}
+	return slices.Index(sorted, target)
+}
+
+// readHistory returns the entries of <remote_path>/deploy-history.jsonl, or nil when
+// there is none or it cannot be read
+func (d *Deployer) readHistory(sshClient *ssh.Client) []state.HistoryEntry {
+	historyPath := filepath.ToSlash(filepath.Join(d.env.RemotePath, "deploy-history.jsonl"))
+	if exists, err := sshClient.FileExists(historyPath); err != nil || !exists {
+		return nil
+	}
+	data, err := sshClient.ReadRemoteBytes(historyPath, maxLockBytes)
+	if err != nil {
+		d.log.Debug("Failed to read deploy history: %v", err)
+		return nil
+	}
+	entries, err := state.ParseHistory(data)
+	if err != nil {
+		d.log.Debug("Failed to parse deploy history: %v", err)
+		return nil
+	}
+	return entries
+}
+
+// warnRollbackDepth warns when target, a release in sorted (newest first), was
+// deployed rollbackDepthWarning or more releases ago according to the deploy history,
+// or is the oldest one left
+func (d *Deployer) warnRollbackDepth(sshClient *ssh.Client, sorted []string, target string) {
+	if !slices.Contains(sorted, target) {
+		return
+	}
+	if depth := rollbackDepth(d.readHistory(sshClient), sorted, target); depth >= rollbackDepthWarning {
+		d.log.Warn("Release %s is %d releases behind the newest deploy", target, depth)
+	}
+	if len(sorted) > 1 && sorted[len(sorted)-1] == target {
+		d.log.Warn("Release %s is the oldest available release; there is nothing further to roll back to", target)
+	}
+}
+
 // Rollback rolls back to the previous release
 func (d *Deployer) Rollback() error {
 	d.log.Info("Rolling back %s...", d.envName)
@@ -989,28 +1950,31 @@ func (d *Deployer) Rollback() error {
 	state.SortReleases(releases)
 	sorted := releases
 
-	// Find previous (skip current if it's in the list)
-	var previousRelease string
-	currentRelease := filepath.Base(currentTarget)
-	for _, release := range sorted {
-		if release != currentRelease {
-			previousRelease = release
-			break
-		}
-	}
-
-	if previousRelease == "" {
-		return fmt.Errorf("could not determine previous release")
+	previousRelease, err := previousReleaseOf(sorted, filepath.Base(currentTarget))
+	if err != nil {
+		return err
 	}
+	d.warnRollbackDepth(sshClient, sorted, previousRelease)
 
 	d.log.Info("Rolling back to: %s", previousRelease)
 
+	if d.dryRun {
+		d.log.Info("DRY RUN - would switch current -> releases/%s", previousRelease)
+		return nil
+	}
+
 	// Switch symlink
 	relativeTarget := filepath.ToSlash(filepath.Join("releases", previousRelease))
 	if err := sshClient.CreateSymlink(relativeTarget, currentSymlink); err != nil {
 		return err
 	}
 
+	if err := d.restartSystemdServices(sshClient); err != nil {
+		d.log.Warn("Service restart after rollback failed: %v", err)
+	}
+	d.promoteReleaseLock(sshClient, previousRelease)
+	d.recordRollback(sshClient, previousRelease)
+
 	d.log.Success("Rollback successful!")
 	return nil
 }
@@ -1043,143 +2007,956 @@ func (d *Deployer) Status() error {
 		return err
 	}
 
+	messages := ReleaseMessages(sshClient, releasesDir, releases)
 	d.log.Info("Available releases: %d", len(releases))
 	for _, release := range releases {
 		marker := " "
 		if release == filepath.Base(currentTarget) {
 			marker = "→"
 		}
-		d.log.Info("  %s %s", marker, release)
+		if msg := messages[release]; msg != "" {
+			d.log.Info("  %s %s  %s", marker, release, msg)
+		} else {
+			d.log.Info("  %s %s", marker, release)
+		}
 	}
 
 	return nil
 }
 
-// calculateDirectorySize calculates the total size of a directory
-func (d *Deployer) calculateDirectorySize(dirPath string) (int64, error) {
-	return fsutil.CalculateDirSize(dirPath)
+// ReleaseMessages reads the deploy message of each release from its manifest.json,
+// keyed by release. Releases without a message (or a readable manifest) are left out;
+// multi-line messages are cut to their first line.
+func ReleaseMessages(sshClient *ssh.Client, releasesDir string, releases []string) map[string]string {
+	messages := make(map[string]string)
+	for _, release := range releases {
+		data, err := sshClient.ReadRemoteBytes(filepath.ToSlash(filepath.Join(releasesDir, release, "manifest.json")), 1<<20)
+		if err != nil {
+			continue
+		}
+		m, err := artifact.ParseManifest(data)
+		if err != nil || m.Message == "" {
+			continue
+		}
+		msg, _, _ := strings.Cut(strings.TrimSpace(m.Message), "\n")
+		messages[release] = msg
+	}
+	return messages
+}
+
+// largestFilesReported is how many files reportLargestFiles lists in debug mode
+const largestFilesReported = 20
+
+// reportLargestFiles lists the largest files in the artifact when --debug is set
+func (d *Deployer) reportLargestFiles(artifactDir string) {
+	if !d.log.DebugEnabled() {
+		return
+	}
+	files, err := fsutil.LargestFiles(artifactDir, largestFilesReported)
+	if err != nil {
+		d.log.Debug("Could not list largest artifact files: %v", err)
+		return
+	}
+	d.log.Debug("Largest files in artifact:")
+	for _, e := range files {
+		d.log.Debug("  %9s  %s", fsutil.HumanSize(e.Size), e.Path)
+	}
+}
+
+// checkDiskSpace verifies the server has room for the artifact, unless the check is
+// disabled with --skip-disk-check or skip_disk_check. The archive chunks are staged in
+// remote_path and extracted under releasesDir, so when those are separate mounts each
+// one is checked.
+func (d *Deployer) checkDiskSpace(sshClient *ssh.Client, releasesDir string, size int64) error {
+	if d.SkipDiskCheck || d.env.SkipDiskCheck {
+		d.log.Warn("Skipping disk space check")
+		return nil
+	}
+	return sshClient.CheckDiskSpaceOn([]string{releasesDir, d.env.RemotePath}, size)
+}
+
+// checkRemotePreflight checks, without building or uploading, that the server could take
+// a release: remote_path is writable and there is room for one more release. The size is
+// estimated from the active release, or from the clone on a first deploy.
+func (d *Deployer) checkRemotePreflight(sshClient *ssh.Client, tmpRepo string, previousLock *state.DeployLock) error {
+	if err := checkRemoteWritable(sshClient, d.env.RemotePath); err != nil {
+		return verserrors.New(verserrors.CodeDeploymentFailed, fmt.Sprintf("Remote path is not writable: %v", err),
+			"Check that the SSH user owns remote_path (or can create it).", err)
+	}
+
+	releasesDir := filepath.ToSlash(filepath.Join(d.env.RemotePath, "releases"))
+	var estimate int64
+	if previousLock != nil {
+		current := filepath.ToSlash(filepath.Join(releasesDir, previousLock.LastDeploy.ReleaseDir))
+		if out, err := sshClient.ExecuteCommand(fmt.Sprintf("du -sk %s | cut -f1", ssh.ShellQuote(current))); err == nil {
+			if kb, err := strconv.ParseInt(strings.TrimSpace(out), 10, 64); err == nil {
+				estimate = kb * 1024
+			}
+		}
+	}
+	if estimate == 0 {
+		if size, err := d.calculateDirectorySize(tmpRepo); err == nil {
+			estimate = size
+		}
+	}
+	if err := d.checkDiskSpace(sshClient, releasesDir, estimate); err != nil {
+		return verserrors.Wrap(err)
+	}
+
+	d.log.Success("Remote checks passed: SSH, deployment lock, remote tools, write access, disk space (~%s per release)", fsutil.HumanSize(estimate))
+	return nil
+}
+
+// checkInodes verifies the server has enough free inodes to extract the artifact
+// (one per file and directory). Like the byte check it is skipped with
+// --skip-disk-check; inode_check decides whether a shortfall warns (default) or fails.
+func (d *Deployer) checkInodes(sshClient *ssh.Client, releasesDir, artifactDir string) error {
+	if d.SkipDiskCheck || d.env.SkipDiskCheck || d.env.InodeCheck == "off" {
+		return nil
+	}
+	entries, err := fsutil.CountEntries(artifactDir)
+	if err != nil {
+		d.log.Warn("Could not count artifact files: %v", err)
+		return nil
+	}
+	err = sshClient.CheckInodesOn([]string{releasesDir, d.env.RemotePath}, entries)
+	if err != nil && d.env.InodeCheck != "fail" {
+		d.log.Warn("%v (set inode_check: fail to abort instead)", err)
+		return nil
+	}
+	return err
+}
+
+// tempDir returns the local scratch directory: TempDir, then temp_dir (relative to
+// the project), then the system temp directory
+func (d *Deployer) tempDir() string {
+	if d.TempDir != "" {
+		return d.TempDir
+	}
+	if dir := d.env.TempDir; dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(d.repoPath, dir)
+		}
+		return dir
+	}
+	return os.TempDir()
+}
+
+// timingsPath resolves timings_file, which is relative to the project
+func (d *Deployer) timingsPath() string {
+	if d.env.TimingsFile == "" || filepath.IsAbs(d.env.TimingsFile) {
+		return d.env.TimingsFile
+	}
+	return filepath.Join(d.repoPath, d.env.TimingsFile)
+}
+
+// ownRepoPaths returns temp_dir and timings_file relative to the repository when
+// they are inside it. versa writes there itself, so they must neither make the tree
+// dirty nor be copied into a --allow-dirty build.
+func (d *Deployer) ownRepoPaths() []string {
+	var paths []string
+	for _, p := range []string{d.tempDir(), d.timingsPath()} {
+		if p == "" {
+			continue
+		}
+		rel, err := filepath.Rel(d.repoPath, p)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		paths = append(paths, rel)
+	}
+	return paths
+}
+
+// checkTempDir creates the scratch directory if needed and verifies it is writable,
+// so a bad temp_dir fails before anything is cloned or built
+func (d *Deployer) checkTempDir() error {
+	dir := d.tempDir()
+	if err := os.MkdirAll(dir, 0775); err != nil {
+		return fmt.Errorf("temp directory %s is not usable: %w", dir, err)
+	}
+	f, err := os.CreateTemp(dir, ".versadeploy-write-test-*")
+	if err != nil {
+		return fmt.Errorf("temp directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
+
+// checkLocalScratchSpace verifies, before cloning, that the scratch directory has
+// room for the clone and for the artifact built from it, each estimated at the size
+// of the local repository. The archive is checked once the artifact's size is known.
+func (d *Deployer) checkLocalScratchSpace() error {
+	if d.SkipDiskCheck || d.env.SkipDiskCheck {
+		return nil
+	}
+	size, err := d.calculateDirectorySize(d.repoPath)
+	if err != nil {
+		d.log.Warn("Could not calculate repository size: %v", err)
+		return nil
+	}
+	return d.checkLocalDiskSpace(2 * size)
+}
+
+// checkLocalDiskSpace verifies the scratch directory has size bytes free, e.g. for
+// the compressed archive, which takes at most the artifact's size. It is skipped
+// with the remote check (--skip-disk-check / skip_disk_check) and only warns when
+// free space cannot be determined.
+func (d *Deployer) checkLocalDiskSpace(size int64) error {
+	if d.SkipDiskCheck || d.env.SkipDiskCheck {
+		return nil
+	}
+	dir := d.tempDir()
+	available, err := fsutil.FreeSpace(dir)
+	if err != nil {
+		d.log.Warn("Could not check free space in %s: %v", dir, err)
+		return nil
+	}
+	if available < size {
+		return verserrors.New(verserrors.CodeBuildFailed,
+			fmt.Sprintf("not enough space in temp directory %s: %s free, %s needed", dir, fsutil.HumanSize(available), fsutil.HumanSize(size)),
+			"Point --temp-dir or temp_dir at a larger local disk",
+			nil)
+	}
+	return nil
+}
+
+// checkArtifactSize warns, or fails with StrictSize, when the artifact is larger than
+// max_artifact_size_mb, listing what takes the most space
+func (d *Deployer) checkArtifactSize(artifactDir string, size int64) error {
+	limit := int64(d.env.MaxArtifactSizeMB) * 1024 * 1024
+	if limit <= 0 || size <= limit {
+		return nil
+	}
+
+	d.log.Warn("!!! Artifact is %s, over max_artifact_size_mb (%d MB) !!!", fsutil.HumanSize(size), d.env.MaxArtifactSizeMB)
+	if dirs, err := fsutil.LargestDirs(artifactDir, 2, 5); err == nil && len(dirs) > 0 {
+		d.log.Warn("Largest directories:")
+		for _, e := range dirs {
+			d.log.Warn("  %9s  %s", fsutil.HumanSize(e.Size), e.Path)
+		}
+	}
+	if files, err := fsutil.LargestFiles(artifactDir, 10); err == nil && len(files) > 0 {
+		d.log.Warn("Largest files:")
+		for _, e := range files {
+			d.log.Warn("  %9s  %s", fsutil.HumanSize(e.Size), e.Path)
+		}
+	}
+
+	if d.StrictSize {
+		return verserrors.New(verserrors.CodeBuildFailed,
+			fmt.Sprintf("artifact is %s, over max_artifact_size_mb (%d MB)", fsutil.HumanSize(size), d.env.MaxArtifactSizeMB),
+			"Remove the large files from the repository, add them to ignored_paths or artifact_exclude, or raise max_artifact_size_mb",
+			nil)
+	}
+	return nil
+}
+
+// calculateDirectorySize calculates the total size of a directory
+func (d *Deployer) calculateDirectorySize(dirPath string) (int64, error) {
+	return fsutil.CalculateDirSize(dirPath)
+}
+
+// requiredRemoteTools are the commands the deploy runs on the remote server
+var requiredRemoteTools = []string{"tar", "cat", "ln", "mv", "df", "cp", "mkdir", "readlink"}
+
+// remoteProbeScript checks for each required tool and for GNU 'mv -T' support in one round-trip.
+// It prints "missing:<tool>" and "unsupported:<flag>" lines and always exits 0.
+var remoteProbeScript = "for t in " + strings.Join(requiredRemoteTools, " ") + "; do command -v $t >/dev/null 2>&1 || echo \"missing:$t\"; done; " +
+	"p=\"${TMPDIR:-/tmp}/.versa-probe-$$\"; mkdir -p \"$p/a\" && { mv -T \"$p/a\" \"$p/b\" >/dev/null 2>&1 || echo \"unsupported:mv -T\"; }; rm -rf \"$p\"; true"
+
+// validateRemoteTools probes the remote for required commands and flags before anything is uploaded
+func (d *Deployer) validateRemoteTools(sshClient *ssh.Client) error {
+	d.log.Debug("Probing remote tools...")
+	output, err := sshClient.ExecuteCommand(remoteProbeScript)
+	if err != nil {
+		d.log.Warn("Could not probe remote tools: %v", err)
+		return nil
+	}
+
+	missing, unsupported := parseRemoteProbe(output)
+	if len(missing) > 0 {
+		return verserrors.New(verserrors.CodeDeploymentFailed,
+			fmt.Sprintf("Remote server is missing required tools: %s", strings.Join(missing, ", ")),
+			"Install the missing commands on the server (e.g. coreutils, tar) and retry.", nil)
+	}
+	mvT := true
+	for _, flag := range unsupported {
+		if flag != "mv -T" {
+			continue
+		}
+		mvT = false
+		switch d.env.SSH.RemoteFlavor {
+		case "gnu":
+			return verserrors.New(verserrors.CodeDeploymentFailed,
+				"Remote mv doesn't support -T but ssh.remote_flavor is 'gnu'",
+				"Install GNU coreutils on the server, or set ssh.remote_flavor to 'busybox' (or remove it to auto-detect).", nil)
+		case "busybox":
+			// Already using portable commands
+		default:
+			d.log.Warn("Remote mv doesn't support -T (busybox/BSD), using portable symlink switching")
+			sshClient.SetPortableMv(true)
+		}
+	}
+	if mvT && d.env.SSH.RemoteFlavor == "" {
+		// Already probed here; spares CreateSymlink a second round-trip
+		sshClient.SetPortableMv(false)
+	}
+	return nil
+}
+
+// renameDirCmd renames a freshly extracted release dir. The target never exists yet,
+// so plain mv is safe when the remote lacks GNU 'mv -T'.
+func renameDirCmd(sshClient *ssh.Client, src, dst string) string {
+	if sshClient.PortableMv() {
+		return fmt.Sprintf("mv -- %q %q", src, dst)
+	}
+	return fmt.Sprintf("mv -T -- %q %q", src, dst)
+}
+
+// parseRemoteProbe extracts missing tools and unsupported flags from remoteProbeScript output
+func parseRemoteProbe(output string) (missing, unsupported []string) {
+	for _, line := range nonEmptyLines(output) {
+		switch {
+		case strings.HasPrefix(line, "missing:"):
+			missing = append(missing, strings.TrimPrefix(line, "missing:"))
+		case strings.HasPrefix(line, "unsupported:"):
+			unsupported = append(unsupported, strings.TrimPrefix(line, "unsupported:"))
+		}
+	}
+	return missing, unsupported
+}
+
+// validateLocalTools checks if necessary build tools are available on the system
+func (d *Deployer) validateLocalTools() error {
+	var g errgroup.Group
+
+	// Check PHP tools
+	if d.env.Builds.PHP.Enabled {
+		g.Go(func() error {
+			cmd := "composer"
+			if d.env.Builds.PHP.ComposerCommand != "" {
+				parts := strings.Fields(d.env.Builds.PHP.ComposerCommand)
+				if len(parts) > 0 {
+					cmd = parts[0]
+				}
+			}
+			if _, err := exec.LookPath(cmd); err != nil {
+				return verserrors.New(verserrors.CodeBuildFailed,
+					fmt.Sprintf("PHP build tool '%s' not found", cmd),
+					fmt.Sprintf("Install %s or ensure it is in your PATH.", cmd), nil)
+			}
+			return nil
+		})
+	}
+
+	// Check Go tools
+	if d.env.Builds.Go.Enabled {
+		g.Go(func() error {
+			if _, err := exec.LookPath("go"); err != nil {
+				return verserrors.New(verserrors.CodeBuildFailed,
+					"Go compiler not found",
+					"Install Go (https://golang.org/dl/) and ensure it is in your PATH.", nil)
+			}
+			return nil
+		})
+	}
+
+	// Check Git LFS
+	if d.env.GitLFS {
+		g.Go(func() error {
+			if err := git.LFSAvailable(); err != nil {
+				return verserrors.New(verserrors.CodeBuildFailed,
+					"git-lfs not found (required by git_lfs)",
+					"Install Git LFS (https://git-lfs.com) and run 'git lfs install', or turn off git_lfs.", nil)
+			}
+			return nil
+		})
+	}
+
+	// Check Frontend tools
+	if d.env.Builds.Frontend.Enabled {
+		g.Go(func() error {
+			tools := []string{}
+			if d.env.Builds.Frontend.NPMCommand != "" {
+				parts := strings.Fields(d.env.Builds.Frontend.NPMCommand)
+				if len(parts) > 0 {
+					tools = append(tools, parts[0])
+				}
+			}
+			if d.env.Builds.Frontend.CompileCommand != "" {
+				parts := strings.Fields(d.env.Builds.Frontend.CompileCommand)
+				if len(parts) > 0 {
+					cmd := parts[0]
+					if !strings.HasPrefix(cmd, "./") && !strings.HasPrefix(cmd, ".\\") {
+						tools = append(tools, cmd)
+					}
+				}
+			}
+
+			for _, tool := range tools {
+				if _, err := exec.LookPath(tool); err != nil {
+					return verserrors.New(verserrors.CodeBuildFailed,
+						fmt.Sprintf("Frontend build tool '%s' not found", tool),
+						fmt.Sprintf("Install %s (npm, pnpm, yarn, etc.) and ensure it is in your PATH.", tool), nil)
+				}
+			}
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+// handleSharedPaths manages symbolic links for persistent directories
+func (d *Deployer) handleSharedPaths(sshClient *ssh.Client, releaseDir string) error {
+	if len(d.env.SharedPaths) == 0 {
+		return nil
+	}
+
+	d.log.Info("Linking shared directories...")
+	sharedBase := filepath.ToSlash(filepath.Join(d.env.RemotePath, "shared"))
+
+	// All mkdir/rm/ln steps go out as one batch: one SSH round trip instead of
+	// several per shared path
+	steps := append([]string{fmt.Sprintf("mkdir -p -- %s", ssh.ShellQuote(sharedBase))}, d.chmodSteps(sharedBase)...)
+	type sharedLink struct{ cleanPath, sharedPath string }
+	var links []sharedLink
+
+	for _, path := range d.env.SharedPaths {
+		// Clean the path to avoid directory traversal or trailing slashes
+		cleanPath := filepath.ToSlash(filepath.Clean(path))
+		if strings.HasPrefix(cleanPath, "../") || cleanPath == ".." {
+			continue // Security: don't allow escaping release dir
+		}
+
+		// Path in release (now inside 'app' subfolder)
+		releasePath := filepath.ToSlash(filepath.Join(releaseDir, "app", cleanPath))
+		// Path in shared (e.g. shared/app/storage)
+		sharedPath := filepath.ToSlash(filepath.Join(sharedBase, cleanPath))
+
+		// 1. Ensure shared target exists
+		steps = append(steps, fmt.Sprintf("mkdir -p -- %s", ssh.ShellQuote(sharedPath)))
+		steps = append(steps, d.chmodSteps(sharedPath)...)
+		// 2. Remove directory in release if it exists to make room for symlink
+		// 3. Create parent directory in release if needed
+		// 4. Create symlink (use absolute path for shared target to be safe)
+		// We use ln -sfn directly for shared paths as they don't need the atomic switch logic of 'current'
+		steps = append(steps,
+			fmt.Sprintf("rm -rf -- %s", ssh.ShellQuote(releasePath)),
+			fmt.Sprintf("mkdir -p -- %s", ssh.ShellQuote(filepath.ToSlash(filepath.Dir(releasePath)))),
+			fmt.Sprintf("ln -sfn %s %s", ssh.ShellQuote(sharedPath), ssh.ShellQuote(releasePath)),
+			// Report the link target so it can be verified below
+			fmt.Sprintf("printf '%%s\\t%%s\\n' %s \"$(readlink %s)\"", ssh.ShellQuote(cleanPath), ssh.ShellQuote(releasePath)),
+		)
+		links = append(links, sharedLink{cleanPath, sharedPath})
+	}
+
+	output, err := sshClient.ExecuteBatch(steps)
+	if err != nil {
+		return fmt.Errorf("failed to link shared paths: %w", err)
+	}
+
+	// 5. Verify the links so uploads can't silently land in a per-release dir
+	targets := parseSharedLinks(output)
+	for _, link := range links {
+		actual := targets[link.cleanPath]
+		if actual == "" {
+			return fmt.Errorf("shared path %s is not a symlink after linking", link.cleanPath)
+		}
+		if !sharedLinkMatches(actual, link.sharedPath) {
+			return fmt.Errorf("shared path %s points to %s, expected %s", link.cleanPath, actual, link.sharedPath)
+		}
+		d.log.Info("  Linked: %s -> %s", link.cleanPath, link.sharedPath)
+	}
+
+	return nil
+}
+
+// handleSecretFiles links each secret file from shared/ into the release. Unlike
+// shared paths the shared file is never created: it holds configuration that only
+// the operator provides, so a missing one is reported instead.
+func (d *Deployer) handleSecretFiles(sshClient *ssh.Client, releaseDir string) error {
+	if len(d.env.SecretFiles) == 0 {
+		return nil
+	}
+
+	d.log.Info("Linking secret files...")
+	sharedBase := filepath.ToSlash(filepath.Join(d.env.RemotePath, "shared"))
+
+	var steps []string
+	for _, file := range d.env.SecretFiles {
+		releasePath := filepath.ToSlash(filepath.Join(releaseDir, "app", file))
+		sharedPath := filepath.ToSlash(filepath.Join(sharedBase, file))
+		steps = append(steps,
+			fmt.Sprintf("mkdir -p -- %s", ssh.ShellQuote(filepath.ToSlash(filepath.Dir(sharedPath)))),
+			fmt.Sprintf("rm -rf -- %s", ssh.ShellQuote(releasePath)),
+			fmt.Sprintf("mkdir -p -- %s", ssh.ShellQuote(filepath.ToSlash(filepath.Dir(releasePath)))),
+			fmt.Sprintf("ln -sfn %s %s", ssh.ShellQuote(sharedPath), ssh.ShellQuote(releasePath)),
+			fmt.Sprintf("if [ -f %s ]; then printf '%%s\tpresent\n' %s; else printf '%%s\tmissing\n' %s; fi",
+				ssh.ShellQuote(sharedPath), ssh.ShellQuote(file), ssh.ShellQuote(file)),
+		)
+	}
+
+	output, err := sshClient.ExecuteBatch(steps)
+	if err != nil {
+		return fmt.Errorf("failed to link secret files: %w", err)
+	}
+
+	status := parseSharedLinks(output)
+	for _, file := range d.env.SecretFiles {
+		sharedPath := filepath.ToSlash(filepath.Join(sharedBase, file))
+		if status[file] == "present" {
+			d.log.Info("  Linked: %s -> %s", file, sharedPath)
+			continue
+		}
+		d.log.Warn("  SECRET FILE MISSING: %s does not exist on the server. The release links %s to it, but the app will not find it until you create it (e.g. 'versa push-secret %s %s').",
+			sharedPath, file, d.envName, file)
+	}
+
+	return nil
+}
+
+// artifactExclude returns artifact_exclude plus the secret files, which must never
+// be shipped since the release links them from shared/
+func (d *Deployer) artifactExclude() []string {
+	if len(d.env.SecretFiles) == 0 {
+		return d.env.ArtifactExclude
+	}
+	exclude := append([]string{}, d.env.ArtifactExclude...)
+	for _, file := range d.env.SecretFiles {
+		exclude = append(exclude, "/"+file)
+	}
+	return exclude
+}
+
+// chmodSteps returns the shell step applying dir_mode to path, or none when unset
+func (d *Deployer) chmodSteps(path string) []string {
+	mode := d.env.DirFileMode()
+	if mode == 0 {
+		return nil
+	}
+	return []string{d.ownerCmd(fmt.Sprintf("chmod %o %s", mode, ssh.ShellQuote(path)))}
+}
+
+// parseSharedLinks maps shared paths to the link targets reported by handleSharedPaths
+func parseSharedLinks(output string) map[string]string {
+	targets := make(map[string]string)
+	for _, line := range nonEmptyLines(output) {
+		if path, target, ok := strings.Cut(line, "\t"); ok {
+			targets[path] = target
+		}
+	}
+	return targets
+}
+
+// sharedLinkMatches reports whether a symlink target read back from the server is the expected shared path
+func sharedLinkMatches(actual, expected string) bool {
+	return filepath.ToSlash(filepath.Clean(strings.TrimSpace(actual))) == filepath.ToSlash(filepath.Clean(expected))
+}
+
+// sharedFile is a file under a shared path as reported by the remote find
+type sharedFile struct {
+	path  string
+	size  int64
+	mtime float64
+}
+
+// pruneSharedPaths applies shared_cleanup retention policies. Failures are logged, never fatal.
+func (d *Deployer) pruneSharedPaths(sshClient *ssh.Client) {
+	if len(d.env.SharedCleanup) == 0 {
+		return
+	}
+
+	d.log.Info("Pruning shared paths...")
+	sharedBase := filepath.ToSlash(filepath.Join(d.env.RemotePath, "shared"))
+
+	for _, sc := range d.env.SharedCleanup {
+		target := filepath.ToSlash(filepath.Join(sharedBase, sc.Path))
+		// Security: never prune the shared root itself or anything outside it
+		if !strings.HasPrefix(target, sharedBase+"/") {
+			d.log.Warn("Skipping shared_cleanup path outside shared dir: %s", sc.Path)
+			continue
+		}
+		if exists, _ := sshClient.FileExists(target); !exists {
+			continue
+		}
+
+		if sc.MaxAgeDays > 0 {
+			out, err := sshClient.ExecuteCommand(d.ownerCmd(fmt.Sprintf("find %s -type f -mtime +%d -print -delete", ssh.ShellQuote(target), sc.MaxAgeDays)))
+			if err != nil {
+				d.log.Warn("Failed to prune %s by age: %v", sc.Path, err)
+			} else {
+				removed := nonEmptyLines(out)
+				for _, f := range removed {
+					d.log.Debug("  Removed: %s", f)
+				}
+				d.log.Info("  %s: removed %d file(s) older than %d day(s)", sc.Path, len(removed), sc.MaxAgeDays)
+			}
+		}
+
+		if sc.MaxSizeMB > 0 {
+			// stat -c rather than find -printf, which busybox does not support
+			out, err := sshClient.ExecuteCommand(fmt.Sprintf("find %s -type f -exec stat -c '%%Y %%s %%n' {} +", ssh.ShellQuote(target)))
+			if err != nil {
+				d.log.Warn("Failed to list %s for size pruning: %v", sc.Path, err)
+				continue
+			}
+			victims := selectFilesToPrune(parseSharedFiles(out), int64(sc.MaxSizeMB)*1024*1024)
+			removed := 0
+			for start := 0; start < len(victims); start += 100 {
+				end := start + 100
+				if end > len(victims) {
+					end = len(victims)
+				}
+				// File names come from user uploads, so they must never reach the shell unquoted
+				quoted := make([]string, 0, end-start)
+				for _, f := range victims[start:end] {
+					quoted = append(quoted, ssh.ShellQuote(f.path))
+				}
+				if _, err := sshClient.ExecuteCommand(d.ownerCmd("rm -f -- " + strings.Join(quoted, " "))); err != nil {
+					d.log.Warn("Failed to prune %s by size: %v", sc.Path, err)
+					break
+				}
+				for _, f := range victims[start:end] {
+					d.log.Debug("  Removed: %s", f.path)
+				}
+				removed = end
+			}
+			d.log.Info("  %s: removed %d file(s) to stay under %d MB", sc.Path, removed, sc.MaxSizeMB)
+		}
+	}
+}
+
+// parseSharedFiles parses "<mtime> <size> <path>" lines produced by stat -c
+func parseSharedFiles(out string) []sharedFile {
+	var files []sharedFile
+	for _, line := range nonEmptyLines(out) {
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		mtime, err1 := strconv.ParseFloat(parts[0], 64)
+		size, err2 := strconv.ParseInt(parts[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		files = append(files, sharedFile{path: parts[2], size: size, mtime: mtime})
+	}
+	return files
+}
+
+// selectFilesToPrune returns the oldest files whose removal brings the total size under maxBytes
+func selectFilesToPrune(files []sharedFile, maxBytes int64) []sharedFile {
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	if total <= maxBytes {
+		return nil
+	}
+
+	sorted := append([]sharedFile(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].mtime < sorted[j].mtime })
+
+	var victims []sharedFile
+	for _, f := range sorted {
+		if total <= maxBytes {
+			break
+		}
+		victims = append(victims, f)
+		total -= f.size
+	}
+	return victims
+}
+
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// configureCleanup sets how the client removes old releases: through sudo when owner
+// has handed their runtime dirs to another user, and no more at once than --concurrency
+func (d *Deployer) configureCleanup(sshClient *ssh.Client) {
+	sshClient.SetSudoRemove(d.env.Owner != "")
+	sshClient.SetPruneWorkers(d.env.Concurrency)
+}
+
+// ensureRemotePath creates remote_path (and its parents) if it does not exist yet and
+// reports whether it exists now. A dry run only says it would create it.
+func (d *Deployer) ensureRemotePath(sshClient *ssh.Client) (bool, error) {
+	if d.dryRun {
+		exists, err := sshClient.FileExists(d.env.RemotePath)
+		if err != nil {
+			return false, fmt.Errorf("failed to check remote_path %s: %w", d.env.RemotePath, err)
+		}
+		if !exists {
+			d.log.Info("DRY RUN - would create remote_path %s", d.env.RemotePath)
+		}
+		return exists, nil
+	}
+	if err := sshClient.MkdirAll(d.env.RemotePath); err != nil {
+		return false, verserrors.New(verserrors.CodeDeploymentFailed,
+			fmt.Sprintf("Failed to create remote_path %s", d.env.RemotePath),
+			"Check that the SSH user can create this directory (or create it manually with the right owner)",
+			err)
+	}
+	return true, nil
+}
+
+// applyDirMode chmods remote directories to dir_mode so permissions don't depend on the server umask
+func (d *Deployer) applyDirMode(sshClient *ssh.Client, paths ...string) error {
+	mode := d.env.DirFileMode()
+	if mode == 0 {
+		return nil
+	}
+	for _, p := range paths {
+		if err := sshClient.Chmod(p, mode); err != nil {
+			return fmt.Errorf("failed to set mode %o on %s: %w", mode, p, err)
+		}
+	}
+	return nil
+}
+
+// chownCmd builds the command applying owner to paths recursively: plain chown first
+// (when deploying as root), then passwordless sudo
+func chownCmd(owner string, paths ...string) string {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = ssh.ShellQuote(p)
+	}
+	return ssh.SudoFallback(fmt.Sprintf("chown -R %s %s", ssh.ShellQuote(owner), strings.Join(quoted, " ")))
+}
+
+// ownedPaths lists the runtime dirs owner applies to: the release's ensure_dirs and
+// the shared_paths targets. The rest of the release stays with the deploy user, so
+// later steps and deploys can still write into it (deploy.lock snapshot, dependency
+// reuse, in-place updates)
+func (d *Deployer) ownedPaths(releaseDir string) []string {
+	var paths []string
+	for _, dir := range d.env.EnsureDirs {
+		paths = append(paths, filepath.ToSlash(filepath.Join(releaseDir, "app", dir)))
+	}
+	sharedBase := filepath.ToSlash(filepath.Join(d.env.RemotePath, "shared"))
+	for _, path := range d.env.SharedPaths {
+		cleanPath := filepath.ToSlash(filepath.Clean(path))
+		if strings.HasPrefix(cleanPath, "../") || cleanPath == ".." {
+			continue // handleSharedPaths never links these
+		}
+		paths = append(paths, filepath.ToSlash(filepath.Join(sharedBase, cleanPath)))
+	}
+	return paths
+}
+
+// applyOwner chowns the release's runtime dirs to the environment's owner. Lacking
+// the privilege is not fatal: the dirs keep the deploy user's ownership and a
+// warning says so.
+func (d *Deployer) applyOwner(sshClient *ssh.Client, releaseDir string) {
+	if d.env.Owner == "" {
+		return
+	}
+	paths := d.ownedPaths(releaseDir)
+	if len(paths) == 0 {
+		d.log.Warn("owner is set but there are no shared_paths or ensure_dirs to chown to %s", d.env.Owner)
+		return
+	}
+	d.log.Info("Setting owner %s on runtime directories...", d.env.Owner)
+	if output, err := sshClient.ExecuteCommand(chownCmd(d.env.Owner, paths...)); err != nil {
+		d.log.Warn("Could not chown the runtime directories to %s (needs root or passwordless sudo for chown); they stay owned by %s: %s",
+			d.env.Owner, d.env.SSH.User, strings.TrimSpace(output))
+	}
+}
+
+// ownerCmd wraps a removal under the runtime dirs in the sudo fallback when owner
+// hands them to another user, whose files the deploy user can't delete
+func (d *Deployer) ownerCmd(cmd string) string {
+	if d.env.Owner == "" {
+		return cmd
+	}
+	return ssh.SudoFallback(cmd)
 }
 
-// validateLocalTools checks if necessary build tools are available on the system
-func (d *Deployer) validateLocalTools() error {
-	var g errgroup.Group
+// verifySampleSize is how many files verify_files: sample checks after extraction
+const verifySampleSize = 50
 
-	// Check PHP tools
-	if d.env.Builds.PHP.Enabled {
-		g.Go(func() error {
-			cmd := "composer"
-			if d.env.Builds.PHP.ComposerCommand != "" {
-				parts := strings.Fields(d.env.Builds.PHP.ComposerCommand)
-				if len(parts) > 0 {
-					cmd = parts[0]
-				}
-			}
-			if _, err := exec.LookPath(cmd); err != nil {
-				return verserrors.New(verserrors.CodeBuildFailed,
-					fmt.Sprintf("PHP build tool '%s' not found", cmd),
-					fmt.Sprintf("Install %s or ensure it is in your PATH.", cmd), nil)
-			}
-			return nil
-		})
-	}
+// verifyBatchSize caps the number of paths passed to a single remote sha256sum call
+const verifyBatchSize = 200
 
-	// Check Go tools
-	if d.env.Builds.Go.Enabled {
-		g.Go(func() error {
-			if _, err := exec.LookPath("go"); err != nil {
-				return verserrors.New(verserrors.CodeBuildFailed,
-					"Go compiler not found",
-					"Install Go (https://golang.org/dl/) and ensure it is in your PATH.", nil)
-			}
-			return nil
-		})
+// verifyExtractedFiles compares the hashes of extracted files on the remote against the
+// artifact's files.json, catching truncated or corrupt transfers before the release goes live
+func (d *Deployer) verifyExtractedFiles(sshClient *ssh.Client, artifactDir, remoteDir string) error {
+	if d.env.VerifyFiles == "" {
+		return nil
 	}
 
-	// Check Frontend tools
-	if d.env.Builds.Frontend.Enabled {
-		g.Go(func() error {
-			tools := []string{}
-			if d.env.Builds.Frontend.NPMCommand != "" {
-				parts := strings.Fields(d.env.Builds.Frontend.NPMCommand)
-				if len(parts) > 0 {
-					tools = append(tools, parts[0])
-				}
-			}
-			if d.env.Builds.Frontend.CompileCommand != "" {
-				parts := strings.Fields(d.env.Builds.Frontend.CompileCommand)
-				if len(parts) > 0 {
-					cmd := parts[0]
-					if !strings.HasPrefix(cmd, "./") && !strings.HasPrefix(cmd, ".\\") {
-						tools = append(tools, cmd)
-					}
-				}
-			}
+	files, err := artifact.ReadFileInventory(artifactDir)
+	if errors.Is(err, os.ErrNotExist) {
+		// Promoted releases deployed without verify_files carry no files.json
+		d.log.Warn("No file inventory in the artifact, skipping file verification")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	paths := selectFilesToVerify(files, d.env.VerifyFiles == "all", verifySampleSize)
+	if len(paths) == 0 {
+		return nil
+	}
 
-			for _, tool := range tools {
-				if _, err := exec.LookPath(tool); err != nil {
-					return verserrors.New(verserrors.CodeBuildFailed,
-						fmt.Sprintf("Frontend build tool '%s' not found", tool),
-						fmt.Sprintf("Install %s (npm, pnpm, yarn, etc.) and ensure it is in your PATH.", tool), nil)
-				}
+	d.log.Info("Verifying %d of %d extracted files...", len(paths), len(files))
+	var mismatched []string
+	for start := 0; start < len(paths); start += verifyBatchSize {
+		batch := paths[start:min(start+verifyBatchSize, len(paths))]
+		quoted := make([]string, len(batch))
+		for i, p := range batch {
+			quoted[i] = ssh.ShellQuote(p)
+		}
+		cmd := fmt.Sprintf("cd %s && sha256sum -- %s", ssh.ShellQuote(remoteDir), strings.Join(quoted, " "))
+		// sha256sum exits non-zero when a file is missing; parse what it printed anyway
+		output, _ := sshClient.ExecuteCommand(cmd)
+		remote := parseSha256sum(output)
+		for _, p := range batch {
+			if remote[p] != strings.TrimPrefix(files[p], "sha256:") {
+				mismatched = append(mismatched, p)
 			}
-			return nil
-		})
+		}
 	}
 
-	return g.Wait()
+	if len(mismatched) > 0 {
+		shown := mismatched
+		if len(shown) > 5 {
+			shown = shown[:5]
+		}
+		return verserrors.New(verserrors.CodeUploadFailed,
+			fmt.Sprintf("%d extracted file(s) do not match the artifact: %s", len(mismatched), strings.Join(shown, ", ")),
+			"The transfer was probably truncated or corrupted; re-run the deploy",
+			nil)
+	}
+	d.log.Debug("All verified files match the artifact")
+	return nil
 }
 
-// handleSharedPaths manages symbolic links for persistent directories
-func (d *Deployer) handleSharedPaths(sshClient *ssh.Client, releaseDir string) error {
-	if len(d.env.SharedPaths) == 0 {
-		return nil
+// selectFilesToVerify returns the sorted inventory paths to check: all of them, or a
+// random sample of at most sampleSize
+func selectFilesToVerify(files map[string]string, all bool, sampleSize int) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	if !all && len(paths) > sampleSize {
+		rand.Shuffle(len(paths), func(i, j int) { paths[i], paths[j] = paths[j], paths[i] })
+		paths = paths[:sampleSize]
 	}
+	sort.Strings(paths)
+	return paths
+}
 
-	d.log.Info("Linking shared directories...")
-	sharedBase := filepath.ToSlash(filepath.Join(d.env.RemotePath, "shared"))
+// parseSha256sum maps paths to hex hashes from "<hash>  <path>" sha256sum output lines
+func parseSha256sum(output string) map[string]string {
+	hashes := make(map[string]string)
+	for _, line := range nonEmptyLines(output) {
+		hash, p, ok := strings.Cut(line, "  ")
+		if !ok {
+			continue
+		}
+		hashes[strings.TrimPrefix(p, "*")] = hash
+	}
+	return hashes
+}
 
-	// Ensure shared directory exists via SFTP
-	sshClient.MkdirAll(sharedBase)
+// ensureDirs creates the configured ensure_dirs inside the release's app directory.
+// Runs after shared paths are linked so dirs nested under a shared path land in shared.
+func (d *Deployer) ensureDirs(sshClient *ssh.Client, releaseDir string) error {
+	if len(d.env.EnsureDirs) == 0 {
+		return nil
+	}
 
-	for _, path := range d.env.SharedPaths {
-		// Clean the path to avoid directory traversal or trailing slashes
-		cleanPath := filepath.ToSlash(filepath.Clean(path))
-		if strings.HasPrefix(cleanPath, "../") || cleanPath == ".." {
-			continue // Security: don't allow escaping release dir
+	d.log.Info("Ensuring runtime directories...")
+	for _, dir := range d.env.EnsureDirs {
+		remoteDir := filepath.ToSlash(filepath.Join(releaseDir, "app", dir))
+		if err := sshClient.MkdirAll(remoteDir); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+		if err := d.applyDirMode(sshClient, remoteDir); err != nil {
+			return err
 		}
+		d.log.Debug("  Ensured: %s", dir)
+	}
 
-		// Path in release (now inside 'app' subfolder)
-		releasePath := filepath.ToSlash(filepath.Join(releaseDir, "app", cleanPath))
-		// Path in shared (e.g. shared/app/storage)
-		sharedPath := filepath.ToSlash(filepath.Join(sharedBase, cleanPath))
+	return nil
+}
 
-		// 1. Ensure shared target exists via SFTP
-		sshClient.MkdirAll(sharedPath)
+// linkOrCopy hardlinks src to dst with cp -al, or copies it with cp -a when
+// hardlink is false. When hardlinking fails it removes the partial tree and falls
+// back to a full cp -a.
+func (d *Deployer) linkOrCopy(sshClient *ssh.Client, src, dst string, hardlink bool) error {
+	if !hardlink {
+		_, err := sshClient.ExecuteCommand(fmt.Sprintf("cp -a -- %q %q", src, dst))
+		return err
+	}
+	if _, err := sshClient.ExecuteCommand(fmt.Sprintf("cp -al -- %q %q", src, dst)); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "cross-device") {
+			d.log.Warn("Hardlinking %s failed: previous release is on another filesystem, copying instead", src)
+		} else {
+			d.log.Warn("Hardlinking %s failed, copying instead: %v", src, err)
+		}
+		if _, err := sshClient.ExecuteCommand(fmt.Sprintf("rm -rf -- %q && cp -a -- %q %q", dst, src, dst)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		// 2. Remove directory in release if it exists to make room for symlink
-		sshClient.ExecuteCommand(fmt.Sprintf("rm -rf -- %q", releasePath))
+// sameFilesystem reports whether two remote paths are on the same device, which
+// hardlinks require. When stat is unavailable it assumes they are, so cp -al is
+// still tried and linkOrCopy's fallback covers a failure.
+func sameFilesystem(sshClient *ssh.Client, a, b string) bool {
+	out, err := sshClient.ExecuteCommand(fmt.Sprintf("stat -c %%d -- %q %q", a, b))
+	if err != nil {
+		return true
+	}
+	return parseSameDevice(out)
+}
 
-		// 3. Create parent directory in release if needed via SFTP
-		sshClient.MkdirAll(filepath.Dir(releasePath))
+// parseSameDevice reads the two device numbers printed by stat -c %d
+func parseSameDevice(out string) bool {
+	fields := strings.Fields(out)
+	return len(fields) != 2 || fields[0] == fields[1]
+}
 
-		// 4. Create symlink (use absolute path for shared target to be safe)
-		// We use ln -sf directly for shared paths as they don't need the atomic switch logic of 'current'
-		cmd := fmt.Sprintf("ln -sfn %q %q", sharedPath, releasePath)
-		if _, err := sshClient.ExecuteCommand(cmd); err != nil {
-			return fmt.Errorf("failed to link shared path %s: %w", cleanPath, err)
-		}
-		d.log.Info("  Linked: %s -> %s", cleanPath, sharedPath)
+// reuseFailed decides what a failed dependency reuse means: with strict_reuse the
+// deploy aborts, otherwise it continues with a warning since the release may be
+// missing the path.
+func (d *Deployer) reuseFailed(err error) error {
+	if d.env.StrictReuse {
+		return err
 	}
-
+	d.log.Warn("%v (continuing; the release may be missing it, set strict_reuse to fail instead)", err)
 	return nil
 }
 
 // reuseDependencies attempts to recover vendor/node_modules and other build assets from previous release using hardlinks
+// (or copies when the previous release is on another filesystem)
 func (d *Deployer) reuseDependencies(sshClient *ssh.Client, previousVersion, finalDir string, cs *changeset.ChangeSet) error {
 	if previousVersion == "" {
+		d.log.Debug("Dependency reuse skipped: previous release unknown")
 		return nil
 	}
 
+	// Hardlinks can't cross filesystems (e.g. releases on separate mounts, some
+	// overlay/NFS setups); copy instead of failing every cp -al
+	previousDir := filepath.ToSlash(filepath.Join(d.env.RemotePath, "releases", previousVersion))
+	hardlink := sameFilesystem(sshClient, previousDir, finalDir)
+	if !hardlink {
+		d.log.Warn("Previous release %s is on a different filesystem; copying reused dependencies instead of hardlinking (slower, uses more disk)", previousVersion)
+	}
+
 	// Internal helper to reuse a specific path
 	reusePath := func(projectRoot, relPath string) error {
 		oldPath := filepath.ToSlash(filepath.Join(d.env.RemotePath, "releases", previousVersion, "app", projectRoot, relPath))
@@ -1195,20 +2972,24 @@ func (d *Deployer) reuseDependencies(sshClient *ssh.Client, previousVersion, fin
 			sourceToUse = oldPathLegacy
 		}
 
-		if sourceToUse != "" {
-			// Check if already exists in new artifact
-			if exists, _ := sshClient.FileExists(newPath); !exists {
-				if err := sshClient.MkdirAll(filepath.Dir(newPath)); err != nil {
-					return fmt.Errorf("failed to create directory for reusable path %s: %w", relPath, err)
-				}
-				cmd := fmt.Sprintf("cp -al -- %q %q", sourceToUse, newPath)
-				if _, err := sshClient.ExecuteCommand(cmd); err != nil {
-					return fmt.Errorf("failed to reuse path %s from previous release: %w", relPath, err)
-				}
-				d.log.Info("  Reused: %s", newPath)
-			}
+		if sourceToUse == "" {
+			d.log.Info("  Not reused: %s (not found in previous release %s)", relPath, previousVersion)
+			return nil
+		}
+
+		// Check if already exists in new artifact
+		if exists, _ := sshClient.FileExists(newPath); exists {
+			d.log.Debug("  Not reused: %s (already present in new release)", relPath)
+			return nil
 		}
 
+		if err := sshClient.MkdirAll(filepath.Dir(newPath)); err != nil {
+			return d.reuseFailed(fmt.Errorf("failed to create directory for reusable path %s: %w", relPath, err))
+		}
+		if err := d.linkOrCopy(sshClient, sourceToUse, newPath, hardlink); err != nil {
+			return d.reuseFailed(fmt.Errorf("failed to reuse path %s from previous release: %w", relPath, err))
+		}
+		d.log.Info("  Reused: %s (from %s)", newPath, sourceToUse)
 		return nil
 	}
 
@@ -1223,23 +3004,24 @@ func (d *Deployer) reuseDependencies(sshClient *ssh.Client, previousVersion, fin
 		}
 
 		if sourceToUse == "" {
+			d.log.Info("  Not reused: %s (not found in previous release %s)", relPath, previousVersion)
 			return nil
 		}
 
 		if exists, _ := sshClient.FileExists(newPath); exists {
+			d.log.Debug("  Not reused: %s (already present in new release)", relPath)
 			return nil
 		}
 
 		if err := sshClient.MkdirAll(filepath.Dir(newPath)); err != nil {
-			return fmt.Errorf("failed to create directory for reusable release path %s: %w", relPath, err)
+			return d.reuseFailed(fmt.Errorf("failed to create directory for reusable release path %s: %w", relPath, err))
 		}
 
-		cmd := fmt.Sprintf("cp -al -- %q %q", sourceToUse, newPath)
-		if _, err := sshClient.ExecuteCommand(cmd); err != nil {
-			return fmt.Errorf("failed to reuse release path %s from previous release: %w", relPath, err)
+		if err := d.linkOrCopy(sshClient, sourceToUse, newPath, hardlink); err != nil {
+			return d.reuseFailed(fmt.Errorf("failed to reuse release path %s from previous release: %w", relPath, err))
 		}
 
-		d.log.Info("  Reused: %s", newPath)
+		d.log.Info("  Reused: %s (from %s)", newPath, sourceToUse)
 		return nil
 	}
 
@@ -1263,6 +3045,8 @@ func (d *Deployer) reuseDependencies(sshClient *ssh.Client, previousVersion, fin
 				return err
 			}
 		}
+	} else if d.env.Builds.PHP.Enabled {
+		d.log.Debug("PHP dependency reuse skipped: composer.json/composer.lock changed")
 	}
 
 	// Frontend
@@ -1285,6 +3069,8 @@ func (d *Deployer) reuseDependencies(sshClient *ssh.Client, previousVersion, fin
 				return err
 			}
 		}
+	} else if d.env.Builds.Frontend.Enabled {
+		d.log.Debug("Frontend dependency reuse skipped: package.json/lock file changed")
 	}
 
 	// Go
@@ -1293,6 +3079,8 @@ func (d *Deployer) reuseDependencies(sshClient *ssh.Client, previousVersion, fin
 		if err := reuseReleasePath(goBinary); err != nil {
 			return err
 		}
+	} else if d.env.Builds.Go.Enabled {
+		d.log.Debug("Go binary reuse skipped: go.mod/go.sum or Go sources changed")
 	}
 
 	// Python
@@ -1325,6 +3113,8 @@ func (d *Deployer) reuseDependencies(sshClient *ssh.Client, previousVersion, fin
 				return err
 			}
 		}
+	} else if d.env.Builds.Python.Enabled {
+		d.log.Debug("Python dependency reuse skipped: requirements changed")
 	}
 
 	return nil
@@ -1463,7 +3253,7 @@ func (d *Deployer) handlePreservedPaths(sshClient *ssh.Client, previousVersion,
 
 // ReloadServices connects to the remote server and re-executes all services_reload commands.
 func (d *Deployer) ReloadServices() error {
-	if len(d.env.ServicesReload) == 0 {
+	if len(d.env.ServicesReload) == 0 && len(d.env.Services) == 0 {
 		d.log.Info("No services_reload commands configured")
 		return nil
 	}
@@ -1475,6 +3265,9 @@ func (d *Deployer) ReloadServices() error {
 	defer sshClient.Close()
 
 	d.executeServicesReload(sshClient)
+	if err := d.restartSystemdServices(sshClient); err != nil {
+		return err
+	}
 	d.log.Success("Services reloaded!")
 	return nil
 }
@@ -1507,6 +3300,85 @@ func (d *Deployer) executeServicesReload(sshClient *ssh.Client) {
 	}
 }
 
+// restartSystemdServices applies services_action to every unit in services and verifies each one
+// reaches the active state. Unlike services_reload, a failure here is fatal.
+func (d *Deployer) restartSystemdServices(sshClient *ssh.Client) error {
+	if len(d.env.Services) == 0 {
+		return nil
+	}
+
+	action := d.env.ServicesAction
+	if action == "" {
+		action = "reload-or-restart"
+	}
+
+	d.log.Info("Restarting systemd services (%s)...", action)
+	for _, unit := range d.env.Services {
+		cmd := d.systemctl(fmt.Sprintf("%s %s", action, unit))
+		d.log.Info("  Executing: %s", cmd)
+		if output, err := sshClient.ExecuteCommandWithTimeout(cmd, 60*time.Second); err != nil {
+			return fmt.Errorf("systemctl %s %s failed: %w (output: %s)", action, unit, err, strings.TrimSpace(output))
+		}
+		if err := d.waitServiceActive(sshClient, unit); err != nil {
+			return err
+		}
+		d.log.Info("  ✓ %s is active", unit)
+	}
+	return nil
+}
+
+// waitServiceActive polls 'systemctl is-active' briefly, since units may still be activating
+func (d *Deployer) waitServiceActive(sshClient *ssh.Client, unit string) error {
+	const attempts = 5
+	var current string
+	for attempt := 1; attempt <= attempts; attempt++ {
+		output, _ := sshClient.ExecuteCommand(fmt.Sprintf("systemctl is-active %s", unit))
+		current = strings.TrimSpace(output)
+		if current == "active" {
+			return nil
+		}
+		if attempt < attempts {
+			time.Sleep(time.Second)
+		}
+	}
+
+	status, _ := sshClient.ExecuteCommand(d.systemctl(fmt.Sprintf("status --no-pager -n 10 %s", unit)))
+	return fmt.Errorf("service %s is %q, expected active\n%s", unit, current, strings.TrimSpace(status))
+}
+
+// systemctl prefixes a systemctl invocation with non-interactive sudo unless connected as root
+func (d *Deployer) systemctl(args string) string {
+	if d.env.SSH.User == "root" {
+		return "systemctl " + args
+	}
+	return "sudo -n systemctl " + args
+}
+
+// rollbackAfterServiceFailure restores the previous release when services fail to come back up
+func (d *Deployer) rollbackAfterServiceFailure(sshClient *ssh.Client, previousLock *state.DeployLock, serviceErr error) error {
+	d.log.Error("Service restart failed: %v", serviceErr)
+	return d.rollbackAfterFailure(sshClient, previousLock, "service restart", serviceErr)
+}
+
+// rollbackAfterFailure switches back to the previous release after a post-switch check
+// (what) failed with cause, then reloads and restarts services so they pick it up again.
+// The returned error always wraps cause.
+func (d *Deployer) rollbackAfterFailure(sshClient *ssh.Client, previousLock *state.DeployLock, what string, cause error) error {
+	if previousLock == nil {
+		return fmt.Errorf("%s failed (no previous version for rollback): %w", what, cause)
+	}
+
+	d.log.Info("Rolling back due to %s failure...", what)
+	if err := d.rollback(sshClient, previousLock); err != nil {
+		return fmt.Errorf("%s failed and rollback also failed: %w (%s: %v)", what, err, what, cause)
+	}
+	d.executeServicesReload(sshClient)
+	if err := d.restartSystemdServices(sshClient); err != nil {
+		d.log.Warn("Services still failing after rollback: %v", err)
+	}
+	return fmt.Errorf("%s failed (rolled back to %s): %w", what, previousLock.LastDeploy.ReleaseDir, cause)
+}
+
 // performHealthCheck verifies the application is working after deployment.
 // If the health check fails after all retries, it rolls back to the previous release.
 func (d *Deployer) performHealthCheck(previousLock *state.DeployLock, sshClient *ssh.Client) error {
@@ -1560,19 +3432,7 @@ func (d *Deployer) performHealthCheck(previousLock *state.DeployLock, sshClient
 	}
 
 	d.log.Error("Health check failed after %d attempts", retries)
-
-	// Rollback on health check failure
-	if previousLock != nil {
-		d.log.Info("Rolling back due to health check failure...")
-		if err := d.rollback(sshClient, previousLock); err != nil {
-			return fmt.Errorf("health check failed and rollback also failed: %w (health: %v)", err, lastErr)
-		}
-		// Re-reload services after rollback
-		d.executeServicesReload(sshClient)
-		return fmt.Errorf("health check failed (rolled back to %s): %w", previousLock.LastDeploy.ReleaseDir, lastErr)
-	}
-
-	return fmt.Errorf("health check failed (no previous version for rollback): %w", lastErr)
+	return d.rollbackAfterFailure(sshClient, previousLock, "health check", lastErr)
 }
 
 // sendNotification sends a webhook notification about the deployment result.
@@ -1645,6 +3505,11 @@ func (d *Deployer) RollbackTo(targetVersion string) error {
 	if err != nil {
 		return err
 	}
+	state.SortReleases(releases)
+	if resolved := resolveRollbackTarget(releases, targetVersion); resolved != targetVersion {
+		d.log.Info("Resolved %s release: %s", targetVersion, resolved)
+		targetVersion = resolved
+	}
 
 	found := false
 	for _, r := range releases {
@@ -1656,6 +3521,12 @@ func (d *Deployer) RollbackTo(targetVersion string) error {
 	if !found {
 		return fmt.Errorf("release %s not found on server (available: %s)", targetVersion, strings.Join(releases, ", "))
 	}
+	d.warnRollbackDepth(sshClient, releases, targetVersion)
+
+	if d.dryRun {
+		d.log.Info("DRY RUN - would switch current -> releases/%s", targetVersion)
+		return nil
+	}
 
 	// Switch symlink
 	currentSymlink := filepath.ToSlash(filepath.Join(d.env.RemotePath, "current"))
@@ -1666,11 +3537,173 @@ func (d *Deployer) RollbackTo(targetVersion string) error {
 
 	// Reload services after rollback
 	d.executeServicesReload(sshClient)
+	if err := d.restartSystemdServices(sshClient); err != nil {
+		d.log.Warn("Service restart after rollback failed: %v", err)
+	}
+	d.promoteReleaseLock(sshClient, targetVersion)
+	d.recordRollback(sshClient, targetVersion)
 
 	d.log.Success("Rollback to %s successful!", targetVersion)
 	return nil
 }
 
+// RunHookByName executes the post_deploy hook with the given name against the
+// currently active release.
+func (d *Deployer) RunHookByName(name string) error {
+	idx, ok := d.env.FindPostDeployHook(name)
+	if !ok {
+		var names []string
+		for _, hook := range d.env.PostDeploy {
+			if hook.Name != "" {
+				names = append(names, hook.Name)
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("hook %q not found: no named post_deploy hooks in environment %s", name, d.envName)
+		}
+		return fmt.Errorf("hook %q not found in environment %s (available: %s)", name, d.envName, strings.Join(names, ", "))
+	}
+	return d.RunHooks([]int{idx})
+}
+
+// Prune removes all but the keep newest releases without deploying. The active
+// release (the target of 'current') is always kept, even when it is older.
+func (d *Deployer) Prune(keep int) error {
+	if keep < 1 {
+		return fmt.Errorf("--keep must be at least 1")
+	}
+
+	sshClient, err := ssh.NewClient(&d.env.SSH, d.log)
+	if err != nil {
+		return verserrors.Wrap(err)
+	}
+	defer sshClient.Close()
+	d.configureCleanup(sshClient)
+
+	currentSymlink := filepath.ToSlash(filepath.Join(d.env.RemotePath, "current"))
+	currentTarget, err := sshClient.ReadSymlink(currentSymlink)
+	if err != nil {
+		return fmt.Errorf("failed to read current symlink (refusing to prune without knowing the active release): %w", err)
+	}
+	active := filepath.Base(currentTarget)
+
+	releasesDir := filepath.ToSlash(filepath.Join(d.env.RemotePath, "releases"))
+	before, dfErr := sshClient.AvailableBytes(releasesDir)
+
+	d.log.Info("Pruning releases on %s (keeping %d, active: %s)...", d.envName, keep, active)
+	removed, pruneErr := sshClient.PruneReleases(releasesDir, keep, active)
+
+	if len(removed) > 0 {
+		if after, err := sshClient.AvailableBytes(releasesDir); dfErr == nil && err == nil && after >= before {
+			d.log.Success("Removed %d release(s), freed %s", len(removed), fsutil.HumanSize(after-before))
+		} else {
+			d.log.Success("Removed %d release(s)", len(removed))
+		}
+	} else if pruneErr == nil {
+		d.log.Success("Nothing to prune")
+	}
+	return pruneErr
+}
+
+// secretRemoteName returns the path under shared/ that a local secret file is
+// pushed to: the relative path as given (".env", "config/app.ini"), or just the
+// file name for absolute paths and paths outside the current directory
+func secretRemoteName(localPath string) string {
+	clean := filepath.ToSlash(filepath.Clean(localPath))
+	if filepath.IsAbs(localPath) || strings.HasPrefix(clean, "/") || clean == ".." || strings.HasPrefix(clean, "../") {
+		return filepath.Base(localPath)
+	}
+	return clean
+}
+
+// PushSecret uploads a local file into the shared/ directory with 0600 permissions
+// so secret_files can link it into releases. An existing shared file is only
+// replaced with force, to avoid clobbering production configuration.
+func (d *Deployer) PushSecret(localPath string, force bool) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to read secret file: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory; push-secret uploads single files", localPath)
+	}
+
+	name := secretRemoteName(localPath)
+	isSecret := false
+	for _, f := range d.env.SecretFiles {
+		isSecret = isSecret || f == name
+	}
+	if !isSecret {
+		d.log.Warn("%s is not listed in secret_files for %s; releases won't link it until it is", name, d.envName)
+	}
+
+	sshClient, err := ssh.NewClient(&d.env.SSH, d.log)
+	if err != nil {
+		return verserrors.Wrap(err)
+	}
+	defer sshClient.Close()
+
+	remotePath := filepath.ToSlash(filepath.Join(d.env.RemotePath, "shared", name))
+	exists, err := sshClient.FileExists(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to check %s: %w", remotePath, err)
+	}
+	if exists && !force {
+		return fmt.Errorf("%s already exists on %s; use --force to overwrite it", remotePath, d.envName)
+	}
+
+	if err := sshClient.MkdirAll(filepath.ToSlash(filepath.Dir(remotePath))); err != nil {
+		return fmt.Errorf("failed to create shared directory: %w", err)
+	}
+	if err := sshClient.UploadFileMode(localPath, remotePath, 0600); err != nil {
+		return err
+	}
+
+	if exists {
+		d.log.Success("Replaced %s (0600)", remotePath)
+	} else {
+		d.log.Success("Uploaded %s (0600)", remotePath)
+	}
+	return nil
+}
+
+// lockPath is the remote directory that serializes deploys to this environment
+func (d *Deployer) lockPath() string {
+	return filepath.ToSlash(filepath.Join(d.env.RemotePath, ".versa.lock"))
+}
+
+// Unlock removes a deployment lock left behind by an interrupted deploy. The holder is
+// logged first and the lock is only removed when confirm returns true (a nil confirm
+// removes it without asking).
+func (d *Deployer) Unlock(confirm func() bool) error {
+	sshClient, err := ssh.NewClient(&d.env.SSH, d.log)
+	if err != nil {
+		return verserrors.Wrap(err)
+	}
+	defer sshClient.Close()
+
+	lockDirPath := d.lockPath()
+	holder, held, err := sshClient.ReadLockHolder(lockDirPath)
+	if err != nil {
+		return err
+	}
+	if !held {
+		d.log.Success("No deployment lock held on %s", d.envName)
+		return nil
+	}
+
+	d.log.Warn("Deployment lock on %s held by %s", d.envName, holder.Describe(time.Now()))
+	if confirm != nil && !confirm() {
+		return fmt.Errorf("unlock cancelled; the lock on %s was left in place", d.envName)
+	}
+
+	if err := sshClient.ReleaseLock(lockDirPath); err != nil {
+		return fmt.Errorf("failed to remove deployment lock %s: %w", lockDirPath, err)
+	}
+	d.log.Success("Removed deployment lock on %s", d.envName)
+	return nil
+}
+
 // RunHooks executes specific hooks against the currently active release.
 // If indices is nil or empty, all post_deploy hooks are executed.
 func (d *Deployer) RunHooks(indices []int) error {
@@ -1724,8 +3757,8 @@ func (d *Deployer) RunHooks(indices []int) error {
 
 	for _, hookConfig := range hooks {
 		if hookConfig.Command != "" {
-			appPath := filepath.ToSlash(filepath.Join(finalDir, "app"))
-			wrappedHook := fmt.Sprintf("cd %s && %s", appPath, hookConfig.Command)
+			appPath := hookWorkDir(finalDir, hookConfig.Dir)
+			wrappedHook := d.wrapRemoteHook(appPath, hookConfig.Command, hookConfig.User)
 			d.log.Info("Executing: %s", hookConfig.Command)
 			output, err := sshClient.ExecuteCommandWithTimeout(wrappedHook, hookTimeout)
 			if err != nil {
@@ -1740,12 +3773,13 @@ func (d *Deployer) RunHooks(indices []int) error {
 			}
 		} else if len(hookConfig.Parallel) > 0 {
 			var g errgroup.Group
+			d.limitGroup(&g)
 			d.log.Info("Executing parallel hook group (%d commands)...", len(hookConfig.Parallel))
 			for _, h := range hookConfig.Parallel {
-				cmd := h
-				appPath := filepath.ToSlash(filepath.Join(finalDir, "app"))
+				cmd, user := h, hookConfig.User
+				appPath := hookWorkDir(finalDir, hookConfig.Dir)
 				g.Go(func() error {
-					wrappedHook := fmt.Sprintf("cd %s && %s", appPath, cmd)
+					wrappedHook := d.wrapRemoteHook(appPath, cmd, user)
 					d.log.Info("Executing: %s", cmd)
 					output, hookErr := sshClient.ExecuteCommandWithTimeout(wrappedHook, hookTimeout)
 					if hookErr != nil {
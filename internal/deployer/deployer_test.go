@@ -2,16 +2,25 @@ package deployer
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/user/versaDeploy/internal/builder"
+	"github.com/user/versaDeploy/internal/changeset"
 	"github.com/user/versaDeploy/internal/config"
 	"github.com/user/versaDeploy/internal/logger"
+	"github.com/user/versaDeploy/internal/ssh"
+	"github.com/user/versaDeploy/internal/ssh/sshtest"
+	"github.com/user/versaDeploy/internal/state"
 )
 
 func TestNewDeployer(t *testing.T) {
@@ -330,6 +339,122 @@ func TestDeployer_PerformHealthCheck_WrongStatus(t *testing.T) {
 	}
 }
 
+func TestWarmURLs(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(404)
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	urls := []string{ts.URL + "/a", ts.URL + "/b", ts.URL + "/missing", ts.URL + "/c", ts.URL + "/slow"}
+	results := warmURLs(urls, 2, 200*time.Millisecond)
+
+	if len(results) != len(urls) {
+		t.Fatalf("got %d results, want %d", len(results), len(urls))
+	}
+	for i, r := range results {
+		if r.URL != urls[i] {
+			t.Errorf("results[%d].URL = %s, want %s", i, r.URL, urls[i])
+		}
+		wantFailed := strings.HasSuffix(r.URL, "/missing") || strings.HasSuffix(r.URL, "/slow")
+		if r.failed() != wantFailed {
+			t.Errorf("%s: failed = %v (status %d, err %v), want %v", r.URL, r.failed(), r.Status, r.Err, wantFailed)
+		}
+	}
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("max concurrent requests = %d, want <= 2", got)
+	}
+}
+
+func TestDeployer_NewDeployLock_Message(t *testing.T) {
+	d := &Deployer{Message: "hotfix for payment bug"}
+	lock := d.newDeployLock("abc123", "20260101-120000", &changeset.ChangeSet{})
+	if lock.LastDeploy.Message != "hotfix for payment bug" {
+		t.Errorf("Message = %q, want the deploy message", lock.LastDeploy.Message)
+	}
+	if lock.LastDeploy.DirtyTree {
+		t.Error("expected a clean deploy not to be flagged dirty")
+	}
+
+	d.dirtyTree = true
+	if lock := d.newDeployLock("abc123", "20260101-120000", &changeset.ChangeSet{}); !lock.LastDeploy.DirtyTree {
+		t.Error("expected an --allow-dirty deploy to be flagged in deploy.lock")
+	}
+}
+
+func TestDeployer_HookRunsHere(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	d := &Deployer{log: log}
+
+	tests := []struct {
+		runOn           string
+		server, primary int
+		want            bool
+	}{
+		{runOn: "", server: 0, primary: 0, want: true},
+		{runOn: "", server: 1, primary: 0, want: false},
+		{runOn: "first", server: 0, primary: 2, want: true},
+		{runOn: "first", server: 2, primary: 2, want: false},
+		{runOn: "primary", server: 2, primary: 2, want: true},
+		{runOn: "primary", server: 0, primary: 2, want: false},
+		{runOn: "all", server: 3, primary: 0, want: true},
+	}
+
+	for _, tt := range tests {
+		d.ServerIndex, d.PrimaryIndex = tt.server, tt.primary
+		if got := d.hookRunsHere(config.HookConfig{Command: "php artisan migrate", RunOn: tt.runOn}); got != tt.want {
+			t.Errorf("run_on %q on server %d (primary %d) = %v, want %v", tt.runOn, tt.server, tt.primary, got, tt.want)
+		}
+	}
+}
+
+func TestDeployer_WarmCaches(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(404)
+		}
+	}))
+	defer ts.Close()
+
+	log, _ := logger.NewLogger("", false, false)
+	newDeployer := func(failOnError bool) *Deployer {
+		cfg := &config.Config{
+			Project: "test",
+			Environments: map[string]config.Environment{
+				"prod": {
+					RemotePath:      "/var/www",
+					WarmURLs:        []string{ts.URL + "/", ts.URL + "/missing"},
+					WarmFailOnError: failOnError,
+				},
+			},
+		}
+		d, _ := NewDeployer(cfg, "prod", ".", false, false, false, false, log)
+		return d
+	}
+
+	if err := newDeployer(false).warmCaches(nil, nil); err != nil {
+		t.Errorf("failed warm URLs should only be reported by default: %v", err)
+	}
+	err := newDeployer(true).warmCaches(nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "/missing") {
+		t.Errorf("expected warm_fail_on_error to fail naming the URL, got %v", err)
+	}
+}
+
 func TestDeployer_PerformHealthCheck_NoURL(t *testing.T) {
 	log, _ := logger.NewLogger("", false, false)
 	cfg := &config.Config{
@@ -386,6 +511,25 @@ func TestDeployer_RunHooks_NoSSH(t *testing.T) {
 	}
 }
 
+func TestDeployer_RunHookByName_Unknown(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	cfg := &config.Config{
+		Project: "test",
+		Environments: map[string]config.Environment{
+			"prod": {
+				RemotePath: "/var/www",
+				PostDeploy: []config.HookConfig{{Name: "migrate", Command: "php artisan migrate"}},
+			},
+		},
+	}
+
+	d, _ := NewDeployer(cfg, "prod", ".", false, false, false, false, log)
+	err := d.RunHookByName("cache:clear")
+	if err == nil || !strings.Contains(err.Error(), "available: migrate") {
+		t.Errorf("expected not-found error listing available hooks, got %v", err)
+	}
+}
+
 func TestDeployer_RollbackTo_NoSSH(t *testing.T) {
 	log, _ := logger.NewLogger("", false, false)
 	cfg := &config.Config{
@@ -431,3 +575,1181 @@ func TestDeployer_ExecRemoteCommand_NoSSH(t *testing.T) {
 		t.Error("ExecRemoteCommand should fail when SSH connection fails")
 	}
 }
+
+func TestParseSharedFiles(t *testing.T) {
+	out := "1700000000 100 /srv/shared/logs/a.log\n\nbad line\n1700000100 200 /srv/shared/logs/with space.log\n1700000200 5 /srv/shared/logs/$(id).log\n"
+	files := parseSharedFiles(out)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %d", len(files))
+	}
+	if files[2].path != "/srv/shared/logs/$(id).log" || files[2].mtime != 1700000200 {
+		t.Errorf("unexpected parse result: %+v", files[2])
+	}
+	if files[1].path != "/srv/shared/logs/with space.log" || files[1].size != 200 {
+		t.Errorf("unexpected parse result: %+v", files[1])
+	}
+}
+
+func TestSelectFilesToPrune(t *testing.T) {
+	files := []sharedFile{
+		{path: "new", size: 40, mtime: 300},
+		{path: "old", size: 50, mtime: 100},
+		{path: "mid", size: 30, mtime: 200},
+	}
+
+	if got := selectFilesToPrune(files, 200); got != nil {
+		t.Fatalf("expected nothing pruned under limit, got %v", got)
+	}
+
+	got := selectFilesToPrune(files, 60)
+	if len(got) != 2 || got[0].path != "old" || got[1].path != "mid" {
+		t.Fatalf("expected oldest files old, mid pruned, got %v", got)
+	}
+}
+
+func TestSelectFilesToVerify(t *testing.T) {
+	files := map[string]string{"app/c": "sha256:3", "app/a": "sha256:1", "app/b": "sha256:2"}
+
+	all := selectFilesToVerify(files, true, 1)
+	if len(all) != 3 || all[0] != "app/a" || all[2] != "app/c" {
+		t.Fatalf("expected all files sorted, got %v", all)
+	}
+
+	sample := selectFilesToVerify(files, false, 2)
+	if len(sample) != 2 {
+		t.Fatalf("expected sample of 2, got %v", sample)
+	}
+	for _, p := range sample {
+		if _, ok := files[p]; !ok {
+			t.Errorf("sampled unknown path %s", p)
+		}
+	}
+}
+
+func TestVerifyExtractedFiles_NoInventory(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	d := &Deployer{env: &config.Environment{VerifyFiles: "all"}, log: log}
+
+	// A promoted release built without verify_files has no files.json to check against
+	if err := d.verifyExtractedFiles(nil, t.TempDir(), "/srv/app/releases/x"); err != nil {
+		t.Errorf("expected missing inventory to skip verification, got %v", err)
+	}
+}
+
+func TestParseSha256sum(t *testing.T) {
+	output := "abc123  app/index.php\ndef456  app/with space.txt\nsha256sum: app/gone: No such file or directory\n"
+	hashes := parseSha256sum(output)
+	if len(hashes) != 2 {
+		t.Fatalf("expected 2 hashes, got %v", hashes)
+	}
+	if hashes["app/index.php"] != "abc123" || hashes["app/with space.txt"] != "def456" {
+		t.Errorf("unexpected parse result: %v", hashes)
+	}
+}
+
+func TestDeployer_SetCommit(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	d := &Deployer{env: &config.Environment{}, log: log}
+	built := "1111111111111111111111111111111111111111"
+
+	if got := d.recordedCommit(built); got != built {
+		t.Errorf("expected built commit without override, got %s", got)
+	}
+
+	for _, bad := range []string{"abc123", "zzzz111111111111111111111111111111111111", "main"} {
+		if err := d.SetCommit(bad); err == nil {
+			t.Errorf("expected error for commit %q", bad)
+		}
+	}
+
+	if err := d.SetCommit("ABCDEF0123456789ABCDEF0123456789ABCDEF01"); err != nil {
+		t.Fatalf("SetCommit() error = %v", err)
+	}
+	if got := d.recordedCommit(built); got != "abcdef0123456789abcdef0123456789abcdef01" {
+		t.Errorf("expected normalized override, got %s", got)
+	}
+}
+
+func TestDeployer_CheckDeployWindow(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	d := &Deployer{
+		envName: "prod",
+		env:     &config.Environment{DeployWindows: config.DeployWindowsConfig{Timezone: "UTC", Allow: []string{"mon-thu 09:00-17:00"}}},
+		log:     log,
+	}
+	friday := time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC)
+
+	if err := d.checkDeployWindow(time.Date(2026, 10, 13, 10, 0, 0, 0, time.UTC)); err != nil || d.windowOverridden {
+		t.Errorf("expected deploy inside window to pass without override, got %v", err)
+	}
+	if err := d.checkDeployWindow(friday); err == nil {
+		t.Error("expected deploy outside window to be refused")
+	}
+
+	d.OverrideWindow = true
+	if err := d.checkDeployWindow(friday); err != nil {
+		t.Errorf("expected --override-window to allow deploy, got %v", err)
+	}
+	if !d.windowOverridden {
+		t.Error("expected override to be recorded")
+	}
+}
+
+func TestDeployer_CheckArtifactSize(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	artifactDir := t.TempDir()
+	os.MkdirAll(filepath.Join(artifactDir, "app", "public"), 0755)
+	os.WriteFile(filepath.Join(artifactDir, "app", "public", "video.mp4"), make([]byte, 2*1024*1024), 0644)
+
+	d := &Deployer{env: &config.Environment{}, log: log}
+	if err := d.checkArtifactSize(artifactDir, 2*1024*1024); err != nil {
+		t.Errorf("expected no check without max_artifact_size_mb, got %v", err)
+	}
+
+	d.env.MaxArtifactSizeMB = 1
+	if err := d.checkArtifactSize(artifactDir, 2*1024*1024); err != nil {
+		t.Errorf("expected only a warning without --strict-size, got %v", err)
+	}
+
+	d.StrictSize = true
+	if err := d.checkArtifactSize(artifactDir, 2*1024*1024); err == nil {
+		t.Error("expected error with --strict-size")
+	}
+	if err := d.checkArtifactSize(artifactDir, 512*1024); err != nil {
+		t.Errorf("expected artifact under the limit to pass, got %v", err)
+	}
+}
+
+func TestParseSharedLinks(t *testing.T) {
+	targets := parseSharedLinks("storage\t/srv/app/shared/storage\npublic/uploads\t/srv/app/shared/public/uploads\nbroken\t\n")
+	if targets["storage"] != "/srv/app/shared/storage" || targets["public/uploads"] != "/srv/app/shared/public/uploads" {
+		t.Errorf("unexpected link targets: %v", targets)
+	}
+	if targets["broken"] != "" {
+		t.Errorf("expected empty target for a path that is not a symlink, got %q", targets["broken"])
+	}
+}
+
+func TestDeployer_ChmodSteps(t *testing.T) {
+	d := &Deployer{env: &config.Environment{}}
+	if steps := d.chmodSteps("/srv/shared"); steps != nil {
+		t.Errorf("expected no chmod without dir_mode, got %v", steps)
+	}
+	d.env.DirMode = "0755"
+	if steps := d.chmodSteps("/srv/shared"); len(steps) != 1 || steps[0] != "chmod 755 '/srv/shared'" {
+		t.Errorf("unexpected chmod steps: %v", steps)
+	}
+}
+
+func TestSharedLinkMatches(t *testing.T) {
+	if !sharedLinkMatches("/srv/app/shared/storage/\n", "/srv/app/shared/storage") {
+		t.Error("expected trailing slash/newline to match")
+	}
+	if sharedLinkMatches("/srv/app/releases/1/app/storage", "/srv/app/shared/storage") {
+		t.Error("expected per-release dir not to match shared path")
+	}
+}
+
+func TestParseRemoteProbe(t *testing.T) {
+	missing, unsupported := parseRemoteProbe("missing:tar\nmissing:readlink\nunsupported:mv -T\nnoise\n")
+	if len(missing) != 2 || missing[0] != "tar" || missing[1] != "readlink" {
+		t.Errorf("unexpected missing tools: %v", missing)
+	}
+	if len(unsupported) != 1 || unsupported[0] != "mv -T" {
+		t.Errorf("unexpected unsupported flags: %v", unsupported)
+	}
+
+	missing, unsupported = parseRemoteProbe("")
+	if missing != nil || unsupported != nil {
+		t.Error("expected nothing reported for a clean probe")
+	}
+}
+
+func TestHookWorkDir(t *testing.T) {
+	tests := map[string]string{
+		"":          "/srv/releases/1/app",
+		".":         "/srv/releases/1",
+		"bin":       "/srv/releases/1/bin",
+		"app/tools": "/srv/releases/1/app/tools",
+	}
+	for dir, want := range tests {
+		if got := hookWorkDir("/srv/releases/1", dir); got != want {
+			t.Errorf("hookWorkDir(%q) = %s, want %s", dir, got, want)
+		}
+	}
+}
+
+func TestDeployer_WrapRemoteHook(t *testing.T) {
+	d := &Deployer{env: &config.Environment{}}
+
+	if got := d.wrapRemoteHook("/srv/app", "php artisan migrate", ""); got != "cd /srv/app && php artisan migrate" {
+		t.Errorf("unexpected plain hook: %s", got)
+	}
+
+	want := `sudo -n -u www-data -- sh -c 'cd /srv/app && echo '"'"'done'"'"''`
+	if got := d.wrapRemoteHook("/srv/app", "echo 'done'", "www-data"); got != want {
+		t.Errorf("unexpected sudo hook:\n got %s\nwant %s", got, want)
+	}
+
+	d.env.HookUser = "deployer"
+	if got := d.wrapRemoteHook("/srv/app", "ls", ""); got != "sudo -n -u deployer -- sh -c 'cd /srv/app && ls'" {
+		t.Errorf("expected hook_user fallback, got %s", got)
+	}
+	if got := d.wrapRemoteHook("/srv/app", "ls", "root"); got != "sudo -n -u root -- sh -c 'cd /srv/app && ls'" {
+		t.Errorf("expected per-hook user to win, got %s", got)
+	}
+}
+
+func TestDeployer_Systemctl(t *testing.T) {
+	d := &Deployer{env: &config.Environment{SSH: config.SSHConfig{User: "deploy"}}}
+	if got := d.systemctl("restart app"); got != "sudo -n systemctl restart app" {
+		t.Errorf("expected sudo for non-root user, got %s", got)
+	}
+
+	d.env.SSH.User = "root"
+	if got := d.systemctl("restart app"); got != "systemctl restart app" {
+		t.Errorf("expected no sudo for root, got %s", got)
+	}
+}
+
+func TestDeployer_RestartSystemdServices_None(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	d := &Deployer{env: &config.Environment{}, log: log}
+	if err := d.restartSystemdServices(nil); err != nil {
+		t.Errorf("expected no-op without services, got %v", err)
+	}
+}
+func TestDeployer_SetRemotePath(t *testing.T) {
+	d := &Deployer{env: &config.Environment{RemotePath: "/var/www/app"}}
+
+	if err := d.SetRemotePath(""); err != nil || d.env.RemotePath != "/var/www/app" {
+		t.Errorf("expected configured path to be kept, got %q (err %v)", d.env.RemotePath, err)
+	}
+	if err := d.SetRemotePath("tmp/test-app"); err == nil {
+		t.Error("expected error for relative path")
+	}
+	if err := d.SetRemotePath("/tmp/test-app/"); err != nil {
+		t.Fatalf("SetRemotePath() error = %v", err)
+	}
+	if d.env.RemotePath != "/tmp/test-app" {
+		t.Errorf("expected override to /tmp/test-app, got %q", d.env.RemotePath)
+	}
+}
+
+func TestDeployer_SetConcurrency(t *testing.T) {
+	d := &Deployer{env: &config.Environment{}}
+	if got := d.uploadStreams(); got != 4 {
+		t.Errorf("expected default 4 upload streams, got %d", got)
+	}
+
+	d.env.Concurrency = 2
+	d.SetConcurrency(0)
+	if got := d.uploadStreams(); got != 2 {
+		t.Errorf("expected configured concurrency to be kept, got %d", got)
+	}
+
+	d.SetConcurrency(1)
+	if got := d.uploadStreams(); got != 1 {
+		t.Errorf("expected override to 1, got %d", got)
+	}
+
+	// A cap, not a target: a high value keeps the default
+	d.SetConcurrency(8)
+	if got := d.uploadStreams(); got != 4 {
+		t.Errorf("expected concurrency 8 to keep 4 upload streams, got %d", got)
+	}
+}
+
+func TestReuseFailed(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	reuseErr := errors.New("cp failed")
+
+	d := &Deployer{env: &config.Environment{}, log: log}
+	if err := d.reuseFailed(reuseErr); err != nil {
+		t.Errorf("expected reuse failure to be non-fatal by default, got %v", err)
+	}
+
+	d.env.StrictReuse = true
+	if err := d.reuseFailed(reuseErr); !errors.Is(err, reuseErr) {
+		t.Errorf("expected strict_reuse to surface the error, got %v", err)
+	}
+}
+
+func TestParseSameDevice(t *testing.T) {
+	tests := []struct {
+		out  string
+		want bool
+	}{
+		{"2049\n2049\n", true},
+		{"2049\n66\n", false},
+		{"", true},
+		{"2049\n", true},
+	}
+	for _, tt := range tests {
+		if got := parseSameDevice(tt.out); got != tt.want {
+			t.Errorf("parseSameDevice(%q) = %v, want %v", tt.out, got, tt.want)
+		}
+	}
+}
+
+func TestArtifactExclude(t *testing.T) {
+	d := &Deployer{env: &config.Environment{ArtifactExclude: []string{"*.map"}}}
+	if got := d.artifactExclude(); strings.Join(got, ",") != "*.map" {
+		t.Errorf("expected artifact_exclude unchanged, got %v", got)
+	}
+
+	d.env.SecretFiles = []string{".env", "config/secrets.ini"}
+	if got := d.artifactExclude(); strings.Join(got, ",") != "*.map,/.env,/config/secrets.ini" {
+		t.Errorf("expected secret files anchored in the exclude list, got %v", got)
+	}
+	if len(d.env.ArtifactExclude) != 1 {
+		t.Errorf("artifact_exclude must not be modified, got %v", d.env.ArtifactExclude)
+	}
+}
+
+func TestSecretRemoteName(t *testing.T) {
+	tests := map[string]string{
+		".env":                                 ".env",
+		"./config/app.ini":                     "config/app.ini",
+		"../other/.env":                        ".env",
+		filepath.Join(t.TempDir(), "prod.env"): "prod.env",
+	}
+	for local, want := range tests {
+		if got := secretRemoteName(local); got != want {
+			t.Errorf("secretRemoteName(%q) = %q, want %q", local, got, want)
+		}
+	}
+}
+
+func TestDeployer_ExecuteFirstDeployHooks_Skipped(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	d := &Deployer{env: &config.Environment{}, log: log}
+
+	// No first_deploy hooks: nothing runs (a nil client would panic otherwise)
+	if err := d.executeFirstDeployHooks(nil, "/var/www/app/releases/1", nil); err != nil {
+		t.Errorf("expected no error without first_deploy hooks, got %v", err)
+	}
+
+	// With a deploy.lock on the server this is not the first deploy
+	d.env.FirstDeploy = []config.HookConfig{{Command: "php artisan db:seed --force"}}
+	if err := d.executeFirstDeployHooks(nil, "/var/www/app/releases/1", &state.DeployLock{}); err != nil {
+		t.Errorf("expected first_deploy hooks to be skipped on later deploys, got %v", err)
+	}
+}
+
+func TestComposerOnlyChanges(t *testing.T) {
+	php := config.PHPBuildConfig{Enabled: true, ProjectRoot: "api"}
+
+	cs := &changeset.ChangeSet{OtherFiles: []string{"api/composer.json", "api/composer.lock"}, ComposerChanged: true}
+	if got := composerOnlyChanges(cs, php); len(got) != 2 {
+		t.Errorf("expected both Composer manifests, got %v", got)
+	}
+
+	for name, cs := range map[string]*changeset.ChangeSet{
+		"source change":      {OtherFiles: []string{"api/composer.lock"}, PHPFiles: []string{"api/src/App.php"}},
+		"other file":         {OtherFiles: []string{"api/composer.lock", "README.md"}},
+		"manifest elsewhere": {OtherFiles: []string{"composer.lock"}},
+		"frontend deps":      {OtherFiles: []string{"api/composer.lock"}, PackageChanged: true},
+		"deleted file":       {OtherFiles: []string{"api/composer.lock"}, DeletedFiles: []string{"api/old.php"}},
+		"forced":             {OtherFiles: []string{"api/composer.lock"}, Force: true},
+		"nothing":            {},
+	} {
+		if got := composerOnlyChanges(cs, php); got != nil {
+			t.Errorf("%s: expected a full release, got %v", name, got)
+		}
+	}
+
+	if got := composerOnlyChanges(&changeset.ChangeSet{OtherFiles: []string{"composer.lock"}}, config.PHPBuildConfig{}); got != nil {
+		t.Errorf("expected nil with PHP builds disabled, got %v", got)
+	}
+}
+
+func TestDeployer_RemoteJobsCmd(t *testing.T) {
+	d := &Deployer{}
+	if got := d.remoteJobsCmd("composer install -j{jobs}"); got != "composer install -j$(nproc 2>/dev/null || echo 1)" {
+		t.Errorf("expected the server's nproc, got %q", got)
+	}
+	d.BuildJobs = 3
+	if got := d.remoteJobsCmd("composer install -j{jobs}"); got != "composer install -j3" {
+		t.Errorf("expected --build-jobs to win, got %q", got)
+	}
+}
+
+func TestRemoteEnvCmd(t *testing.T) {
+	if got := remoteEnvCmd(nil, "composer install"); got != "composer install" {
+		t.Errorf("expected the command unchanged without env, got %q", got)
+	}
+	env := map[string]string{"COMPOSER_MEMORY_LIMIT": "-1", "APP_ENV": "prod's"}
+	want := `export APP_ENV='prod'"'"'s' COMPOSER_MEMORY_LIMIT='-1' && composer install`
+	if got := remoteEnvCmd(env, "composer install"); got != want {
+		t.Errorf("remoteEnvCmd() = %q, want %q", got, want)
+	}
+	if out, err := exec.Command("sh", "-c", remoteEnvCmd(env, `printf %s "$APP_ENV"`)).Output(); err == nil && string(out) != "prod's" {
+		t.Errorf("expected the env to reach the command, got %q", out)
+	}
+}
+
+func TestUnlinkDirCmd(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	older := filepath.Join(dir, "old", "vendor")
+	live := filepath.Join(dir, "live", "vendor")
+	if err := os.MkdirAll(older, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(older, "autoload.php"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("cp", "-al", filepath.Dir(older), filepath.Dir(live)).CombinedOutput(); err != nil {
+		t.Skipf("cp -al not available: %v: %s", err, out)
+	}
+
+	if out, err := exec.Command("sh", "-c", unlinkDirCmd(live)).CombinedOutput(); err != nil {
+		t.Fatalf("unlinkDirCmd failed: %v: %s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(live, "autoload.php"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(older, "autoload.php")); string(data) != "old" {
+		t.Errorf("older release changed through a hardlink: %q", data)
+	}
+	if _, err := os.Stat(live + ".versa-old"); !os.IsNotExist(err) {
+		t.Errorf("expected the replaced dir to be removed, got %v", err)
+	}
+
+	// A release without the dir is left alone
+	if out, err := exec.Command("sh", "-c", unlinkDirCmd(filepath.Join(dir, "missing"))).CombinedOutput(); err != nil {
+		t.Errorf("expected a missing dir to be skipped: %v: %s", err, out)
+	}
+}
+
+func TestInPlaceChanges(t *testing.T) {
+	previous := state.New("abc", "20260101_000000", map[string]string{
+		"src/App.php": "h1", "config/app.ini": "h2", "storage/cache.php": "h3", "public/app.js.map": "h4",
+		"a.php": "h", "b.php": "h", "c.php": "h", "d.php": "h", "e.php": "h", "f.php": "h",
+		".idea/tool.php": "h5", "lib/.svn/entries": "h6",
+	}, "", "", "", "")
+	d := &Deployer{env: &config.Environment{
+		SharedPaths:     []string{"storage"},
+		ArtifactExclude: []string{"*.map"},
+	}}
+
+	cs := &changeset.ChangeSet{PHPFiles: []string{"src/App.php"}, OtherFiles: []string{"config/app.ini"}}
+	if got := d.inPlaceChanges(cs, previous); len(got) != 2 {
+		t.Errorf("expected both files to be applied in place, got %v", got)
+	}
+
+	for name, cs := range map[string]*changeset.ChangeSet{
+		"new file":         {PHPFiles: []string{"src/New.php"}},
+		"shared path":      {PHPFiles: []string{"storage/cache.php"}},
+		"excluded file":    {OtherFiles: []string{"public/app.js.map"}},
+		"skipped dir":      {PHPFiles: []string{".idea/tool.php"}},
+		"nested skip dir":  {OtherFiles: []string{"lib/.svn/entries"}},
+		"template":         {PHPFiles: []string{"src/App.php"}, TwigFiles: []string{"views/home.twig"}},
+		"composer changed": {PHPFiles: []string{"src/App.php"}, ComposerChanged: true},
+		"routes changed":   {PHPFiles: []string{"src/App.php"}, RoutesChanged: true},
+		"deleted file":     {PHPFiles: []string{"src/App.php"}, DeletedFiles: []string{"src/Old.php"}},
+		"too many files":   {PHPFiles: []string{"a.php", "b.php", "c.php", "d.php", "e.php", "f.php"}},
+		"forced":           {PHPFiles: []string{"src/App.php"}, Force: true},
+		"nothing":          {},
+	} {
+		if got := d.inPlaceChanges(cs, previous); got != nil {
+			t.Errorf("%s: expected a full release, got %v", name, got)
+		}
+	}
+
+	if got := d.inPlaceChanges(cs, nil); got != nil {
+		t.Errorf("expected a full release on the first deploy, got %v", got)
+	}
+	d.env.InPlaceMaxFiles = 1
+	if got := d.inPlaceChanges(cs, previous); got != nil {
+		t.Errorf("expected in_place_max_files to cap the changeset, got %v", got)
+	}
+}
+
+func TestCompareFileHashes(t *testing.T) {
+	from := map[string]string{"app/a.php": "1", "app/b.php": "2", "app/old.php": "3"}
+	to := map[string]string{"app/a.php": "1", "app/b.php": "20", "app/new.php": "4", "app/c.php": "5"}
+
+	added, removed, modified := compareFileHashes(from, to)
+	if strings.Join(added, ",") != "app/c.php,app/new.php" {
+		t.Errorf("added = %v", added)
+	}
+	if strings.Join(removed, ",") != "app/old.php" {
+		t.Errorf("removed = %v", removed)
+	}
+	if strings.Join(modified, ",") != "app/b.php" {
+		t.Errorf("modified = %v", modified)
+	}
+}
+
+func TestDeployer_ExecuteCacheCommands_NotNeeded(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	d := &Deployer{env: &config.Environment{}, log: log}
+	d.env.Builds.PHP = config.PHPBuildConfig{Enabled: true, RouteCacheCommand: "php artisan route:cache"}
+
+	// None of these reach the (nil) SSH client
+	for name, result := range map[string]*builder.BuildResult{
+		"no build result":      nil,
+		"routes unchanged":     {},
+		"twig without command": {TwigCacheCleanup: true},
+	} {
+		if err := d.executeCacheCommands(nil, "/var/www/app/releases/1", result, nil); err != nil {
+			t.Errorf("%s: expected no cache command, got %v", name, err)
+		}
+	}
+}
+
+func TestDeployer_ExecuteCacheCommands_Runs(t *testing.T) {
+	d, _ := newRemoteTestDeployer(t, func(env *config.Environment) {
+		env.Builds.PHP = config.PHPBuildConfig{
+			Enabled:           true,
+			ProjectRoot:       "api",
+			RouteCacheCommand: "touch routes-cached",
+			TwigCacheCommand:  "touch twig-cleared",
+		}
+	})
+	sshClient, err := ssh.NewClient(&d.env.SSH, d.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sshClient.Close()
+
+	finalDir := t.TempDir()
+	phpRoot := filepath.Join(finalDir, "app", "api")
+	if err := os.MkdirAll(phpRoot, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the flagged command runs, in the PHP root of the release
+	if err := d.executeCacheCommands(sshClient, finalDir, &builder.BuildResult{RouteCacheRegenerate: true}, nil); err != nil {
+		t.Fatalf("executeCacheCommands() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(phpRoot, "routes-cached")); err != nil {
+		t.Errorf("expected route_cache_command to run in the PHP root: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(phpRoot, "twig-cleared")); err == nil {
+		t.Error("expected twig_cache_command not to run without TwigCacheCleanup")
+	}
+
+	if err := d.executeCacheCommands(sshClient, finalDir, &builder.BuildResult{TwigCacheCleanup: true}, nil); err != nil {
+		t.Fatalf("executeCacheCommands() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(phpRoot, "twig-cleared")); err != nil {
+		t.Errorf("expected twig_cache_command to run: %v", err)
+	}
+
+	// A failing command fails the deploy
+	d.env.Builds.PHP.RouteCacheCommand = "false"
+	if err := d.executeCacheCommands(sshClient, finalDir, &builder.BuildResult{RouteCacheRegenerate: true}, nil); err == nil {
+		t.Error("expected a failing route_cache_command to fail")
+	}
+}
+
+func TestTracer(t *testing.T) {
+	var off *tracer
+	off.step("clone")
+	off.record(stepTiming{Step: "build: php"})
+	off.end()
+
+	trace := newTracer()
+	trace.step("clone")
+	trace.step("build")
+	trace.end()
+	trace.record(buildSteps(map[string]time.Duration{"php": 2 * time.Second, "frontend": time.Second})...)
+	trace.step("upload")
+
+	report := trace.report()
+	var steps []string
+	for _, line := range strings.Split(strings.TrimSpace(report), "\n") {
+		steps = append(steps, strings.Fields(line)[0])
+	}
+	want := "clone build build: build: upload total"
+	if got := strings.Join(steps, " "); got != want {
+		t.Errorf("unexpected step order %q, want %q\n%s", got, want, report)
+	}
+	if !strings.Contains(report, "build: frontend") || strings.Index(report, "build: frontend") > strings.Index(report, "build: php") {
+		t.Errorf("expected sorted per-language build steps:\n%s", report)
+	}
+}
+
+func TestTracer_AppendTimings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deploy-timings.csv")
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, release := range []string{"20260301_120000", "20260302_120000"} {
+		trace := newTracer()
+		trace.step("clone")
+		trace.end()
+		trace.record(buildSteps(map[string]time.Duration{"php": 1500 * time.Millisecond})...)
+		if err := trace.appendTimings(path, "production", release, at); err != nil {
+			t.Fatalf("appendTimings() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 7 {
+		t.Fatalf("expected header plus 3 rows per deploy, got %d lines:\n%s", len(lines), data)
+	}
+	if lines[0] != "timestamp,environment,release,step,duration" {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if lines[2] != "2026-03-01T12:00:00Z,production,20260301_120000,build: php,1.500" {
+		t.Errorf("unexpected build row %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[6], "2026-03-01T12:00:00Z,production,20260302_120000,total,") {
+		t.Errorf("unexpected total row %q", lines[6])
+	}
+}
+
+func TestDeployer_CheckDiskSpace_Skip(t *testing.T) {
+	d, remotePath := newRemoteTestDeployer(t, nil)
+	if err := os.MkdirAll(filepath.Join(remotePath, "releases"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	sshClient, err := ssh.NewClient(&d.env.SSH, d.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sshClient.Close()
+	releasesDir := filepath.ToSlash(filepath.Join(remotePath, "releases"))
+
+	if err := d.checkDiskSpace(sshClient, releasesDir, 1<<60); err == nil {
+		t.Error("expected the check to fail for 1 EiB")
+	}
+	// Either the flag or the config setting bypasses it
+	d.SkipDiskCheck = true
+	if err := d.checkDiskSpace(sshClient, releasesDir, 1<<60); err != nil {
+		t.Errorf("expected --skip-disk-check to bypass the check, got %v", err)
+	}
+	d.SkipDiskCheck = false
+	d.env.SkipDiskCheck = true
+	if err := d.checkDiskSpace(sshClient, releasesDir, 1<<60); err != nil {
+		t.Errorf("expected skip_disk_check to bypass the check, got %v", err)
+	}
+}
+
+func TestDeployer_TempDir(t *testing.T) {
+	repo := t.TempDir()
+	d := &Deployer{env: &config.Environment{}, repoPath: repo}
+	if got := d.tempDir(); got != os.TempDir() {
+		t.Errorf("expected system temp dir, got %q", got)
+	}
+
+	d.env.TempDir = "scratch"
+	if got, want := d.tempDir(), filepath.Join(repo, "scratch"); got != want {
+		t.Errorf("expected temp_dir relative to the project %q, got %q", want, got)
+	}
+	if err := d.checkTempDir(); err != nil {
+		t.Fatalf("checkTempDir() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "scratch")); err != nil {
+		t.Errorf("expected temp dir to be created: %v", err)
+	}
+
+	d.TempDir = filepath.Join(repo, "flag")
+	if got := d.tempDir(); got != d.TempDir {
+		t.Errorf("expected TempDir to override temp_dir, got %q", got)
+	}
+
+	// A regular file in place of the directory is not usable
+	blocked := filepath.Join(repo, "blocked")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	d.TempDir = blocked
+	if err := d.checkTempDir(); err == nil {
+		t.Error("expected error when the temp dir is a file")
+	}
+}
+
+func TestDeployer_OwnRepoPaths(t *testing.T) {
+	repo := t.TempDir()
+	d := &Deployer{env: &config.Environment{TimingsFile: "deploy-timings.csv", TempDir: ".versa-tmp"}, repoPath: repo}
+	if got := strings.Join(d.ownRepoPaths(), ","); got != ".versa-tmp,deploy-timings.csv" {
+		t.Errorf("ownRepoPaths() = %q, want temp_dir and timings_file", got)
+	}
+
+	// Paths outside the repository are not the tree's business
+	d.env.TimingsFile = filepath.Join(t.TempDir(), "timings.csv")
+	d.env.TempDir = ""
+	if got := d.ownRepoPaths(); len(got) != 0 {
+		t.Errorf("ownRepoPaths() = %v, want none", got)
+	}
+}
+
+func TestCheckSameCommit(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	commit := strings.Repeat("a", 40)
+	live := state.New(commit, "20260101_000000", nil, "", "", "", "")
+
+	d := &Deployer{env: &config.Environment{}, envName: "production", log: log}
+	if err := d.checkSameCommit(nil, commit); err != nil {
+		t.Errorf("first deploy: unexpected error %v", err)
+	}
+	if err := d.checkSameCommit(live, strings.Repeat("b", 40)); err != nil {
+		t.Errorf("new commit: unexpected error %v", err)
+	}
+	if err := d.checkSameCommit(live, commit); err == nil {
+		t.Error("expected the same commit to be refused without --force or confirmation")
+	}
+
+	d.SameCommitConfirm = func() bool { return false }
+	if err := d.checkSameCommit(live, commit); err == nil {
+		t.Error("expected a declined confirmation to refuse the deploy")
+	}
+	d.SameCommitConfirm = func() bool { return true }
+	if err := d.checkSameCommit(live, commit); err != nil {
+		t.Errorf("confirmed: unexpected error %v", err)
+	}
+
+	d.SameCommitConfirm = nil
+	d.force = true
+	if err := d.checkSameCommit(live, commit); err != nil {
+		t.Errorf("--force: unexpected error %v", err)
+	}
+	d.force = false
+	d.dirtyTree = true
+	if err := d.checkSameCommit(live, commit); err != nil {
+		t.Errorf("dirty tree: unexpected error %v", err)
+	}
+
+	// The live release was built from uncommitted changes: the clean commit differs from it
+	d.dirtyTree = false
+	live.LastDeploy.DirtyTree = true
+	if err := d.checkSameCommit(live, commit); err != nil {
+		t.Errorf("dirty live release: unexpected error %v", err)
+	}
+}
+
+func TestDeployer_HookLimit(t *testing.T) {
+	for _, tt := range []struct {
+		hookConcurrency, concurrency, want int
+	}{
+		{0, 0, defaultHookConcurrency},
+		{20, 0, 20},
+		{0, 2, 2},
+		{3, 8, 3},
+		{0, 50, defaultHookConcurrency},
+	} {
+		d := &Deployer{env: &config.Environment{HookConcurrency: tt.hookConcurrency, Concurrency: tt.concurrency}}
+		if got := d.hookLimit(); got != tt.want {
+			t.Errorf("hook_concurrency %d, concurrency %d: hookLimit() = %d, want %d", tt.hookConcurrency, tt.concurrency, got, tt.want)
+		}
+	}
+}
+
+func TestRunSuffix(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		s := runSuffix()
+		if len(s) != 6 || strings.Trim(s, "0123456789abcdef") != "" {
+			t.Fatalf("expected 6 hex characters, got %q", s)
+		}
+		seen[s] = true
+	}
+	if len(seen) < 2 {
+		t.Error("expected suffixes to differ between runs")
+	}
+}
+
+func TestPreviousReleaseOf(t *testing.T) {
+	sorted := []string{"20240103_000000", "20240102_000000", "20240101_000000"}
+
+	if got, err := previousReleaseOf(sorted, "20240103_000000"); err != nil || got != "20240102_000000" {
+		t.Errorf("expected 20240102_000000, got %q (err %v)", got, err)
+	}
+	// Repeated rollbacks keep moving back instead of returning to the newest
+	if got, err := previousReleaseOf(sorted, "20240102_000000"); err != nil || got != "20240101_000000" {
+		t.Errorf("expected 20240101_000000, got %q (err %v)", got, err)
+	}
+	if _, err := previousReleaseOf(sorted, "20240101_000000"); err == nil {
+		t.Error("expected error when current is the oldest release")
+	}
+	if got, err := previousReleaseOf(sorted, "20231231_000000"); err != nil || got != "20240103_000000" {
+		t.Errorf("expected newest release when current is unknown, got %q (err %v)", got, err)
+	}
+}
+
+func TestResolveRollbackTarget(t *testing.T) {
+	sorted := []string{"20240103_000000", "20240102_000000", "20240101_000000"}
+	tests := map[string]string{
+		"oldest":          "20240101_000000",
+		"newest":          "20240103_000000",
+		"20240102_000000": "20240102_000000",
+	}
+	for target, want := range tests {
+		if got := resolveRollbackTarget(sorted, target); got != want {
+			t.Errorf("resolveRollbackTarget(%q) = %q, want %q", target, got, want)
+		}
+	}
+	if got := resolveRollbackTarget(nil, "oldest"); got != "oldest" {
+		t.Errorf("expected alias unchanged without releases, got %q", got)
+	}
+}
+
+func TestRollbackDepth(t *testing.T) {
+	sorted := []string{"r4", "r3", "r2", "r1"}
+	history := []state.HistoryEntry{
+		{ReleaseDir: "r2"},
+		{ReleaseDir: "r3"},
+		{ReleaseDir: "r2", Action: state.HistoryActionRollback},
+		{ReleaseDir: "r4"},
+	}
+	tests := map[string]int{
+		"r4": 0,
+		"r3": 1,
+		// Deployed two releases ago; the rollback in between doesn't count
+		"r2": 2,
+		// Not in the history: falls back to the release listing
+		"r1": 3,
+	}
+	for target, want := range tests {
+		if got := rollbackDepth(history, sorted, target); got != want {
+			t.Errorf("rollbackDepth(%q) = %d, want %d", target, got, want)
+		}
+	}
+}
+
+func TestPromoteExcludes(t *testing.T) {
+	source := &Deployer{env: &config.Environment{
+		SharedPaths:    []string{"storage/", "../outside"},
+		SecretFiles:    []string{".env"},
+		PreservedPaths: []string{"public/uploads"},
+	}}
+
+	got := strings.Join(promoteExcludes(source), " ")
+	want := "--exclude='./app/storage' --exclude='./app/.env' --exclude='./app/public/uploads' --exclude='./deploy.lock'"
+	if got != want {
+		t.Errorf("promoteExcludes() = %s, want %s", got, want)
+	}
+}
+
+func TestChownCmd(t *testing.T) {
+	got := chownCmd("www-data:www-data", "/var/www/releases/20240101_000000/app/cache", "/var/www/shared/uploads")
+	want := "chown -R 'www-data:www-data' '/var/www/releases/20240101_000000/app/cache' '/var/www/shared/uploads' 2>/dev/null || " +
+		"sudo -n chown -R 'www-data:www-data' '/var/www/releases/20240101_000000/app/cache' '/var/www/shared/uploads'"
+	if got != want {
+		t.Errorf("chownCmd() = %s, want %s", got, want)
+	}
+}
+
+// newRemoteTestDeployer commits a one-file repository and returns an initial deploy
+// of it to a local SSH server standing in for the remote, along with remote_path.
+// edit, when set, adjusts the environment first.
+func newRemoteTestDeployer(t *testing.T, edit func(env *config.Environment)) (*Deployer, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "index.html"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	remotePath := filepath.Join(t.TempDir(), "www")
+	env := config.Environment{
+		SSH:        sshtest.NewServer(t),
+		RemotePath: remotePath,
+		TempDir:    t.TempDir(),
+	}
+	if edit != nil {
+		edit(&env)
+	}
+	cfg := &config.Config{Project: "test", Environments: map[string]config.Environment{"prod": env}}
+	log, _ := logger.NewLogger("", false, false)
+	d, err := NewDeployer(cfg, "prod", repo, false, true, false, false, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, remotePath
+}
+
+func TestDeployer_Deploy_OwnerKeepsLockSnapshot(t *testing.T) {
+	d, remotePath := newRemoteTestDeployer(t, func(env *config.Environment) {
+		env.Owner = fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+		env.EnsureDirs = []string{"storage/cache"}
+	})
+	if err := d.Deploy(); err != nil {
+		t.Fatalf("Deploy() error = %v", err)
+	}
+
+	for _, path := range []string{"deploy.lock", "app/storage/cache", "app/index.html"} {
+		if _, err := os.Stat(filepath.Join(remotePath, "current", path)); err != nil {
+			t.Errorf("expected %s in the release: %v", path, err)
+		}
+	}
+}
+
+func TestDeployer_Deploy_SmokeTestFailureRollsBack(t *testing.T) {
+	reloads := filepath.Join(t.TempDir(), "reloads")
+	d, remotePath := newRemoteTestDeployer(t, func(env *config.Environment) {
+		env.ServicesReload = []string{"readlink " + filepath.Join(env.RemotePath, "current") + " >> " + reloads}
+	})
+	if err := d.Deploy(); err != nil {
+		t.Fatalf("first Deploy() error = %v", err)
+	}
+	current := filepath.Join(remotePath, "current")
+	target, err := os.Readlink(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := filepath.Base(target)
+
+	if err := os.WriteFile(filepath.Join(d.repoPath, "index.html"), []byte("broken\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", d.repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-am", "broken").CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v: %s", err, out)
+	}
+	d.initialDeploy = false
+	d.env.SmokeTests = []config.HookConfig{{Command: "grep -q hello index.html"}}
+	// Release versions have second granularity
+	time.Sleep(time.Second)
+
+	err = d.Deploy()
+	if err == nil || !strings.Contains(err.Error(), "smoke test failed (rolled back to") {
+		t.Fatalf("expected the smoke test failure to roll back, got %v", err)
+	}
+	if got, _ := os.Readlink(current); filepath.Base(got) != first {
+		t.Errorf("current -> %s after rollback, want %s", got, first)
+	}
+
+	// Services are reloaded once more after the switch back
+	data, err := os.ReadFile(reloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if last := lines[len(lines)-1]; filepath.Base(last) != first {
+		t.Errorf("last services_reload saw current -> %s, want %s", last, first)
+	}
+}
+
+func TestDeployer_RollbackTo_RecordsHistory(t *testing.T) {
+	d, remotePath := newRemoteTestDeployer(t, nil)
+	// Deployed with --override-window outside deploy_windows
+	d.windowOverridden = true
+	if err := d.Deploy(); err != nil {
+		t.Fatalf("first Deploy() error = %v", err)
+	}
+	target, err := os.Readlink(filepath.Join(remotePath, "current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := filepath.Base(target)
+
+	d.initialDeploy = false
+	d.force = true
+	d.windowOverridden = false
+	// Release versions have second granularity
+	time.Sleep(time.Second)
+	if err := d.Deploy(); err != nil {
+		t.Fatalf("second Deploy() error = %v", err)
+	}
+	if err := d.RollbackTo(first); err != nil {
+		t.Fatalf("RollbackTo() error = %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(remotePath, "deploy-history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []state.HistoryEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e state.HistoryEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 2 deploys and a rollback in the history, got %d entries", len(entries))
+	}
+	if !entries[0].WindowOverride || entries[1].WindowOverride {
+		t.Errorf("expected only the first deploy to record the window override: %+v", entries[:2])
+	}
+	rollback := entries[2]
+	if rollback.Action != state.HistoryActionRollback || rollback.ReleaseDir != first || rollback.CommitHash != entries[0].CommitHash {
+		t.Errorf("unexpected rollback entry: %+v", rollback)
+	}
+}
+
+func TestDeployer_Rollback_PromotesLockSnapshot(t *testing.T) {
+	d, remotePath := newRemoteTestDeployer(t, nil)
+	if err := d.Deploy(); err != nil {
+		t.Fatalf("first Deploy() error = %v", err)
+	}
+	readLock := func(path string) *state.DeployLock {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lock, err := state.Parse(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lock
+	}
+	first := readLock(filepath.Join(remotePath, "current", "deploy.lock"))
+
+	if err := os.WriteFile(filepath.Join(d.repoPath, "index.html"), []byte("changed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", d.repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-am", "changed").CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %v: %s", err, out)
+	}
+	d.initialDeploy = false
+	// Release versions have second granularity
+	time.Sleep(time.Second)
+	if err := d.Deploy(); err != nil {
+		t.Fatalf("second Deploy() error = %v", err)
+	}
+	if lock := readLock(filepath.Join(remotePath, "deploy.lock")); lock.LastDeploy.CommitHash == first.LastDeploy.CommitHash {
+		t.Fatal("expected the second deploy to update deploy.lock")
+	}
+
+	if err := d.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	lock := readLock(filepath.Join(remotePath, "deploy.lock"))
+	if lock.LastDeploy.CommitHash != first.LastDeploy.CommitHash || lock.LastDeploy.ReleaseDir != first.LastDeploy.ReleaseDir {
+		t.Errorf("expected deploy.lock to be the first release's snapshot, got %+v", lock.LastDeploy)
+	}
+}
+
+func TestDeployer_Rollback_DryRun(t *testing.T) {
+	d, remotePath := newRemoteTestDeployer(t, nil)
+	if err := d.Deploy(); err != nil {
+		t.Fatalf("first Deploy() error = %v", err)
+	}
+	target, err := os.Readlink(filepath.Join(remotePath, "current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := filepath.Base(target)
+
+	d.initialDeploy = false
+	d.force = true
+	// Release versions have second granularity
+	time.Sleep(time.Second)
+	if err := d.Deploy(); err != nil {
+		t.Fatalf("second Deploy() error = %v", err)
+	}
+	current, err := os.Readlink(filepath.Join(remotePath, "current"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockBefore, err := os.ReadFile(filepath.Join(remotePath, "deploy.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.dryRun = true
+	if err := d.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if err := d.RollbackTo(first); err != nil {
+		t.Fatalf("RollbackTo() error = %v", err)
+	}
+
+	if got, _ := os.Readlink(filepath.Join(remotePath, "current")); got != current {
+		t.Errorf("dry run switched current from %s to %s", current, got)
+	}
+	if lock, _ := os.ReadFile(filepath.Join(remotePath, "deploy.lock")); string(lock) != string(lockBefore) {
+		t.Error("dry run rewrote deploy.lock")
+	}
+	history, err := os.ReadFile(filepath.Join(remotePath, "deploy-history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(history), state.HistoryActionRollback) {
+		t.Errorf("dry run recorded a rollback:\n%s", history)
+	}
+}
+
+func TestDeployer_Deploy_PostDeployConfirm(t *testing.T) {
+	for _, confirm := range []bool{true, false} {
+		t.Run(fmt.Sprintf("confirm=%v", confirm), func(t *testing.T) {
+			markers := t.TempDir()
+			d, _ := newRemoteTestDeployer(t, func(env *config.Environment) {
+				env.FirstDeploy = []config.HookConfig{{Command: "touch " + filepath.Join(markers, "first_deploy")}}
+				env.PostDeploy = []config.HookConfig{{Command: "touch " + filepath.Join(markers, "post_deploy")}}
+			})
+			asked := 0
+			d.PostDeployConfirm = func() bool {
+				asked++
+				return confirm
+			}
+			if err := d.Deploy(); err != nil {
+				t.Fatalf("Deploy() error = %v", err)
+			}
+
+			if asked != 1 {
+				t.Errorf("expected one confirmation prompt, got %d", asked)
+			}
+			// first_deploy hooks run either way; the answer only decides post_deploy
+			if _, err := os.Stat(filepath.Join(markers, "first_deploy")); err != nil {
+				t.Errorf("expected first_deploy hooks to run: %v", err)
+			}
+			_, err := os.Stat(filepath.Join(markers, "post_deploy"))
+			if ran := err == nil; ran != confirm {
+				t.Errorf("post_deploy ran = %v, want %v", ran, confirm)
+			}
+		})
+	}
+}
+
+func TestDeployer_TestBuild(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "hook-ran")
+	d, _ := newRemoteTestDeployer(t, func(env *config.Environment) {
+		env.PreDeployLocal = []config.HookConfig{{Command: "touch " + marker}}
+	})
+
+	if err := d.TestBuild(false, false); err != nil {
+		t.Fatalf("TestBuild() error = %v", err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("expected pre_deploy_local hooks to be skipped by default")
+	}
+	// The artifact and chunks are removed without --keep
+	leftovers, _ := filepath.Glob(filepath.Join(d.tempDir(), "versadeploy-*"))
+	if len(leftovers) > 0 {
+		t.Errorf("expected the artifact to be removed, found %v", leftovers)
+	}
+
+	if err := d.TestBuild(false, true); err != nil {
+		t.Fatalf("TestBuild() with hooks error = %v", err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected pre_deploy_local hooks to run when asked for: %v", err)
+	}
+}
+
+func TestDeployer_Deploy_DryRunLeavesFreshServerUntouched(t *testing.T) {
+	d, remotePath := newRemoteTestDeployer(t, nil)
+	d.dryRun = true
+	if err := d.Deploy(); err != nil {
+		t.Fatalf("Deploy() error = %v", err)
+	}
+	if _, err := os.Stat(remotePath); !os.IsNotExist(err) {
+		t.Errorf("expected a dry run not to create remote_path, stat: %v", err)
+	}
+}
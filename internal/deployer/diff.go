@@ -0,0 +1,84 @@
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/user/versaDeploy/internal/changeset"
+	verserrors "github.com/user/versaDeploy/internal/errors"
+	"github.com/user/versaDeploy/internal/git"
+	"github.com/user/versaDeploy/internal/ssh"
+	"github.com/user/versaDeploy/internal/state"
+)
+
+// Diff calculates the changeset the next deploy of HEAD would ship, against the
+// deploy.lock on the server. It only reads: nothing is built, locked or uploaded.
+// Without a deploy.lock on the server every file counts as changed.
+func (d *Deployer) Diff() (*changeset.ChangeSet, error) {
+	if err := git.ValidateRepository(d.repoPath); err != nil {
+		return nil, fmt.Errorf("repository validation failed: %w", err)
+	}
+	if clean, err := git.IsClean(d.repoPath, d.ownRepoPaths()...); err == nil && !clean {
+		d.log.Warn("Working directory has uncommitted changes; they are not part of the diff")
+	}
+
+	// Same clone as a deploy (LFS content included), so the hashes match what it would ship
+	tmpRepo, err := d.cloneRepo()
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpRepo)
+
+	d.log.Info("Connecting to %s@%s...", d.env.SSH.User, d.env.SSH.Host)
+	sshClient, err := ssh.NewClient(&d.env.SSH, d.log)
+	if err != nil {
+		return nil, verserrors.Wrap(err)
+	}
+	defer sshClient.Close()
+
+	previousLock, err := d.fetchRemoteLock(sshClient)
+	if err != nil {
+		return nil, err
+	}
+	if previousLock == nil {
+		d.log.Warn("No deploy.lock on %s: every file counts as changed", d.envName)
+	}
+
+	detector := changeset.NewDetector(tmpRepo, d.env.Ignored, d.env.RouteFiles, d.env.Builds.PHP.ProjectRoot, d.env.Builds.Go.ProjectRoot, d.env.Builds.Frontend.ProjectRoot, d.env.Builds.Python.ProjectRoot, d.env.Builds.Python.RequirementsFile, previousLock)
+	detector.Workers = d.env.Concurrency
+	return detector.Detect()
+}
+
+// fetchRemoteLock downloads and parses the server's deploy.lock, returning nil
+// when there is none yet
+func (d *Deployer) fetchRemoteLock(sshClient *ssh.Client) (*state.DeployLock, error) {
+	lockPath := filepath.ToSlash(filepath.Join(d.env.RemotePath, "deploy.lock"))
+	exists, err := sshClient.FileExists(lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check deploy.lock: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	tmpLockFile, err := os.CreateTemp(d.tempDir(), fmt.Sprintf("deploy-%s-*.lock", d.envName))
+	if err != nil {
+		return nil, err
+	}
+	tmpLockFile.Close()
+	defer os.Remove(tmpLockFile.Name())
+
+	if err := sshClient.DownloadFile(lockPath, tmpLockFile.Name()); err != nil {
+		return nil, err
+	}
+	lockData, err := os.ReadFile(tmpLockFile.Name())
+	if err != nil {
+		return nil, err
+	}
+	lock, err := state.Parse(lockData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deploy.lock: %w", err)
+	}
+	return lock, nil
+}
@@ -0,0 +1,102 @@
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/user/versaDeploy/internal/ssh"
+)
+
+// doctorMinFreeBytes is the free space doctor expects on the remote path
+const doctorMinFreeBytes = 100 * 1024 * 1024
+
+// DoctorCheck is the result of a single diagnostic run by Doctor
+type DoctorCheck struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// Doctor diagnoses common setup problems for the environment. It runs every
+// check it can and never stops at the first failure; remote checks are marked
+// as failed when the SSH connection can't be established.
+func (d *Deployer) Doctor() []DoctorCheck {
+	var checks []DoctorCheck
+	add := func(name string, err error, okDetail string) {
+		if err != nil {
+			checks = append(checks, DoctorCheck{Name: name, Detail: err.Error()})
+			return
+		}
+		checks = append(checks, DoctorCheck{Name: name, OK: true, Detail: okDetail})
+	}
+
+	// Local checks
+	add("SSH key", checkKeyFile(d.env.SSH.KeyPath), d.env.SSH.KeyPath)
+
+	if _, err := exec.LookPath("git"); err != nil {
+		add("Local git", fmt.Errorf("git not found in PATH"), "")
+	} else {
+		add("Local git", nil, "found")
+	}
+
+	add("Local build tools", d.validateLocalTools(), "all required tools found")
+
+	// Remote checks
+	sshClient, err := ssh.NewClient(&d.env.SSH, d.log)
+	if err != nil {
+		add("SSH connection", err, "")
+		for _, name := range []string{"SFTP write access", "Disk space", "Remote tools"} {
+			checks = append(checks, DoctorCheck{Name: name, Detail: "skipped: no SSH connection"})
+		}
+		return checks
+	}
+	defer sshClient.Close()
+	add("SSH connection", nil, fmt.Sprintf("%s@%s:%d", d.env.SSH.User, d.env.SSH.Host, d.env.SSH.Port))
+
+	add("SFTP write access", checkRemoteWritable(sshClient, d.env.RemotePath), d.env.RemotePath)
+
+	add("Disk space", sshClient.CheckDiskSpace(d.env.RemotePath, doctorMinFreeBytes),
+		fmt.Sprintf("at least %d MB free", doctorMinFreeBytes/(1024*1024)))
+
+	var missing []string
+	for _, tool := range []string{"tar", "git"} {
+		if _, err := sshClient.ExecuteCommand(fmt.Sprintf("command -v %s", tool)); err != nil {
+			missing = append(missing, tool)
+		}
+	}
+	if len(missing) > 0 {
+		add("Remote tools", fmt.Errorf("missing on remote: %s", strings.Join(missing, ", ")), "")
+	} else {
+		add("Remote tools", nil, "tar, git")
+	}
+
+	return checks
+}
+
+// checkKeyFile verifies the SSH key exists and is not readable by group/other
+func checkKeyFile(keyPath string) error {
+	info, err := os.Stat(keyPath)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %w", keyPath, err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return fmt.Errorf("insecure permissions %o on %s (run 'chmod 600 %s')", info.Mode().Perm(), keyPath, keyPath)
+	}
+	return nil
+}
+
+// checkRemoteWritable writes and removes a probe file under remotePath via SFTP
+func checkRemoteWritable(sshClient *ssh.Client, remotePath string) error {
+	if err := sshClient.MkdirAll(remotePath); err != nil {
+		return fmt.Errorf("cannot create %s: %w", remotePath, err)
+	}
+	probe := filepath.ToSlash(filepath.Join(remotePath, ".versa-doctor"))
+	if err := sshClient.WriteRemoteBytes(probe, []byte("ok")); err != nil {
+		return fmt.Errorf("cannot write to %s: %w", remotePath, err)
+	}
+	return sshClient.Remove(probe)
+}
@@ -0,0 +1,66 @@
+package deployer
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/user/versaDeploy/internal/config"
+	"github.com/user/versaDeploy/internal/logger"
+)
+
+func TestCheckKeyFile(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := checkKeyFile(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing key")
+	}
+
+	key := filepath.Join(dir, "id_rsa")
+	os.WriteFile(key, []byte("fake"), 0600)
+	if err := checkKeyFile(key); err != nil {
+		t.Errorf("expected 0600 key to pass, got %v", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		os.Chmod(key, 0644)
+		if err := checkKeyFile(key); err == nil {
+			t.Error("expected error for group/other readable key")
+		}
+	}
+}
+
+func TestDeployer_Doctor_NoSSH(t *testing.T) {
+	log, _ := logger.NewLogger("", false, false)
+	cfg := &config.Config{
+		Project: "test",
+		Environments: map[string]config.Environment{
+			"prod": {
+				RemotePath: "/var/www",
+				SSH: config.SSHConfig{
+					Host:    "invalid-host-that-does-not-exist.local",
+					User:    "testuser",
+					KeyPath: "/nonexistent/key",
+				},
+			},
+		},
+	}
+
+	d, _ := NewDeployer(cfg, "prod", ".", false, false, false, false, log)
+	checks := d.Doctor()
+
+	results := make(map[string]DoctorCheck)
+	for _, c := range checks {
+		results[c.Name] = c
+	}
+	for _, name := range []string{"SSH key", "SSH connection", "SFTP write access", "Disk space", "Remote tools"} {
+		c, ok := results[name]
+		if !ok {
+			t.Fatalf("missing check %q", name)
+		}
+		if c.OK {
+			t.Errorf("expected %q to fail without a reachable host", name)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package deployer
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/user/versaDeploy/internal/builder/lang"
+	"github.com/user/versaDeploy/internal/changeset"
+	"github.com/user/versaDeploy/internal/config"
+	"github.com/user/versaDeploy/internal/ssh"
+)
+
+// composerOnlyChanges returns the changed composer.json/composer.lock paths when they
+// are the only changes in cs, or nil when anything else changed. Only Composer is
+// eligible for fast_dependency_update: Go, frontend and Python dependencies feed a
+// local build, so they always need a full release.
+func composerOnlyChanges(cs *changeset.ChangeSet, php config.PHPBuildConfig) []string {
+	if !php.Enabled || cs.Force || cs.RoutesChanged || cs.PackageChanged || cs.GoModChanged || cs.RequirementsChanged {
+		return nil
+	}
+	if len(cs.PHPFiles)+len(cs.TwigFiles)+len(cs.GoFiles)+len(cs.FrontendFiles)+len(cs.PythonFiles)+len(cs.DeletedFiles) > 0 {
+		return nil
+	}
+
+	manifests := map[string]bool{}
+	for _, name := range []string{"composer.json", "composer.lock"} {
+		manifests[strings.TrimPrefix(filepath.ToSlash(filepath.Join(php.ProjectRoot, name)), "./")] = true
+	}
+	for _, f := range cs.OtherFiles {
+		if !manifests[f] {
+			return nil
+		}
+	}
+	return cs.OtherFiles
+}
+
+// unlinkDirCmd replaces dir, when present, with a plain copy of itself, so none of
+// its files share an inode with another release any more. The copy is swapped in
+// with two renames.
+func unlinkDirCmd(dir string) string {
+	q := ssh.ShellQuote(dir)
+	fresh, old := ssh.ShellQuote(dir+".versa-new"), ssh.ShellQuote(dir+".versa-old")
+	return fmt.Sprintf("if [ -d %s ]; then rm -rf -- %s %s && cp -a -- %s %s && mv -- %s %s && mv -- %s %s && rm -rf -- %s; fi",
+		q, fresh, old, q, fresh, q, old, fresh, q, old)
+}
+
+// remoteJobsCmd expands {jobs} in a build command run on the server: --build-jobs
+// when set, otherwise the server's CPU count, which the local one says nothing about
+func (d *Deployer) remoteJobsCmd(command string) string {
+	if d.BuildJobs > 0 {
+		return lang.ExpandJobs(command, d.BuildJobs)
+	}
+	return strings.ReplaceAll(command, "{jobs}", "$(nproc 2>/dev/null || echo 1)")
+}
+
+// remoteEnvCmd exports a build's env map (sorted, values shell-quoted) before command
+// on the server
+func remoteEnvCmd(env map[string]string, command string) string {
+	if len(env) == 0 {
+		return command
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vars := make([]string, len(names))
+	for i, name := range names {
+		vars[i] = name + "=" + ssh.ShellQuote(env[name])
+	}
+	return "export " + strings.Join(vars, " ") + " && " + command
+}
+
+// fastDependencyUpdate applies a Composer-only change to the live release in place:
+// the new manifests are uploaded into current, vendor/ is unlinked from older
+// releases, composer_command runs there and
+// deploy.lock is rewritten for the same release. This skips building and shipping a
+// new release, at the cost of atomicity: a failure leaves the live release half
+// updated, and there is nothing to roll back to.
+func (d *Deployer) fastDependencyUpdate(sshClient *ssh.Client, tmpRepo, commitHash string, files []string, cs *changeset.ChangeSet) error {
+	currentSymlink := filepath.ToSlash(filepath.Join(d.env.RemotePath, "current"))
+	currentTarget, err := sshClient.ReadSymlink(currentSymlink)
+	if err != nil {
+		return fmt.Errorf("fast dependency update: failed to read current symlink: %w", err)
+	}
+	releaseDir := currentTarget
+	if !strings.HasPrefix(releaseDir, "/") {
+		releaseDir = filepath.ToSlash(filepath.Join(d.env.RemotePath, currentTarget))
+	}
+	release := filepath.Base(releaseDir)
+
+	d.log.Warn("Only Composer dependencies changed: updating release %s in place (fast_dependency_update)", release)
+	for _, f := range files {
+		remote := filepath.ToSlash(filepath.Join(releaseDir, "app", f))
+		d.log.Info("  Updating %s", f)
+		if err := sshClient.UploadFileMode(filepath.Join(tmpRepo, f), remote, 0644); err != nil {
+			return fmt.Errorf("fast dependency update: failed to upload %s: %w", f, err)
+		}
+	}
+
+	timeout := time.Duration(d.env.HookTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 300 * time.Second
+	}
+	composerDir := hookWorkDir(releaseDir, filepath.Join("app", d.env.Builds.PHP.ProjectRoot))
+
+	// vendor/ may be hardlinked (cp -al) with older releases, and composer rewriting it
+	// in place would change them too: give the live release its own copy first
+	vendorDir := filepath.ToSlash(filepath.Join(composerDir, "vendor"))
+	if output, err := sshClient.ExecuteCommand(unlinkDirCmd(vendorDir)); err != nil {
+		return fmt.Errorf("fast dependency update: failed to copy %s: %w (output: %s)", vendorDir, err, strings.TrimSpace(output))
+	}
+	composerCmd := d.remoteJobsCmd(d.env.Builds.PHP.ComposerCommand)
+	d.log.Info("Running %s in %s...", composerCmd, composerDir)
+	// Same environment as the local PHP build
+	composerCmd = remoteEnvCmd(d.env.Builds.PHP.Env, composerCmd)
+	output, err := sshClient.ExecuteCommandWithTimeout(d.wrapRemoteHook(composerDir, composerCmd, ""), timeout)
+	if err != nil {
+		d.log.Error("Composer output:\n%s", strings.TrimSpace(output))
+		return fmt.Errorf("fast dependency update failed in live release %s, which may now be inconsistent (run a full deploy with --force): %w", release, err)
+	}
+	d.log.Debug("Composer output:\n%s", strings.TrimSpace(output))
+
+	d.executeServicesReload(sshClient)
+
+	// There is no separate release to roll back to, so a failing check only fails the deploy
+	if err := d.performHealthCheck(nil, sshClient); err != nil {
+		return err
+	}
+
+	if err := d.recordLiveUpdate(sshClient, releaseDir, commitHash, cs); err != nil {
+		return err
+	}
+
+	d.log.Success("Dependencies updated in place in release %s", release)
+	return nil
+}
+
+// recordLiveUpdate records a change applied to the live release in place
+// (fast_dependency_update, in_place): deploy.lock is rewritten for the same release,
+// snapshotted into it and appended to the history
+func (d *Deployer) recordLiveUpdate(sshClient *ssh.Client, releaseDir, commitHash string, cs *changeset.ChangeSet) error {
+	newLock := d.newDeployLock(commitHash, filepath.Base(releaseDir), cs)
+	lockData, err := newLock.ToJSON()
+	if err != nil {
+		return err
+	}
+	lockPath := filepath.ToSlash(filepath.Join(d.env.RemotePath, "deploy.lock"))
+	if err := sshClient.WriteRemoteFileAtomic(lockPath, lockData); err != nil {
+		d.log.Error("Failed to upload deploy.lock: %v", err)
+	}
+	d.snapshotReleaseLock(sshClient, releaseDir, lockData)
+	d.recordHistory(sshClient, newLock)
+	return nil
+}
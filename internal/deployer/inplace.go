@@ -0,0 +1,152 @@
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/user/versaDeploy/internal/artifact"
+	"github.com/user/versaDeploy/internal/builder"
+	"github.com/user/versaDeploy/internal/changeset"
+	"github.com/user/versaDeploy/internal/ssh"
+	"github.com/user/versaDeploy/internal/state"
+)
+
+// defaultInPlaceMaxFiles is the largest changeset in_place applies when
+// in_place_max_files is not set
+const defaultInPlaceMaxFiles = 5
+
+// underPath reports whether f equals or is nested under one of paths
+func underPath(f string, paths []string) bool {
+	for _, p := range paths {
+		clean := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(p)), "/")
+		if f == clean || strings.HasPrefix(f, clean+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// copyExcluded reports whether f is left out of the artifact by copy_exclude: bare names
+// match any path segment, paths containing / match that path and everything under it
+func copyExcluded(f string, copyExclude []string) bool {
+	segments := strings.Split(f, "/")
+	for _, p := range copyExclude {
+		p = strings.Trim(filepath.ToSlash(p), "/")
+		if strings.Contains(p, "/") {
+			if underPath(f, []string{p}) {
+				return true
+			}
+			continue
+		}
+		for _, s := range segments {
+			if s == p {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inSkippedDir reports whether f sits under a directory the artifact copy skips at
+// any depth: .git and skip_dirs (builder.DefaultSkipDirs when unset)
+func (d *Deployer) inSkippedDir(f string) bool {
+	names := builder.DefaultSkipDirs
+	if d.env.SkipDirs != nil {
+		names = d.env.SkipDirs
+	}
+	segments := strings.Split(f, "/")
+	for _, dir := range segments[:len(segments)-1] {
+		if dir == ".git" {
+			return true
+		}
+		for _, name := range names {
+			if dir == strings.Trim(name, "/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inPlaceChanges returns the files to upload when cs can be applied to the live release in
+// place (in_place), or nil when it needs a full release. Only small changesets qualify:
+// at most in_place_max_files PHP or plain files, each already part of the previous deploy,
+// with nothing deleted and no dependency, route, template or compiled source change.
+// Files the artifact would not ship as-is (shared, secret, preserved, skipped or excluded paths,
+// or anything when artifact_prune is set) also force a full release.
+func (d *Deployer) inPlaceChanges(cs *changeset.ChangeSet, previousLock *state.DeployLock) []string {
+	if previousLock == nil || cs.Force || cs.RoutesChanged || cs.ComposerChanged || cs.PackageChanged || cs.GoModChanged || cs.RequirementsChanged {
+		return nil
+	}
+	if len(cs.TwigFiles)+len(cs.GoFiles)+len(cs.FrontendFiles)+len(cs.PythonFiles)+len(cs.DeletedFiles) > 0 || len(d.env.ArtifactPrune) > 0 {
+		return nil
+	}
+
+	limit := d.env.InPlaceMaxFiles
+	if limit == 0 {
+		limit = defaultInPlaceMaxFiles
+	}
+	files := append(append([]string{}, cs.PHPFiles...), cs.OtherFiles...)
+	if len(files) == 0 || len(files) > limit {
+		return nil
+	}
+
+	for _, f := range files {
+		// New files are left to a full release: an optimized autoloader would not know them
+		if _, ok := previousLock.GetFileHash(f); !ok {
+			return nil
+		}
+		if d.inSkippedDir(f) || underPath(f, d.env.SharedPaths) || underPath(f, d.env.SecretFiles) || underPath(f, d.env.PreservedPaths) ||
+			copyExcluded(f, d.env.CopyExclude) || artifact.MatchesExclude(d.env.ArtifactExclude, f) {
+			return nil
+		}
+	}
+	return files
+}
+
+// inPlaceUpdate uploads the changed files straight into the live release's app dir
+// instead of shipping a new release. Each file is replaced atomically, but the set is
+// not: requests can see a mix of old and new files while it runs, and a failure leaves
+// the release partly updated. The old files are overwritten, so the change cannot be
+// rolled back on its own; a rollback goes to the release before the patched one.
+func (d *Deployer) inPlaceUpdate(sshClient *ssh.Client, tmpRepo, commitHash string, files []string, cs *changeset.ChangeSet) error {
+	currentSymlink := filepath.ToSlash(filepath.Join(d.env.RemotePath, "current"))
+	currentTarget, err := sshClient.ReadSymlink(currentSymlink)
+	if err != nil {
+		return fmt.Errorf("in-place update: failed to read current symlink: %w", err)
+	}
+	releaseDir := currentTarget
+	if !strings.HasPrefix(releaseDir, "/") {
+		releaseDir = filepath.ToSlash(filepath.Join(d.env.RemotePath, currentTarget))
+	}
+	release := filepath.Base(releaseDir)
+
+	d.log.Warn("Updating %d file(s) in place in live release %s (in_place): not atomic and cannot be rolled back", len(files), release)
+	for _, f := range files {
+		data, err := os.ReadFile(filepath.Join(tmpRepo, f))
+		if err != nil {
+			return fmt.Errorf("in-place update: failed to read %s: %w", f, err)
+		}
+		remote := filepath.ToSlash(filepath.Join(releaseDir, "app", f))
+		d.log.Info("  Updating %s", f)
+		if err := sshClient.WriteRemoteFileAtomic(remote, data); err != nil {
+			return fmt.Errorf("in-place update failed in live release %s, which may now be partly updated (run a full deploy with --force): %w", release, err)
+		}
+	}
+
+	d.executeServicesReload(sshClient)
+
+	// There is no separate release to roll back to, so a failing check only fails the deploy
+	if err := d.performHealthCheck(nil, sshClient); err != nil {
+		return err
+	}
+
+	if err := d.recordLiveUpdate(sshClient, releaseDir, commitHash, cs); err != nil {
+		return err
+	}
+
+	d.log.Success("Updated %d file(s) in place in release %s", len(files), release)
+	return nil
+}
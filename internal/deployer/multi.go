@@ -0,0 +1,41 @@
+package deployer
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EnvResult is the outcome of one environment in RunConcurrent
+type EnvResult struct {
+	Env      string
+	Err      error
+	Duration time.Duration
+}
+
+// RunConcurrent runs deploy for every environment at the same time and returns one
+// result per environment, in the order given. With failFast, the context passed to
+// the remaining deploys is cancelled as soon as one fails; a Deployer given that
+// context via SetContext stops before switching its release live.
+func RunConcurrent(envs []string, failFast bool, deploy func(ctx context.Context, env string) error) []EnvResult {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make([]EnvResult, len(envs))
+	var wg sync.WaitGroup
+	for i, env := range envs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := deploy(ctx, env)
+			results[i] = EnvResult{Env: env, Err: err, Duration: time.Since(start)}
+			if err != nil && failFast {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,47 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunConcurrent(t *testing.T) {
+	results := RunConcurrent([]string{"staging", "canary"}, false, func(ctx context.Context, env string) error {
+		if env == "canary" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	if len(results) != 2 || results[0].Env != "staging" || results[1].Env != "canary" {
+		t.Fatalf("expected results in input order, got %+v", results)
+	}
+	if results[0].Err != nil || results[1].Err == nil {
+		t.Errorf("unexpected outcomes: %+v", results)
+	}
+}
+
+func TestRunConcurrent_FailFast(t *testing.T) {
+	deploy := func(ctx context.Context, env string) error {
+		if env == "broken" {
+			return errors.New("boom")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return nil
+		}
+	}
+
+	start := time.Now()
+	results := RunConcurrent([]string{"broken", "slow"}, true, deploy)
+	if time.Since(start) > 2*time.Second {
+		t.Fatal("expected fail-fast to cancel the remaining deploy")
+	}
+	if !errors.Is(results[1].Err, context.Canceled) {
+		t.Errorf("expected slow deploy to be cancelled, got %v", results[1].Err)
+	}
+}
@@ -0,0 +1,167 @@
+package deployer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/user/versaDeploy/internal/artifact"
+	"github.com/user/versaDeploy/internal/changeset"
+	verserrors "github.com/user/versaDeploy/internal/errors"
+	"github.com/user/versaDeploy/internal/ssh"
+	"github.com/user/versaDeploy/internal/state"
+)
+
+// promoteChunkSize matches the chunk size of a regular deploy's parallel upload
+const promoteChunkSize = 10 * 1024 * 1024
+
+// promoteExcludes returns the tar --exclude options that keep the source server's own
+// state out of a promoted release: its shared paths, secret file links, preserved
+// paths and deploy.lock snapshot. The target relinks and restores its own.
+func promoteExcludes(source *Deployer) []string {
+	var paths []string
+	for _, list := range [][]string{source.env.SharedPaths, source.env.SecretFiles, source.env.PreservedPaths} {
+		for _, p := range list {
+			clean := filepath.ToSlash(filepath.Clean(p))
+			if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+				continue
+			}
+			paths = append(paths, "./app/"+strings.TrimPrefix(clean, "/"))
+		}
+	}
+	paths = append(paths, "./deploy.lock")
+
+	excludes := make([]string, len(paths))
+	for i, p := range paths {
+		excludes[i] = "--exclude=" + ssh.ShellQuote(p)
+	}
+	return excludes
+}
+
+// Promote copies an existing release from the source environment to d's environment
+// through the local machine and activates it there with the regular pipeline (shared
+// paths, hooks, health check, deploy.lock), so both environments run the exact same
+// artifact. An empty release promotes the one the source's current points to.
+func (d *Deployer) Promote(source *Deployer, release string) error {
+	d.log.Info("Promoting from %s to %s...", source.envName, d.envName)
+
+	srcClient, err := ssh.NewClient(&source.env.SSH, source.log)
+	if err != nil {
+		return verserrors.Wrap(err)
+	}
+	defer srcClient.Close()
+
+	releasesDir := filepath.ToSlash(filepath.Join(source.env.RemotePath, "releases"))
+	releases, err := srcClient.ListReleases(releasesDir)
+	if err != nil {
+		return err
+	}
+	if release == "" {
+		currentTarget, err := srcClient.ReadSymlink(filepath.ToSlash(filepath.Join(source.env.RemotePath, "current")))
+		if err != nil {
+			return fmt.Errorf("failed to read current release of %s: %w", source.envName, err)
+		}
+		release = filepath.Base(currentTarget)
+	}
+	if !slices.Contains(releases, release) {
+		return fmt.Errorf("release %s not found on %s (available: %s)", release, source.envName, strings.Join(releases, ", "))
+	}
+	releaseDir := filepath.ToSlash(filepath.Join(releasesDir, release))
+
+	// The snapshot provides the commit and the file hashes for the target's deploy.lock
+	lockData, err := srcClient.ReadRemoteBytes(filepath.ToSlash(filepath.Join(releaseDir, "deploy.lock")), maxLockBytes)
+	if err != nil {
+		return fmt.Errorf("release %s on %s has no deploy.lock snapshot; deploy it again before promoting", release, source.envName)
+	}
+	lock, err := state.Parse(lockData)
+	if err != nil {
+		return fmt.Errorf("invalid deploy.lock snapshot in %s on %s: %w", release, source.envName, err)
+	}
+	d.log.Info("Release: %s (commit %s)", release, ShortHash(lock.LastDeploy.CommitHash))
+	if d.Message == "" {
+		d.Message = lock.LastDeploy.Message
+	}
+
+	if d.dryRun {
+		d.log.Info("DRY RUN - would copy releases/%s from %s to %s and activate it", release, source.envName, d.envName)
+		return nil
+	}
+
+	if err := d.checkTempDir(); err != nil {
+		return verserrors.Wrap(err)
+	}
+	localDir, err := os.MkdirTemp(d.tempDir(), fmt.Sprintf("versadeploy-promote-%s-*", d.envName))
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(localDir)
+
+	localArchive, err := d.fetchRelease(srcClient, source, release, localDir)
+	if err != nil {
+		return err
+	}
+
+	chunkPaths, err := artifact.SplitFile(localArchive, promoteChunkSize)
+	if err != nil {
+		return err
+	}
+	os.Remove(localArchive)
+
+	prebuilt := &PrebuiltArtifact{
+		ReleaseVersion: release,
+		CommitHash:     lock.LastDeploy.CommitHash,
+		ChunkPaths:     chunkPaths,
+		ChangeSet: &changeset.ChangeSet{
+			AllFileHashes:    lock.LastDeploy.FileHashes,
+			ComposerHash:     lock.LastDeploy.ComposerHash,
+			ComposerLockHash: lock.LastDeploy.ComposerLockHash,
+			PackageHash:      lock.LastDeploy.PackageJSONHash,
+			GoModHash:        lock.LastDeploy.GoModHash,
+			GoSumHash:        lock.LastDeploy.GoSumHash,
+			RequirementsHash: lock.LastDeploy.RequirementsHash,
+		},
+		artifactDir: localDir,
+		runID:       runSuffix(),
+	}
+	defer prebuilt.Cleanup()
+
+	return d.DeployWithArtifact(prebuilt)
+}
+
+// fetchRelease packs release on the source server and downloads the archive, plus the
+// release's file inventory, into localDir. It returns the local archive path.
+func (d *Deployer) fetchRelease(srcClient *ssh.Client, source *Deployer, release, localDir string) (string, error) {
+	releaseDir := filepath.ToSlash(filepath.Join(source.env.RemotePath, "releases", release))
+	remoteArchive := filepath.ToSlash(filepath.Join(source.env.RemotePath, release+".promote.tar.gz"))
+	defer srcClient.ExecuteCommand(fmt.Sprintf("rm -f -- %s", ssh.ShellQuote(remoteArchive)))
+
+	d.log.Info("Packing release %s on %s...", release, source.envName)
+	packCmd := fmt.Sprintf("tar -czf %s %s -C %s .", ssh.ShellQuote(remoteArchive), strings.Join(promoteExcludes(source), " "), ssh.ShellQuote(releaseDir))
+	if output, err := srcClient.ExecuteCommand(packCmd); err != nil {
+		return "", fmt.Errorf("failed to pack release %s on %s: %w\n%s", release, source.envName, err, strings.TrimSpace(output))
+	}
+
+	d.log.Info("Downloading release %s from %s...", release, source.envName)
+	localArchive := filepath.Join(localDir, release+".tar.gz")
+	if err := srcClient.DownloadFile(remoteArchive, localArchive); err != nil {
+		return "", err
+	}
+
+	// verify_files on the target checks against the release's own inventory
+	if data, err := srcClient.ReadRemoteBytes(filepath.ToSlash(filepath.Join(releaseDir, artifact.FileInventoryName)), maxLockBytes); err == nil {
+		if err := os.WriteFile(filepath.Join(localDir, artifact.FileInventoryName), data, 0644); err != nil {
+			return "", err
+		}
+	}
+	return localArchive, nil
+}
+
+// ShortHash returns the first 8 characters of a commit hash
+func ShortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	return hash
+}
@@ -0,0 +1,103 @@
+package deployer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/user/versaDeploy/internal/builder"
+	"github.com/user/versaDeploy/internal/ssh"
+	"github.com/user/versaDeploy/internal/state"
+)
+
+// uploadResume is written next to the local chunks before they are uploaded and removed
+// once the server has reassembled them. A deploy interrupted mid-upload leaves it behind,
+// and the next deploy with the same inputs reuses its release version, artifact and
+// chunks, so the chunk names match and the ones already on the server are skipped.
+type uploadResume struct {
+	ReleaseVersion string               `json:"release_version"`
+	Key            string               `json:"key"`
+	Chunks         []string             `json:"chunks"`
+	ArtifactDir    string               `json:"artifact_dir"` // built artifact the chunks were compressed from
+	BuildResult    *builder.BuildResult `json:"build_result"`
+}
+
+// resumePath is the local resume record for this environment
+func (d *Deployer) resumePath() string {
+	return filepath.Join(d.tempDir(), fmt.Sprintf("versadeploy-resume-%s.json", d.envName))
+}
+
+// resumeKey identifies the inputs of a build: the commit, the release it builds on and
+// the environment config. Chunks are only reused when all three are unchanged.
+func (d *Deployer) resumeKey(commitHash string, previousLock *state.DeployLock) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", d.envName, commitHash)
+	if previousLock != nil {
+		fmt.Fprintf(h, "%s\n", previousLock.LastDeploy.ReleaseDir)
+	}
+	if data, err := json.Marshal(d.env); err == nil {
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// loadUploadResume returns the resume record at path when it matches key and its
+// artifact and all of its chunks are still on disk, or nil.
+func loadUploadResume(path, key string) *uploadResume {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var r uploadResume
+	if err := json.Unmarshal(data, &r); err != nil || r.Key != key || r.ReleaseVersion == "" || len(r.Chunks) == 0 || r.BuildResult == nil {
+		return nil
+	}
+	if info, err := os.Stat(r.ArtifactDir); r.ArtifactDir == "" || err != nil || !info.IsDir() {
+		return nil
+	}
+	for _, p := range r.Chunks {
+		if _, err := os.Stat(p); err != nil {
+			return nil
+		}
+	}
+	return &r
+}
+
+// saveUploadResume writes the resume record for chunks about to be uploaded
+func saveUploadResume(path string, r *uploadResume) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// discardUploadResume removes a resume record that no longer applies, along with its
+// local artifact and chunks and whatever part of them reached remotePath.
+func (d *Deployer) discardUploadResume(sshClient *ssh.Client, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	os.Remove(path)
+	var r uploadResume
+	if err := json.Unmarshal(data, &r); err != nil || r.ReleaseVersion == "" {
+		return
+	}
+	d.log.Debug("Discarding interrupted upload of release %s", r.ReleaseVersion)
+	if r.ArtifactDir != "" {
+		os.RemoveAll(r.ArtifactDir)
+	}
+	for _, p := range r.Chunks {
+		os.Remove(p)
+	}
+	if len(r.Chunks) > 0 {
+		os.Remove(filepath.Dir(r.Chunks[0]))
+	}
+	remoteArchive := filepath.ToSlash(filepath.Join(d.env.RemotePath, r.ReleaseVersion+".tar.gz"))
+	partGlob := filepath.ToSlash(filepath.Join(d.env.RemotePath, "."+r.ReleaseVersion+".tar.gz"))
+	sshClient.ExecuteCommand(fmt.Sprintf("rm -f -- %s.* %s.*.part", ssh.ShellQuote(remoteArchive), ssh.ShellQuote(partGlob)))
+}
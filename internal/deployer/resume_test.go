@@ -0,0 +1,76 @@
+package deployer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/user/versaDeploy/internal/builder"
+	"github.com/user/versaDeploy/internal/config"
+	"github.com/user/versaDeploy/internal/state"
+)
+
+func TestUploadResume_ResumesFromEarlierRun(t *testing.T) {
+	d := &Deployer{env: &config.Environment{RemotePath: "/srv/app"}, envName: "prod", TempDir: t.TempDir()}
+	previous := &state.DeployLock{LastDeploy: state.DeployInfo{ReleaseDir: "20260101-000000"}}
+
+	// First run: artifact built, chunks written and recorded, then the upload is interrupted
+	artifactDir := filepath.Join(d.tempDir(), "versadeploy-artifact-prod-20260301-120000")
+	if err := os.MkdirAll(artifactDir, 0775); err != nil {
+		t.Fatal(err)
+	}
+	chunkDir := filepath.Join(d.tempDir(), "versadeploy-chunks-prod-20260301-120000")
+	if err := os.MkdirAll(chunkDir, 0775); err != nil {
+		t.Fatal(err)
+	}
+	var chunks []string
+	for _, name := range []string{"20260301-120000.tar.gz.000", "20260301-120000.tar.gz.001"} {
+		p := filepath.Join(chunkDir, name)
+		if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, p)
+	}
+	key := d.resumeKey("abc123", previous)
+	if err := saveUploadResume(d.resumePath(), &uploadResume{ReleaseVersion: "20260301-120000", Key: key, Chunks: chunks, ArtifactDir: artifactDir, BuildResult: &builder.BuildResult{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Second run with the same inputs picks up the release version, artifact and chunks
+	r := loadUploadResume(d.resumePath(), d.resumeKey("abc123", previous))
+	if r == nil {
+		t.Fatal("expected the interrupted upload to be resumed")
+	}
+	if r.ReleaseVersion != "20260301-120000" || len(r.Chunks) != 2 || r.Chunks[1] != chunks[1] || r.ArtifactDir != artifactDir || r.BuildResult == nil {
+		t.Errorf("unexpected resume record: %+v", r)
+	}
+
+	// Different commit, base release or config: not resumed
+	if loadUploadResume(d.resumePath(), d.resumeKey("def456", previous)) != nil {
+		t.Error("expected a different commit not to resume")
+	}
+	if loadUploadResume(d.resumePath(), d.resumeKey("abc123", nil)) != nil {
+		t.Error("expected a different base release not to resume")
+	}
+	d.env.NormalizeFileModes = true
+	if loadUploadResume(d.resumePath(), d.resumeKey("abc123", previous)) != nil {
+		t.Error("expected a config change not to resume")
+	}
+	d.env.NormalizeFileModes = false
+
+	// The artifact the chunks came from missing locally makes the record unusable, since
+	// the preflight checks and verify_files would otherwise run against a rebuild
+	os.RemoveAll(artifactDir)
+	if loadUploadResume(d.resumePath(), key) != nil {
+		t.Error("expected a missing artifact not to resume")
+	}
+	if err := os.MkdirAll(artifactDir, 0775); err != nil {
+		t.Fatal(err)
+	}
+
+	// A chunk missing locally makes the record unusable
+	os.Remove(chunks[0])
+	if loadUploadResume(d.resumePath(), key) != nil {
+		t.Error("expected a missing chunk not to resume")
+	}
+}
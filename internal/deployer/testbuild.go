@@ -0,0 +1,72 @@
+package deployer
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/user/versaDeploy/internal/fsutil"
+)
+
+// TestBuild runs the local half of a deploy as if it were the first one: full
+// change detection, build, manifest and structure validation, and compression.
+// It reports what was built and how big the artifact is, then removes it unless
+// keep is set. Nothing connects to the server, and pre_deploy_local hooks, which
+// may have side effects, only run with localHooks.
+func (d *Deployer) TestBuild(keep, localHooks bool) error {
+	d.skipLocalHooks = !localHooks
+	a, err := d.BuildArtifact()
+	if err != nil {
+		return err
+	}
+	if keep {
+		os.RemoveAll(a.tmpRepo)
+	} else {
+		defer a.Cleanup()
+	}
+
+	r := a.BuildResult
+	d.log.Success("Build OK: %s (commit %s)", a.ReleaseVersion, a.CommitHash[:8])
+	d.log.Info("  PHP files:      %d", r.PHPFilesChanged)
+	d.log.Info("  Go binary:      %s", yesNo(r.GoBinaryRebuilt))
+	d.log.Info("  Frontend files: %d", r.FrontendCompiled)
+	d.log.Info("  Python files:   %d", r.PythonFilesBuilt)
+	d.log.Info("  Composer:       %s", yesNo(r.ComposerUpdated))
+	d.log.Info("  npm:            %s", yesNo(r.NPMUpdated))
+	d.log.Info("  pip:            %s", yesNo(r.PipUpdated))
+
+	langs := make([]string, 0, len(r.Durations))
+	for lang := range r.Durations {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		d.log.Debug("  %s build took %s", lang, r.Durations[lang].Round(time.Millisecond))
+	}
+
+	if size, err := d.calculateDirectorySize(a.artifactDir); err == nil {
+		d.log.Info("Artifact size: %s", fsutil.HumanSize(size))
+	}
+	var archiveSize int64
+	for _, p := range a.ChunkPaths {
+		if info, err := os.Stat(p); err == nil {
+			archiveSize += info.Size()
+		}
+	}
+	d.log.Info("Compressed:    %s in %d chunk(s)", fsutil.HumanSize(archiveSize), len(a.ChunkPaths))
+
+	if keep {
+		d.log.Info("Artifact kept at %s", a.artifactDir)
+		for _, p := range a.ChunkPaths {
+			d.log.Info("  %s", p)
+		}
+	}
+	return nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
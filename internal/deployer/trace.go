@@ -0,0 +1,123 @@
+package deployer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stepTiming is how long one deploy step took
+type stepTiming struct {
+	Step     string
+	Duration time.Duration
+}
+
+// tracer times the consecutive steps of a deploy for --trace. Starting a step ends
+// the previous one. A nil tracer ignores every call, so untraced deploys pay nothing.
+type tracer struct {
+	steps   []stepTiming
+	current string
+	started time.Time
+	begin   time.Time
+}
+
+func newTracer() *tracer {
+	now := time.Now()
+	return &tracer{begin: now, started: now}
+}
+
+// step ends the running step and starts timing name
+func (t *tracer) step(name string) {
+	if t == nil {
+		return
+	}
+	t.end()
+	t.current, t.started = name, time.Now()
+}
+
+// end stops the running step, if any
+func (t *tracer) end() {
+	if t == nil || t.current == "" {
+		return
+	}
+	t.steps = append(t.steps, stepTiming{Step: t.current, Duration: time.Since(t.started)})
+	t.current = ""
+}
+
+// record adds steps measured elsewhere (e.g. each concurrent build)
+func (t *tracer) record(steps ...stepTiming) {
+	if t == nil {
+		return
+	}
+	t.steps = append(t.steps, steps...)
+}
+
+// total ends the running step and returns the time since the tracer started
+func (t *tracer) total() time.Duration {
+	t.end()
+	return time.Since(t.begin)
+}
+
+// report ends the running step and renders the breakdown, one step per line with
+// its share of the total
+func (t *tracer) report() string {
+	total := t.total()
+
+	width := len("total")
+	for _, s := range t.steps {
+		width = max(width, len(s.Step))
+	}
+
+	var sb strings.Builder
+	for _, s := range t.steps {
+		share := 0.0
+		if total > 0 {
+			share = float64(s.Duration) / float64(total) * 100
+		}
+		fmt.Fprintf(&sb, "  %-*s  %9s  %5.1f%%\n", width, s.Step, s.Duration.Round(time.Millisecond), share)
+	}
+	fmt.Fprintf(&sb, "  %-*s  %9s\n", width, "total", total.Round(time.Millisecond))
+	return sb.String()
+}
+
+// buildSteps returns per-language build durations as trace steps, sorted by name
+func buildSteps(durations map[string]time.Duration) []stepTiming {
+	var steps []stepTiming
+	for name, d := range durations {
+		steps = append(steps, stepTiming{Step: "  build: " + name, Duration: d})
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Step < steps[j].Step })
+	return steps
+}
+
+// timingsHeader is the first row of a timings_file
+var timingsHeader = []string{"timestamp", "environment", "release", "step", "duration"}
+
+// appendTimings appends one row per step plus a "total" row to the CSV at path,
+// writing the header first when the file is new. Durations are in seconds.
+func (t *tracer) appendTimings(path, envName, release string, at time.Time) error {
+	total := t.total()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		w.Write(timingsHeader)
+	}
+	timestamp := at.UTC().Format(time.RFC3339)
+	seconds := func(d time.Duration) string { return strconv.FormatFloat(d.Seconds(), 'f', 3, 64) }
+	for _, s := range t.steps {
+		w.Write([]string{timestamp, envName, release, strings.TrimSpace(s.Step), seconds(s.Duration)})
+	}
+	w.Write([]string{timestamp, envName, release, "total", seconds(total)})
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,106 @@
+package deployer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/user/versaDeploy/internal/ssh"
+	"github.com/user/versaDeploy/internal/state"
+)
+
+// warmResult is the outcome of requesting one warm_urls entry
+type warmResult struct {
+	URL      string
+	Status   int
+	Err      error
+	Duration time.Duration
+}
+
+// failed reports whether the request errored or returned a non-2xx/3xx status
+func (r warmResult) failed() bool {
+	return r.Err != nil || r.Status >= 400
+}
+
+// warmURLs requests every URL with at most concurrency requests in flight and returns
+// the results in the order of urls.
+func warmURLs(urls []string, concurrency int, timeout time.Duration) []warmResult {
+	client := &http.Client{Timeout: timeout}
+	results := make([]warmResult, len(urls))
+
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+	for i, u := range urls {
+		g.Go(func() error {
+			start := time.Now()
+			results[i] = warmResult{URL: u}
+			resp, err := client.Get(u)
+			if err != nil {
+				results[i].Err = err
+			} else {
+				// Read the body so the response is generated (and cached) in full
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				results[i].Status = resp.StatusCode
+			}
+			results[i].Duration = time.Since(start)
+			return nil
+		})
+	}
+	g.Wait()
+	return results
+}
+
+// warmCaches requests the environment's warm_urls after the health check. Failed URLs
+// are reported; with warm_fail_on_error they fail the deploy and roll back to the
+// previous release, like a failed health check.
+func (d *Deployer) warmCaches(previousLock *state.DeployLock, sshClient *ssh.Client) error {
+	if len(d.env.WarmURLs) == 0 {
+		return nil
+	}
+
+	concurrency := d.env.WarmConcurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	concurrency = min(concurrency, len(d.env.WarmURLs))
+	timeout := d.env.WarmTimeout
+	if timeout <= 0 {
+		timeout = 10
+	}
+
+	d.log.Info("Warming caches: %d URLs (%d at a time)...", len(d.env.WarmURLs), concurrency)
+	start := time.Now()
+	results := warmURLs(d.env.WarmURLs, concurrency, time.Duration(timeout)*time.Second)
+
+	var failures []string
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			d.log.Warn("  ✗ %s: %v", r.URL, r.Err)
+		case r.failed():
+			d.log.Warn("  ✗ %s: status %d (%s)", r.URL, r.Status, r.Duration.Round(time.Millisecond))
+		default:
+			d.log.Debug("  ✓ %s: status %d (%s)", r.URL, r.Status, r.Duration.Round(time.Millisecond))
+		}
+		if r.failed() {
+			failures = append(failures, r.URL)
+		}
+	}
+
+	if len(failures) == 0 {
+		d.log.Info("  ✓ Warmed %d URLs in %s", len(results), time.Since(start).Round(time.Millisecond))
+		return nil
+	}
+	d.log.Warn("Cache warming: %d of %d URLs failed", len(failures), len(results))
+	if !d.env.WarmFailOnError {
+		return nil
+	}
+
+	warmErr := fmt.Errorf("%d of %d warm_urls failed: %s", len(failures), len(results), strings.Join(failures, ", "))
+	return d.rollbackAfterFailure(sshClient, previousLock, "cache warming", warmErr)
+}
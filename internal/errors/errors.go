@@ -18,6 +18,9 @@ const (
 	CodeGitDirty         ErrorCode = "GIT_DIRTY"
 	CodeStateMissing     ErrorCode = "STATE_MISSING"
 	CodeUploadFailed     ErrorCode = "UPLOAD_FAILED"
+	CodeExtractFailed    ErrorCode = "EXTRACT_FAILED"
+	CodeDiskSpace        ErrorCode = "DISK_SPACE"
+	CodeHookTimeout      ErrorCode = "HOOK_TIMEOUT"
 	CodeDeploymentFailed ErrorCode = "DEPLOYMENT_FAILED"
 	CodeUnknown          ErrorCode = "UNKNOWN"
 )
@@ -39,6 +42,28 @@ func (e *VersaError) Error() string {
 
 func (e *VersaError) Unwrap() error { return e.WrappedErr }
 
+// Is makes errors.Is match any VersaError with the same code, so the sentinels below
+// identify a class of failure: errors.Is(err, verserrors.ErrUploadFailed).
+func (e *VersaError) Is(target error) bool {
+	t, ok := target.(*VersaError)
+	return ok && t.Code == e.Code
+}
+
+// Sentinels for matching errors by code with errors.Is
+var (
+	ErrConfigInvalid    = &VersaError{Code: CodeConfigInvalid, Message: "configuration invalid"}
+	ErrSSHAuthFailed    = &VersaError{Code: CodeSSHAuthFailed, Message: "SSH authentication failed"}
+	ErrSSHConnectFailed = &VersaError{Code: CodeSSHConnectFailed, Message: "SSH connection failed"}
+	ErrBuildFailed      = &VersaError{Code: CodeBuildFailed, Message: "build failed"}
+	ErrGitDirty         = &VersaError{Code: CodeGitDirty, Message: "working directory not clean"}
+	ErrStateMissing     = &VersaError{Code: CodeStateMissing, Message: "deploy.lock missing"}
+	ErrUploadFailed     = &VersaError{Code: CodeUploadFailed, Message: "upload failed"}
+	ErrExtractFailed    = &VersaError{Code: CodeExtractFailed, Message: "extraction failed"}
+	ErrDiskSpace        = &VersaError{Code: CodeDiskSpace, Message: "not enough disk space"}
+	ErrHookTimeout      = &VersaError{Code: CodeHookTimeout, Message: "remote command timed out"}
+	ErrDeploymentFailed = &VersaError{Code: CodeDeploymentFailed, Message: "deployment failed"}
+)
+
 // New creates a new VersaError
 func New(code ErrorCode, msg, suggestion string, err error) *VersaError {
 	return &VersaError{
@@ -49,14 +74,20 @@ func New(code ErrorCode, msg, suggestion string, err error) *VersaError {
 	}
 }
 
-// FormatError pretty-prints the error with suggestions
+// FormatError pretty-prints the error with suggestions. A *VersaError anywhere in the
+// chain is formatted, with the full error as details when it was wrapped with context.
 func FormatError(err error) string {
-	if vErr, ok := err.(*VersaError); ok {
+	var vErr *VersaError
+	if errors.As(err, &vErr) {
+		var details error = vErr.WrappedErr
+		if err != error(vErr) {
+			details = err
+		}
 		var sb strings.Builder
 		sb.WriteString(fmt.Sprintf("\n\x1b[31m[ERROR] %s\x1b[0m\n", vErr.Message))
 		sb.WriteString(fmt.Sprintf("\x1b[33mCode:\x1b[0m %s\n", vErr.Code))
-		if vErr.WrappedErr != nil {
-			sb.WriteString(fmt.Sprintf("\x1b[33mDetails:\x1b[0m %v\n", vErr.WrappedErr))
+		if details != nil {
+			sb.WriteString(fmt.Sprintf("\x1b[33mDetails:\x1b[0m %v\n", details))
 		}
 		if vErr.Suggestion != "" {
 			sb.WriteString(fmt.Sprintf("\n\x1b[32mSuggestion:\x1b[0m %s\n", vErr.Suggestion))
@@ -66,11 +97,16 @@ func FormatError(err error) string {
 	return fmt.Sprintf("\x1b[31m[ERROR]\x1b[0m %v", err)
 }
 
-// Wrap maps common Go errors to VersaErrors
+// Wrap maps common Go errors to VersaErrors. Errors that already carry a *VersaError
+// (directly or wrapped with %w) are returned unchanged, keeping their code.
 func Wrap(err error) error {
 	if err == nil {
 		return nil
 	}
+	var vErr *VersaError
+	if errors.As(err, &vErr) {
+		return err
+	}
 
 	// Network errors: prefer type assertions (O(1), robust to message changes),
 	// with string-matching fallback for errors that don't carry *net.OpError.
@@ -99,6 +135,34 @@ func Wrap(err error) error {
 		return New(CodeSSHAuthFailed, "SSH Authentication failed", "Check your SSH private key path and ensure it's added to the remote server's authorized_keys.", err)
 	}
 
+	// Remote disk space (pre-upload check)
+	if strings.Contains(errMsg, "insufficient disk space") {
+		return New(CodeDiskSpace, "Not enough disk space on the remote server", "Free space on the server (e.g. 'versa prune' to remove old releases), or use --skip-disk-check if df misreports on this filesystem.", err)
+	}
+	if strings.Contains(errMsg, "insufficient inodes") {
+		return New(CodeDiskSpace, "Not enough free inodes on the remote server", "Remove old releases ('versa prune') or stale cache files, or set inode_check: warn to deploy anyway.", err)
+	}
+
+	// Upload errors (SFTP)
+	if strings.Contains(errMsg, "parallel upload failed") || strings.Contains(errMsg, "failed to upload") {
+		return New(CodeUploadFailed, "Artifact upload failed", "Check the connection to the server and that the SSH user can write to remote_path. Lower --concurrency if the server limits SFTP sessions.", err)
+	}
+
+	// Extraction errors (reassembly and tar on the server)
+	if strings.Contains(errMsg, "failed to reassemble artifact") || strings.Contains(errMsg, "failed to extract archive") {
+		return New(CodeExtractFailed, "Extracting the artifact on the server failed", "Check free space and inodes on the server (df -h, df -i) and that tar and gzip are installed. A corrupt archive from an interrupted upload is fixed by deploying again.", err)
+	}
+
+	// Symlink switch errors
+	if strings.Contains(errMsg, "failed to create symlink") || strings.Contains(errMsg, "symlink verification failed") {
+		return New(CodeDeploymentFailed, "Switching the current symlink failed", "The previous release is still live. Check that the SSH user owns remote_path and that 'current' is a symlink, not a directory.", err)
+	}
+
+	// Remote command (hook) timeouts
+	if strings.Contains(errMsg, "command timed out after") {
+		return New(CodeHookTimeout, "Remote command timed out", "Raise hook_timeout in deploy.yml, or make the command faster (e.g. queue long jobs instead of running them in the hook).", err)
+	}
+
 	// Git common errors
 	if strings.Contains(errMsg, "uncommitted changes") {
 		return New(CodeGitDirty, "Working directory is not clean", "Commit or stash your changes before deploying to ensure a reproducible build.", err)
@@ -2,6 +2,7 @@ package verserrors
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -84,6 +85,41 @@ func TestWrap(t *testing.T) {
 			input:    errors.New("config validation failed"),
 			wantCode: CodeConfigInvalid,
 		},
+		{
+			name:     "Disk Space",
+			input:    errors.New("disk space check failed: insufficient disk space: need 120 MB, have 80 MB available"),
+			wantCode: CodeDiskSpace,
+		},
+		{
+			name:     "Inodes",
+			input:    errors.New("insufficient inodes: need 60000, have 1200 free"),
+			wantCode: CodeDiskSpace,
+		},
+		{
+			name:     "Upload Failed",
+			input:    errors.New("parallel upload failed: failed to upload 20260101_120000.tar.gz.000 after 3 attempts: connection lost"),
+			wantCode: CodeUploadFailed,
+		},
+		{
+			name:     "Extract Failed",
+			input:    errors.New("failed to extract archive: Process exited with status 2 (output: gzip: stdin: unexpected end of file)"),
+			wantCode: CodeExtractFailed,
+		},
+		{
+			name:     "Reassemble Failed",
+			input:    errors.New("failed to reassemble artifact on server: No space left on device"),
+			wantCode: CodeExtractFailed,
+		},
+		{
+			name:     "Symlink Switch Failed",
+			input:    errors.New("failed to create symlink: mv: cannot overwrite directory"),
+			wantCode: CodeDeploymentFailed,
+		},
+		{
+			name:     "Hook Timeout",
+			input:    fmt.Errorf("post_deploy hook %q failed: %w", "php artisan migrate", errors.New("command timed out after 5m0s")),
+			wantCode: CodeHookTimeout,
+		},
 		{
 			name:     "Unknown error",
 			input:    errors.New("just a random error"),
@@ -118,3 +154,53 @@ func TestWrap(t *testing.T) {
 		})
 	}
 }
+
+func TestWrap_KeepsExistingCode(t *testing.T) {
+	// The message would match the CodeGitDirty pattern; the existing code must win
+	vErr := New(CodeBuildFailed, "build failed on uncommitted changes", "", nil)
+	if got := Wrap(vErr); got != error(vErr) {
+		t.Errorf("Wrap(VersaError) = %v, want it unchanged", got)
+	}
+
+	nested := fmt.Errorf("deploy to prod: %w", vErr)
+	if got := Wrap(nested); got != nested {
+		t.Errorf("Wrap(nested VersaError) = %v, want it unchanged", got)
+	}
+}
+
+func TestFormatError_NestedVersaError(t *testing.T) {
+	vErr := New(CodeUploadFailed, "Artifact upload failed", "check the network", errors.New("connection lost"))
+	formatted := FormatError(fmt.Errorf("deploy to prod: %w", vErr))
+
+	for _, want := range []string{"Artifact upload failed", string(CodeUploadFailed), "check the network", "deploy to prod", "connection lost"} {
+		if !strings.Contains(formatted, want) {
+			t.Errorf("expected formatted error to contain %q, got:\n%s", want, formatted)
+		}
+	}
+}
+
+func TestVersaError_IsAs(t *testing.T) {
+	cause := errors.New("connection lost")
+	vErr := New(CodeUploadFailed, "Artifact upload failed", "", cause)
+	chain := fmt.Errorf("deploy to prod: %w", vErr)
+
+	if !errors.Is(chain, cause) {
+		t.Error("errors.Is should reach the wrapped cause")
+	}
+	if !errors.Is(chain, ErrUploadFailed) {
+		t.Error("errors.Is should match the sentinel for the same code")
+	}
+	if errors.Is(chain, ErrBuildFailed) {
+		t.Error("errors.Is should not match a sentinel for another code")
+	}
+
+	var got *VersaError
+	if !errors.As(chain, &got) || got != vErr {
+		t.Errorf("errors.As = %v, want %v", got, vErr)
+	}
+
+	// Classified by Wrap
+	if !errors.Is(Wrap(errors.New("failed to extract archive: exit 2")), ErrExtractFailed) {
+		t.Error("expected a wrapped extraction error to match ErrExtractFailed")
+	}
+}
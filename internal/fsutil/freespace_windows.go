@@ -0,0 +1,18 @@
+//go:build windows
+
+package fsutil
+
+import "golang.org/x/sys/windows"
+
+// FreeSpace returns the bytes available to the current user on the volume holding path
+func FreeSpace(path string) (int64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var available uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &available, nil, nil); err != nil {
+		return 0, err
+	}
+	return int64(available), nil
+}
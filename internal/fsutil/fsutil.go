@@ -1,8 +1,11 @@
 package fsutil
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 // CalculateDirSize calculates the total size of all files in a directory recursively.
@@ -19,3 +22,110 @@ func CalculateDirSize(path string) (int64, error) {
 	})
 	return size, err
 }
+
+// CountEntries counts the files and directories under root (root excluded), which is
+// roughly how many inodes extracting a copy of it takes.
+func CountEntries(root string) (int64, error) {
+	var n int64
+	err := filepath.Walk(root, func(path string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root {
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// SizeEntry is a path (relative to the walked root) and its size in bytes
+type SizeEntry struct {
+	Path string
+	Size int64
+}
+
+// LargestFiles returns the n largest regular files under root, largest first.
+func LargestFiles(root string, n int) ([]SizeEntry, error) {
+	var files []SizeEntry
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, SizeEntry{Path: filepath.ToSlash(rel), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return topN(files, n), nil
+}
+
+// LargestDirs returns the n largest directories exactly depth levels below root
+// (e.g. depth 2 under an artifact gives app/vendor, app/node_modules), largest first.
+// Sizes include everything nested inside each directory.
+func LargestDirs(root string, depth, n int) ([]SizeEntry, error) {
+	totals := make(map[string]int64)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) <= depth {
+			return nil // file sits above the requested depth
+		}
+		totals[strings.Join(parts[:depth], "/")] += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]SizeEntry, 0, len(totals))
+	for path, size := range totals {
+		dirs = append(dirs, SizeEntry{Path: path, Size: size})
+	}
+	return topN(dirs, n), nil
+}
+
+// topN sorts entries by size (descending, then path) and keeps the first n
+func topN(entries []SizeEntry, n int) []SizeEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Size != entries[j].Size {
+			return entries[i].Size > entries[j].Size
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// HumanSize formats a byte count as B, KB, MB, GB...
+func HumanSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,85 @@
+package fsutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSized(t *testing.T, root, rel string, size int) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(rel))
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLargestFilesAndDirs(t *testing.T) {
+	root := t.TempDir()
+	writeSized(t, root, "manifest.json", 10)
+	writeSized(t, root, "app/public/video.mp4", 5000)
+	writeSized(t, root, "app/node_modules/a/index.js", 300)
+	writeSized(t, root, "app/node_modules/b/index.js", 400)
+	writeSized(t, root, "app/index.php", 50)
+
+	files, err := LargestFiles(root, 2)
+	if err != nil {
+		t.Fatalf("LargestFiles() error = %v", err)
+	}
+	if len(files) != 2 || files[0].Path != "app/public/video.mp4" || files[1].Path != "app/node_modules/b/index.js" {
+		t.Errorf("unexpected largest files: %+v", files)
+	}
+
+	dirs, err := LargestDirs(root, 2, 5)
+	if err != nil {
+		t.Fatalf("LargestDirs() error = %v", err)
+	}
+	if len(dirs) != 2 || dirs[0].Path != "app/public" || dirs[1].Path != "app/node_modules" || dirs[1].Size != 700 {
+		t.Errorf("unexpected largest dirs: %+v", dirs)
+	}
+}
+
+func TestCountEntries(t *testing.T) {
+	root := t.TempDir()
+	writeSized(t, root, "manifest.json", 1)
+	writeSized(t, root, "app/vendor/a/x.php", 1)
+	writeSized(t, root, "app/vendor/a/y.php", 1)
+
+	// manifest.json, app, app/vendor, app/vendor/a and two files
+	if n, err := CountEntries(root); err != nil || n != 6 {
+		t.Errorf("CountEntries() = %d, %v, want 6", n, err)
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := map[int64]string{
+		512:                    "512B",
+		2048:                   "2.0KB",
+		5 * 1024 * 1024:        "5.0MB",
+		3 * 1024 * 1024 * 1024: "3.0GB",
+	}
+	for in, want := range tests {
+		if got := HumanSize(in); got != want {
+			t.Errorf("HumanSize(%d) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	available, err := FreeSpace(t.TempDir())
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("FreeSpace not supported on this platform")
+	}
+	if err != nil {
+		t.Fatalf("FreeSpace() error = %v", err)
+	}
+	if available <= 0 {
+		t.Errorf("expected some free space, got %d", available)
+	}
+	if _, err := FreeSpace(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("expected error for a missing path")
+	}
+}
@@ -2,17 +2,35 @@ package git
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
+// CloneOptions tunes CloneIn
+type CloneOptions struct {
+	Ref        string // Branch, tag or commit to check out; empty keeps the repository's HEAD
+	Submodules bool   // Also clone submodules of the checked-out ref (recursively)
+	Depth      int    // Shallow clone with this many commits of history; 0 clones everything
+}
+
 // Clone creates a clean clone of the repository in a temporary directory
 func Clone(repoPath, ref string) (string, error) {
+	return CloneIn("", repoPath, CloneOptions{Ref: ref})
+}
+
+// CloneIn is Clone with the temporary directory created under baseDir and extra
+// options. An empty baseDir uses the system temp directory.
+func CloneIn(baseDir, repoPath string, opts CloneOptions) (string, error) {
+	ref, submodules := opts.Ref, opts.Submodules
+
 	// Create temporary directory
-	tmpDir, err := os.MkdirTemp("", "versadeploy-*")
+	tmpDir, err := os.MkdirTemp(baseDir, "versadeploy-*")
 	if err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
@@ -25,7 +43,26 @@ func Clone(repoPath, ref string) (string, error) {
 	}
 
 	// Clone the repository
-	if _, err := executeGitInternal(repoPath, "clone", absRepoPath, tmpDir); err != nil {
+	source := absRepoPath
+	cloneArgs := []string{"clone"}
+	if opts.Depth > 0 {
+		// git ignores --depth for plain local paths; a file:// URL goes through the
+		// regular transport (copying objects instead of hardlinking them)
+		source = fileURL(absRepoPath)
+		cloneArgs = append(cloneArgs, "--depth", strconv.Itoa(opts.Depth))
+		if ref != "" {
+			// Only the fetched history is available, so fetch the ref itself
+			cloneArgs = append(cloneArgs, "--branch", ref)
+			ref = ""
+		}
+		if submodules {
+			cloneArgs = append(cloneArgs, "--shallow-submodules")
+		}
+	}
+	if submodules {
+		cloneArgs = append(cloneArgs, "--recurse-submodules")
+	}
+	if _, err := executeGitInternal(repoPath, append(cloneArgs, source, tmpDir)...); err != nil {
 		os.RemoveAll(tmpDir)
 		return "", fmt.Errorf("git clone failed: %w", err)
 	}
@@ -36,11 +73,164 @@ func Clone(repoPath, ref string) (string, error) {
 			os.RemoveAll(tmpDir)
 			return "", fmt.Errorf("git checkout %s failed: %w", ref, err)
 		}
+		// The ref may pin different submodule commits than the default branch
+		if submodules {
+			if _, err := executeGitInternal(tmpDir, "submodule", "update", "--init", "--recursive"); err != nil {
+				os.RemoveAll(tmpDir)
+				return "", fmt.Errorf("git submodule update failed: %w", err)
+			}
+		}
 	}
 
 	return tmpDir, nil
 }
 
+// OverlayWorkingTree copies the uncommitted state of repoPath's working tree over
+// cloneDir, a clone of it: modified and untracked (non-ignored) files are copied and
+// files deleted in the working tree are removed. The clone's HEAD is still the last
+// commit, so the result does not correspond to any commit. Paths in exclude are
+// never copied.
+func OverlayWorkingTree(cloneDir, repoPath string, exclude ...string) error {
+	// Staged and unstaged changes against HEAD, then untracked files
+	changed, err := listPaths(repoPath, append([]string{"diff", "--name-only", "--no-renames", "-z", "HEAD"}, excludePathspec(exclude)...)...)
+	if err != nil {
+		return err
+	}
+	untracked, err := listPaths(repoPath, append([]string{"ls-files", "-z", "--others", "--exclude-standard"}, excludePathspec(exclude)...)...)
+	if err != nil {
+		return err
+	}
+	for _, rel := range append(changed, untracked...) {
+		if err := overlayFile(filepath.Join(repoPath, rel), filepath.Join(cloneDir, rel)); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// excludePathspec builds the pathspec arguments limiting a git command to the
+// working tree minus the given paths, or none when there is nothing to exclude
+func excludePathspec(exclude []string) []string {
+	if len(exclude) == 0 {
+		return nil
+	}
+	args := []string{"--", "."}
+	for _, p := range exclude {
+		args = append(args, ":(exclude)"+filepath.ToSlash(p))
+	}
+	return args
+}
+
+// listPaths runs a git command printing NUL-separated paths and returns them
+func listPaths(repoPath string, args ...string) ([]string, error) {
+	output, err := executeGitInternal(repoPath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list working tree files: %w", err)
+	}
+	var files []string
+	for _, f := range strings.Split(output, "\x00") {
+		if f != "" {
+			files = append(files, filepath.FromSlash(f))
+		}
+	}
+	return files, nil
+}
+
+// overlayFile replaces dst with src, keeping symlinks as links and the file mode.
+// A missing src (deleted in the working tree) removes dst.
+func overlayFile(src, dst string) error {
+	info, err := os.Lstat(src)
+	if os.IsNotExist(err) {
+		return os.RemoveAll(dst)
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil // Submodule checkouts show up as directories
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0775); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dst)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// lfsFilter matches a .gitattributes line routing files through the Git LFS filter
+var lfsFilter = regexp.MustCompile(`(^|\s)filter=lfs(\s|$)`)
+
+// UsesLFS reports whether the repository's root .gitattributes tracks files with Git LFS
+func UsesLFS(repoPath string) bool {
+	data, err := os.ReadFile(filepath.Join(repoPath, ".gitattributes"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") && lfsFilter.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// LFSAvailable returns an error unless the git-lfs extension is installed
+func LFSAvailable() error {
+	if _, err := executeGitInternal(".", "lfs", "version"); err != nil {
+		return fmt.Errorf("git-lfs is not installed: %w", err)
+	}
+	return nil
+}
+
+// LFSPull replaces the Git LFS pointer files of a clone with their real content
+func LFSPull(repoPath string) error {
+	if _, err := executeGitInternal(repoPath, "lfs", "pull"); err != nil {
+		return fmt.Errorf("git lfs pull failed: %w", err)
+	}
+	return nil
+}
+
+// fileURL returns the file:// URL of an absolute local path
+func fileURL(absPath string) string {
+	p := filepath.ToSlash(absPath)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p // Windows drive paths: file:///C:/repo
+	}
+	return "file://" + p
+}
+
+// HasSubmodules reports whether the repository declares git submodules (.gitmodules)
+func HasSubmodules(repoPath string) bool {
+	_, err := os.Stat(filepath.Join(repoPath, ".gitmodules"))
+	return err == nil
+}
+
 // GetCurrentCommit returns the current commit hash
 func GetCurrentCommit(repoPath string) (string, error) {
 	output, err := executeGitInternal(repoPath, "rev-parse", "HEAD")
@@ -50,9 +240,24 @@ func GetCurrentCommit(repoPath string) (string, error) {
 	return strings.TrimSpace(output), nil
 }
 
-// IsClean checks if the working directory has uncommitted changes
-func IsClean(repoPath string) (bool, error) {
-	output, err := executeGitInternal(repoPath, "status", "--porcelain")
+// CountCommits returns the number of commits reachable from to but not from from
+// (git rev-list --count from..to). Both commits must exist in the repository.
+func CountCommits(repoPath, from, to string) (int, error) {
+	output, err := executeGitInternal(repoPath, "rev-list", "--count", from+".."+to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count commits: %w", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected git rev-list output %q", strings.TrimSpace(output))
+	}
+	return n, nil
+}
+
+// IsClean checks if the working directory has uncommitted changes. Paths in exclude
+// (relative to repoPath) don't count, e.g. files versa itself writes into the repo.
+func IsClean(repoPath string, exclude ...string) (bool, error) {
+	output, err := executeGitInternal(repoPath, append([]string{"status", "--porcelain"}, excludePathspec(exclude)...)...)
 	if err != nil {
 		return false, fmt.Errorf("failed to check git status: %w", err)
 	}
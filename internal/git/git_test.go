@@ -4,6 +4,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -75,6 +77,16 @@ func TestIsClean(t *testing.T) {
 	if clean {
 		t.Error("expected repo to be dirty after creating new file")
 	}
+
+	os.MkdirAll(filepath.Join(repoDir, ".versa-tmp", "clone"), 0755)
+	os.WriteFile(filepath.Join(repoDir, ".versa-tmp", "clone", "f"), []byte("x"), 0644)
+	clean, err = IsClean(repoDir, "dirty.txt", ".versa-tmp")
+	if err != nil {
+		t.Fatalf("IsClean() error = %v", err)
+	}
+	if !clean {
+		t.Error("expected excluded paths not to make the repo dirty")
+	}
 }
 
 func TestClone(t *testing.T) {
@@ -115,6 +127,66 @@ func TestClone_WithRef(t *testing.T) {
 	}
 }
 
+func TestCloneIn_Depth(t *testing.T) {
+	repoDir := setupGitRepo(t)
+	gitPath := resolveGitPath()
+	os.WriteFile(filepath.Join(repoDir, "second.txt"), []byte("second"), 0644)
+	exec.Command(gitPath, "-C", repoDir, "add", "second.txt").Run()
+	exec.Command(gitPath, "-C", repoDir, "commit", "-m", "second commit").Run()
+
+	tmpDir, err := CloneIn(t.TempDir(), repoDir, CloneOptions{Depth: 1})
+	if err != nil {
+		t.Fatalf("CloneIn(depth 1) error = %v", err)
+	}
+
+	out, err := exec.Command(gitPath, "-C", tmpDir, "rev-list", "--count", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != "1" {
+		t.Errorf("shallow clone has %s commits, want 1", got)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "second.txt")); err != nil {
+		t.Errorf("shallow clone is missing second.txt: %v", err)
+	}
+
+	want, _ := GetCurrentCommit(repoDir)
+	if got, _ := GetCurrentCommit(tmpDir); got != want {
+		t.Errorf("shallow clone HEAD = %s, want %s", got, want)
+	}
+}
+
+func TestCountCommits(t *testing.T) {
+	repoDir := setupGitRepo(t)
+	gitPath := resolveGitPath()
+	first, _ := GetCurrentCommit(repoDir)
+	for _, name := range []string{"second.txt", "third.txt"} {
+		os.WriteFile(filepath.Join(repoDir, name), []byte(name), 0644)
+		exec.Command(gitPath, "-C", repoDir, "add", name).Run()
+		exec.Command(gitPath, "-C", repoDir, "commit", "-m", name).Run()
+	}
+	last, _ := GetCurrentCommit(repoDir)
+
+	if n, err := CountCommits(repoDir, first, last); err != nil || n != 2 {
+		t.Errorf("CountCommits() = %d, %v, want 2", n, err)
+	}
+	if n, err := CountCommits(repoDir, last, first); err != nil || n != 0 {
+		t.Errorf("CountCommits() backwards = %d, %v, want 0", n, err)
+	}
+	if _, err := CountCommits(repoDir, first, strings.Repeat("0", 40)); err == nil {
+		t.Error("expected error for an unknown commit")
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	if got := fileURL("/srv/repo"); got != "file:///srv/repo" {
+		t.Errorf("fileURL(/srv/repo) = %q", got)
+	}
+	if got := fileURL(`C:\repo`); runtime.GOOS == "windows" && got != "file:///C:/repo" {
+		t.Errorf("fileURL(C:\\repo) = %q", got)
+	}
+}
+
 func TestClone_Fail(t *testing.T) {
 	_, err := Clone("/invalid/path", "")
 	if err == nil {
@@ -128,3 +200,109 @@ func TestGetCurrentCommit_Fail(t *testing.T) {
 		t.Error("expected error for invalid repo path")
 	}
 }
+
+func TestOverlayWorkingTree(t *testing.T) {
+	repoDir := setupGitRepo(t)
+	gitPath := resolveGitPath()
+
+	os.WriteFile(filepath.Join(repoDir, "gone.txt"), []byte("gone"), 0644)
+	exec.Command(gitPath, "-C", repoDir, "add", "gone.txt").Run()
+	exec.Command(gitPath, "-C", repoDir, "commit", "-m", "add gone.txt").Run()
+
+	os.WriteFile(filepath.Join(repoDir, ".gitignore"), []byte("ignored.txt\n"), 0644)
+	os.WriteFile(filepath.Join(repoDir, "file.txt"), []byte("modified"), 0644)
+	os.MkdirAll(filepath.Join(repoDir, "gen"), 0755)
+	os.WriteFile(filepath.Join(repoDir, "gen", "untracked.txt"), []byte("generated"), 0644)
+	os.WriteFile(filepath.Join(repoDir, "staged.txt"), []byte("staged"), 0644)
+	exec.Command(gitPath, "-C", repoDir, "add", "staged.txt").Run()
+	os.WriteFile(filepath.Join(repoDir, "ignored.txt"), []byte("ignored"), 0644)
+	os.Remove(filepath.Join(repoDir, "gone.txt"))
+	os.MkdirAll(filepath.Join(repoDir, ".versa-tmp"), 0755)
+	os.WriteFile(filepath.Join(repoDir, ".versa-tmp", "scratch"), []byte("scratch"), 0644)
+
+	tmpDir, err := CloneIn(t.TempDir(), repoDir, CloneOptions{})
+	if err != nil {
+		t.Fatalf("CloneIn() error = %v", err)
+	}
+	if err := OverlayWorkingTree(tmpDir, repoDir, ".versa-tmp"); err != nil {
+		t.Fatalf("OverlayWorkingTree() error = %v", err)
+	}
+
+	for name, want := range map[string]string{"file.txt": "modified", "gen/untracked.txt": "generated", "staged.txt": "staged"} {
+		got, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"ignored.txt", "gone.txt", ".versa-tmp"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be absent from the copy", name)
+		}
+	}
+
+	// HEAD is still the last commit
+	want, _ := GetCurrentCommit(repoDir)
+	if got, err := GetCurrentCommit(tmpDir); err != nil || got != want {
+		t.Errorf("GetCurrentCommit() = %q, %v; want %q", got, err, want)
+	}
+}
+
+func TestCloneIn_Submodules(t *testing.T) {
+	// Local submodule URLs need the file transport, which git disables by default
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	libDir := setupGitRepo(t)
+	repoDir := setupGitRepo(t)
+	gitPath := resolveGitPath()
+	if out, err := exec.Command(gitPath, "-C", repoDir, "submodule", "add", libDir, "lib").CombinedOutput(); err != nil {
+		t.Fatalf("submodule add: %v\n%s", err, out)
+	}
+	exec.Command(gitPath, "-C", repoDir, "commit", "-m", "add submodule").Run()
+
+	if !HasSubmodules(repoDir) {
+		t.Error("HasSubmodules() = false, want true")
+	}
+	if HasSubmodules(libDir) {
+		t.Error("HasSubmodules() = true for a repo without .gitmodules")
+	}
+
+	plain, err := CloneIn(t.TempDir(), repoDir, CloneOptions{})
+	if err != nil {
+		t.Fatalf("CloneIn() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(plain, "lib", "file.txt")); !os.IsNotExist(err) {
+		t.Error("expected an empty submodule directory without submodules")
+	}
+
+	withSubs, err := CloneIn(t.TempDir(), repoDir, CloneOptions{Submodules: true})
+	if err != nil {
+		t.Fatalf("CloneIn(submodules) error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(withSubs, "lib", "file.txt")); err != nil {
+		t.Errorf("expected submodule file to be cloned: %v", err)
+	}
+}
+
+func TestUsesLFS(t *testing.T) {
+	tests := []struct {
+		attributes string
+		want       bool
+	}{
+		{"", false},
+		{"*.psd filter=lfs diff=lfs merge=lfs -text\n", true},
+		{"*.txt text eol=lf\n", false},
+		{"# *.psd filter=lfs diff=lfs merge=lfs -text\n", false},
+		{"*.txt text\nassets/** filter=lfs -text\n", true},
+	}
+	for _, tt := range tests {
+		repoDir := t.TempDir()
+		if tt.attributes != "" {
+			os.WriteFile(filepath.Join(repoDir, ".gitattributes"), []byte(tt.attributes), 0644)
+		}
+		if got := UsesLFS(repoDir); got != tt.want {
+			t.Errorf("UsesLFS(%q) = %v, want %v", tt.attributes, got, tt.want)
+		}
+	}
+}
@@ -34,6 +34,8 @@ type Logger struct {
 	extraWriter io.Writer // additional writer (used by TUI for streaming)
 	verbose     bool
 	debug       bool
+	prefix      string  // prepended to every message (see WithPrefix)
+	root        *Logger // logger owning the file, for loggers created by WithPrefix
 }
 
 // NewLogger creates a new logger
@@ -66,6 +68,9 @@ func (l *Logger) Close() error {
 // log writes a log entry
 func (l *Logger) log(level Level, format string, args ...interface{}) {
 	message := fmt.Sprintf(format, args...)
+	if l.prefix != "" {
+		message = fmt.Sprintf("[%s] %s", l.prefix, message)
+	}
 
 	entry := Entry{
 		Timestamp: time.Now().UTC(),
@@ -73,14 +78,18 @@ func (l *Logger) log(level Level, format string, args ...interface{}) {
 		Message:   message,
 	}
 
-	l.mu.Lock()
+	out := l
+	if l.root != nil {
+		out = l.root
+	}
+	out.mu.Lock()
 	// Write to file as JSON
-	if l.file != nil {
+	if out.file != nil {
 		data, _ := json.Marshal(entry)
-		l.file.Write(data)
-		l.file.Write([]byte("\n"))
+		out.file.Write(data)
+		out.file.Write([]byte("\n"))
 	}
-	l.mu.Unlock()
+	out.mu.Unlock()
 
 	// Write to console with formatting
 	l.writeConsole(level, message)
@@ -92,6 +101,28 @@ func NewTUILogger(w io.Writer, verbose, debug bool) *Logger {
 	return &Logger{extraWriter: w, verbose: verbose, debug: debug}
 }
 
+// DebugEnabled reports whether debug messages are printed, so callers can skip
+// expensive work that only feeds Debug output
+func (l *Logger) DebugEnabled() bool {
+	return l.debug
+}
+
+// WithPrefix returns a logger that tags every message with "[prefix]", e.g. to tell
+// concurrent deploys apart. It shares the log file of l; closing it is a no-op.
+func (l *Logger) WithPrefix(prefix string) *Logger {
+	root := l
+	if l.root != nil {
+		root = l.root
+	}
+	return &Logger{
+		extraWriter: l.extraWriter,
+		verbose:     l.verbose,
+		debug:       l.debug,
+		prefix:      prefix,
+		root:        root,
+	}
+}
+
 // writeConsole writes formatted output to console
 func (l *Logger) writeConsole(level Level, message string) {
 	var prefix string
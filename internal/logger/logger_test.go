@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -64,3 +65,44 @@ func TestLogger_Close(t *testing.T) {
 		t.Errorf("Close() on valid file error = %v", err)
 	}
 }
+
+func TestLogger_WithPrefix(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "test.log")
+	l, err := NewLogger(tmpFile, false, false)
+	if err != nil {
+		t.Fatalf("NewLogger() error = %v", err)
+	}
+	defer l.Close()
+
+	staging := l.WithPrefix("staging")
+	staging.Info("deploying")
+	staging.Close() // must not close the shared file
+	l.Info("done")
+
+	data, err := os.ReadFile(tmpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 entries in shared log file, got %d", len(lines))
+	}
+	var entry Entry
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Message != "[staging] deploying" {
+		t.Errorf("expected prefixed message, got %q", entry.Message)
+	}
+}
+
+func TestLogger_DebugEnabled(t *testing.T) {
+	l, _ := NewLogger("", false, true)
+	if !l.DebugEnabled() || !l.WithPrefix("x").DebugEnabled() {
+		t.Error("expected debug enabled")
+	}
+	l, _ = NewLogger("", true, false)
+	if l.DebugEnabled() {
+		t.Error("expected debug disabled")
+	}
+}
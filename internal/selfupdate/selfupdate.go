@@ -172,6 +172,17 @@ func (u *Updater) performUpdate(url string) error {
 	return nil
 }
 
+// CleanupOldBinary removes the ".old" backup a previous update left next to the
+// executable. On Windows the backup cannot be deleted while the old process runs,
+// so it is retried on the next invocation. Best-effort: errors are ignored.
+func CleanupOldBinary() {
+	currentPath, err := os.Executable()
+	if err != nil {
+		return
+	}
+	_ = os.Remove(currentPath + ".old")
+}
+
 // copyFile copies src to dst, preserving executable permissions.
 func copyFile(src, dst string) error {
 	in, err := os.Open(src)
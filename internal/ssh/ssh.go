@@ -2,13 +2,20 @@ package ssh
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"os/user"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/sftp"
@@ -27,10 +34,18 @@ import (
 // Client wraps SSH and SFTP operations
 type Client struct {
 	sshClient  *ssh.Client
+	jumps      []*ssh.Client // Bastion connections the session is tunnelled through, outermost first
 	sftpClient *sftp.Client
 	agentConn  net.Conn
 	config     *config.SSHConfig
 	log        *logger.Logger
+	portableMv bool // remote mv lacks -T (busybox/BSD); switch symlinks without it
+	mvChecked  bool // portableMv has been decided, by config or by probing the remote
+	sudoRemove bool // release removals retry through passwordless sudo (releases hold owner's files)
+	maxRemoves int  // caps concurrent release removals below defaultPruneWorkers (0 = no cap)
+
+	stopKeepalive chan struct{} // closed by Close to stop the keepalive loop
+	closeOnce     sync.Once
 }
 
 // NewClient creates a new SSH client
@@ -51,20 +66,13 @@ func NewClient(cfg *config.SSHConfig, log *logger.Logger) (*Client, error) {
 
 	// Try reading private key if path is provided
 	if cfg.KeyPath != "" {
-		keyData, err := os.ReadFile(cfg.KeyPath)
+		signer, err := loadKeySigner(cfg.KeyPath)
 		if err != nil {
 			if len(authMethods) == 0 {
-				return nil, fmt.Errorf("failed to read SSH key: %w", err)
+				return nil, err
 			}
 		} else {
-			signer, err := ssh.ParsePrivateKey(keyData)
-			if err != nil {
-				if len(authMethods) == 0 {
-					return nil, fmt.Errorf("failed to parse SSH key: %w", err)
-				}
-			} else {
-				authMethods = append(authMethods, ssh.PublicKeys(signer))
-			}
+			authMethods = append(authMethods, ssh.PublicKeys(signer))
 		}
 	}
 
@@ -77,24 +85,32 @@ func NewClient(cfg *config.SSHConfig, log *logger.Logger) (*Client, error) {
 		User:            cfg.User,
 		Auth:            authMethods,
 		HostKeyCallback: createHostKeyCallback(cfg),
-		Timeout:         10 * time.Second,
+		Timeout:         connectTimeout(cfg),
 	}
 
 	// Connect with retry logic
-	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	addr := dialAddress(cfg.Host, cfg.Port)
+	hops, err := parseJumpHosts(cfg.JumpHost, cfg.User)
+	if err != nil {
+		return nil, err
+	}
+	if len(hops) > 0 {
+		log.Debug("Connecting to %s through %s", addr, cfg.JumpHost)
+	}
+
 	var sshClient *ssh.Client
-	var err error
+	var jumps []*ssh.Client
 
-	maxRetries := 3
+	maxRetries, maxBackoff := connectRetryPolicy(cfg)
 	for attempt := 0; attempt < maxRetries; attempt++ {
-		sshClient, err = ssh.Dial("tcp", addr, sshConfig)
+		sshClient, jumps, err = dialThrough(hops, addr, sshConfig)
 		if err == nil {
 			break
 		}
 
 		if attempt < maxRetries-1 {
-			// Exponential backoff: 1s, 2s, 4s
-			backoff := time.Duration(1<<uint(attempt)) * time.Second
+			backoff := connectBackoff(attempt, maxBackoff)
+			log.Info("SSH connection to %s failed (attempt %d/%d), retrying in %v: %v", addr, attempt+1, maxRetries, backoff, err)
 			time.Sleep(backoff)
 		}
 	}
@@ -104,33 +120,255 @@ func NewClient(cfg *config.SSHConfig, log *logger.Logger) (*Client, error) {
 	}
 
 	// Create SFTP client with optimized settings
-	sftpClient, err := sftp.NewClient(sshClient, sftp.MaxPacket(1<<15))
+	sftpClient, err := newSFTPSession(sshClient)
 	if err != nil {
 		sshClient.Close()
+		closeJumps(jumps)
 		return nil, fmt.Errorf("failed to create SFTP client: %w", err)
 	}
 
-	return &Client{
-		sshClient:  sshClient,
-		sftpClient: sftpClient,
-		agentConn:  agentConn,
-		config:     cfg,
-		log:        log,
-	}, nil
+	client := &Client{
+		sshClient:     sshClient,
+		jumps:         jumps,
+		sftpClient:    sftpClient,
+		agentConn:     agentConn,
+		config:        cfg,
+		log:           log,
+		portableMv:    cfg.RemoteFlavor == "busybox",
+		mvChecked:     cfg.RemoteFlavor != "",
+		stopKeepalive: make(chan struct{}),
+	}
+	if cfg.KeepaliveInterval > 0 {
+		go client.keepalive(time.Duration(cfg.KeepaliveInterval) * time.Second)
+	}
+	return client, nil
+}
+
+// loadKeySigner reads and parses a private key (RSA, ECDSA or ed25519, PEM or OpenSSH
+// format). Parse failures are turned into a diagnosis: encrypted, PuTTY and public keys
+// each get their own message and fix.
+func loadKeySigner(keyPath string) (ssh.Signer, error) {
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(keyData)
+	if err == nil {
+		return signer, nil
+	}
+
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		return nil, verserrors.New(verserrors.CodeConfigInvalid,
+			fmt.Sprintf("SSH key %s is protected by a passphrase", keyPath),
+			"Load it into ssh-agent ('ssh-add "+keyPath+"') and set ssh.use_ssh_agent: true, or use a key without a passphrase for deploys.", err)
+	}
+	if problem, hint := config.CheckPrivateKey(keyData, keyPath); problem != "" {
+		return nil, verserrors.New(verserrors.CodeConfigInvalid, problem, hint, err)
+	}
+	return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+}
+
+// dialAddress builds the host:port to dial. IPv6 literals are bracketed, and a
+// host that already carries a port ("example.com:2222", "[::1]:2222") keeps it
+// instead of the port setting.
+func dialAddress(host string, port int) string {
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		return net.JoinHostPort(h, p)
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// jumpHop is one bastion of a jump_host chain
+type jumpHop struct {
+	user string
+	addr string
+}
+
+// parseJumpHosts splits a jump_host chain ("[user@]host[:port],...", as for ssh -J)
+// into hops. Hops without a user connect as defaultUser, without a port on 22.
+func parseJumpHosts(chain, defaultUser string) ([]jumpHop, error) {
+	if strings.TrimSpace(chain) == "" {
+		return nil, nil
+	}
+
+	var hops []jumpHop
+	for _, spec := range strings.Split(chain, ",") {
+		spec = strings.TrimPrefix(strings.TrimSpace(spec), "ssh://")
+		user, host, ok := strings.Cut(spec, "@")
+		if !ok {
+			user, host = defaultUser, spec
+		}
+		if user == "" || host == "" {
+			return nil, fmt.Errorf("invalid jump host %q: expected [user@]host[:port]", spec)
+		}
+		hops = append(hops, jumpHop{user: user, addr: dialAddress(host, 22)})
+	}
+	return hops, nil
+}
+
+// dialThrough connects to addr, tunnelling through each hop in order. Every hop
+// authenticates with the same keys and host key checks as the target. The
+// returned jump clients must be closed after the target client.
+func dialThrough(hops []jumpHop, addr string, sshConfig *ssh.ClientConfig) (*ssh.Client, []*ssh.Client, error) {
+	if len(hops) == 0 {
+		client, err := ssh.Dial("tcp", addr, sshConfig)
+		return client, nil, err
+	}
+
+	var jumps []*ssh.Client
+	var prev *ssh.Client
+	next := func(hopAddr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+		if prev == nil {
+			return ssh.Dial("tcp", hopAddr, cfg)
+		}
+		return dialTunnelled(prev, hopAddr, cfg)
+	}
+
+	for _, hop := range hops {
+		hopConfig := *sshConfig
+		hopConfig.User = hop.user
+		client, err := next(hop.addr, &hopConfig)
+		if err != nil {
+			closeJumps(jumps)
+			return nil, nil, fmt.Errorf("jump host %s: %w", hop.addr, err)
+		}
+		jumps = append(jumps, client)
+		prev = client
+	}
+
+	client, err := next(addr, sshConfig)
+	if err != nil {
+		closeJumps(jumps)
+		return nil, nil, err
+	}
+	return client, jumps, nil
+}
+
+// dialTunnelled opens an SSH connection to addr through an established hop. Tunnelled
+// channels don't support deadlines, so cfg.Timeout bounds the channel open and the
+// handshake from outside; a stuck attempt ends once the caller closes the hop.
+func dialTunnelled(prev *ssh.Client, addr string, cfg *ssh.ClientConfig) (*ssh.Client, error) {
+	dial := func() (*ssh.Client, error) {
+		conn, err := prev.Dial("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		c, chans, reqs, err := ssh.NewClientConn(conn, addr, cfg)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return ssh.NewClient(c, chans, reqs), nil
+	}
+	if cfg.Timeout <= 0 {
+		return dial()
+	}
+
+	type result struct {
+		client *ssh.Client
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		client, err := dial()
+		done <- result{client, err}
+	}()
+
+	timer := time.NewTimer(cfg.Timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.client, r.err
+	case <-timer.C:
+		go func() {
+			if r := <-done; r.client != nil {
+				r.client.Close()
+			}
+		}()
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	}
+}
+
+// closeJumps closes bastion connections innermost first
+func closeJumps(jumps []*ssh.Client) {
+	for i := len(jumps) - 1; i >= 0; i-- {
+		jumps[i].Close()
+	}
+}
+
+// connectTimeout returns the dial/handshake timeout from connect_timeout (default 10s)
+func connectTimeout(cfg *config.SSHConfig) time.Duration {
+	if cfg.ConnectTimeout > 0 {
+		return time.Duration(cfg.ConnectTimeout) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// connectRetryPolicy returns the connection attempts and the backoff cap from
+// connect_retries and connect_retry_max_backoff (default: 3 attempts, 30s cap)
+func connectRetryPolicy(cfg *config.SSHConfig) (int, time.Duration) {
+	attempts, maxBackoff := 3, 30*time.Second
+	if cfg.ConnectRetries > 0 {
+		attempts = cfg.ConnectRetries
+	}
+	if cfg.ConnectRetryMaxBackoff > 0 {
+		maxBackoff = time.Duration(cfg.ConnectRetryMaxBackoff) * time.Second
+	}
+	return attempts, maxBackoff
+}
+
+// connectBackoff returns the wait after a failed attempt (0-based): 1s, 2s, 4s, ...
+// capped at maxBackoff
+func connectBackoff(attempt int, maxBackoff time.Duration) time.Duration {
+	if attempt >= 30 {
+		return maxBackoff
+	}
+	backoff := time.Duration(1<<uint(attempt)) * time.Second
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+// keepalive sends keepalive@openssh.com requests every interval until the client is
+// closed, so idle connections (e.g. during long hooks) aren't dropped by firewalls/NAT
+func (c *Client) keepalive(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopKeepalive:
+			return
+		case <-ticker.C:
+			if _, _, err := c.sshClient.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				c.log.Debug("SSH keepalive failed: %v", err)
+				return
+			}
+		}
+	}
 }
 
 // Close closes the SSH and SFTP connections
 func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		if c.stopKeepalive != nil {
+			close(c.stopKeepalive)
+		}
+	})
 	if c.sftpClient != nil {
 		c.sftpClient.Close()
 	}
 	if c.agentConn != nil {
 		c.agentConn.Close()
 	}
+	var err error
 	if c.sshClient != nil {
-		return c.sshClient.Close()
+		err = c.sshClient.Close()
 	}
-	return nil
+	closeJumps(c.jumps)
+	return err
 }
 
 // UploadDirectory uploads a directory recursively.
@@ -232,7 +470,7 @@ func (c *Client) UploadFilesParallel(localPaths []string, remoteDir string, conc
 	for i := 0; i < concurrency; i++ {
 		g.Go(func() error {
 			for job := range jobs {
-				if err := c.uploadFile(job.localPath, job.remotePath, bar); err != nil {
+				if err := c.uploadChunk(job.localPath, job.remotePath, bar); err != nil {
 					return err
 				}
 			}
@@ -243,6 +481,127 @@ func (c *Client) UploadFilesParallel(localPaths []string, remoteDir string, conc
 	return g.Wait()
 }
 
+// newSFTPSession opens an SFTP session (its own SSH channel) over conn
+func newSFTPSession(conn *ssh.Client) (*sftp.Client, error) {
+	return sftp.NewClient(conn, sftp.MaxPacket(1<<15))
+}
+
+// uploadRetryPolicy returns the attempts per chunk and the base backoff between them
+func (c *Client) uploadRetryPolicy() (int, time.Duration) {
+	attempts, delay := 3, time.Second
+	if c.config != nil {
+		if c.config.UploadRetries > 0 {
+			attempts = c.config.UploadRetries
+		}
+		if c.config.UploadRetryDelay > 0 {
+			delay = time.Duration(c.config.UploadRetryDelay) * time.Second
+		}
+	}
+	return attempts, delay
+}
+
+// uploadChunk uploads one archive chunk. A chunk already present on the remote with the
+// same size is skipped, so an interrupted upload resumes where it stopped; transient
+// failures are retried with exponential backoff instead of failing the whole upload.
+func (c *Client) uploadChunk(localPath, remotePath string, bar *progressbar.ProgressBar) error {
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
+	}
+	if remote, err := c.sftpClient.Stat(remotePath); err == nil && remote.Size() == info.Size() {
+		c.log.Debug("Chunk %s already on remote, skipping", filepath.Base(localPath))
+		bar.Add64(info.Size())
+		return nil
+	}
+
+	attempts, delay := c.uploadRetryPolicy()
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		progress := &countingWriter{w: bar}
+		lastErr = uploadChunkOnce(c.sftpClient, localPath, remotePath, progress)
+		if lastErr == nil {
+			return nil
+		}
+		// Take back the bytes of the failed attempt so the bar stays accurate
+		bar.Add64(-progress.n)
+
+		if !isTransientUploadError(lastErr) {
+			return fmt.Errorf("failed to upload %s: %w", filepath.Base(localPath), lastErr)
+		}
+		if attempt < attempts {
+			backoff := delay << uint(attempt-1)
+			c.log.Debug("Upload of %s failed (attempt %d/%d), retrying in %v: %v", filepath.Base(localPath), attempt, attempts, backoff, lastErr)
+			time.Sleep(backoff)
+		}
+	}
+	return fmt.Errorf("failed to upload %s after %d attempts: %w", filepath.Base(localPath), attempts, lastErr)
+}
+
+// uploadChunkOnce uploads a chunk with pipelined writes, so a single file is not
+// limited to one packet per round trip. The data goes to a hidden ".<name>.part" file
+// that is renamed into place once complete: pipelined writes can leave holes in an
+// interrupted file, and the resume check trusts any chunk with the right size. The
+// temporary name also stays out of the "<archive>.*" glob that reassembles the chunks.
+func uploadChunkOnce(session *sftp.Client, localPath, remotePath string, progress io.Writer) error {
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer localFile.Close()
+
+	partPath := path.Join(path.Dir(remotePath), "."+path.Base(remotePath)+".part")
+	remoteFile, err := session.Create(partPath)
+	if err != nil {
+		return fmt.Errorf("failed to create remote file: %w", err)
+	}
+	_, err = remoteFile.ReadFromWithConcurrency(io.TeeReader(localFile, progress), 0)
+	if closeErr := remoteFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		session.Remove(partPath)
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	if err := session.PosixRename(partPath, remotePath); err != nil {
+		// Servers without the posix-rename extension can't rename over an existing file
+		session.Remove(remotePath)
+		if err := session.Rename(partPath, remotePath); err != nil {
+			session.Remove(partPath)
+			return fmt.Errorf("failed to move chunk into place: %w", err)
+		}
+	}
+	return nil
+}
+
+// isTransientUploadError reports whether retrying an upload could help. Permission,
+// missing-path and unsupported-operation errors are permanent; network blips are not.
+func isTransientUploadError(err error) bool {
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	var statusErr *sftp.StatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.FxCode() {
+		case sftp.ErrSSHFxPermissionDenied, sftp.ErrSSHFxNoSuchFile, sftp.ErrSSHFxOpUnsupported:
+			return false
+		}
+	}
+	return true
+}
+
+// countingWriter forwards writes and counts the bytes written
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
 // uploadFile uploads a single file, optionally reporting progress to a writer.
 // Uses a 256 KB buffer to reduce syscall overhead for large files.
 func (c *Client) uploadFile(localPath, remotePath string, progress io.Writer) error {
@@ -342,6 +701,44 @@ func (c *Client) UploadFileWithProgress(localPath, remotePath string) error {
 	return nil
 }
 
+// UploadFileMode uploads a single file with the given permissions. The file is
+// written to a temporary name that gets mode before any content arrives, then
+// renamed over remotePath, so the contents are never readable with looser
+// permissions and a failed upload leaves an existing file untouched.
+func (c *Client) UploadFileMode(localPath, remotePath string, mode os.FileMode) error {
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer localFile.Close()
+
+	tmpPath := remotePath + ".versa-upload"
+	remoteFile, err := c.sftpClient.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create remote file: %w", err)
+	}
+	if err := remoteFile.Chmod(mode); err != nil {
+		remoteFile.Close()
+		c.sftpClient.Remove(tmpPath)
+		return fmt.Errorf("failed to set permissions on remote file: %w", err)
+	}
+	if _, err := io.Copy(remoteFile, localFile); err != nil {
+		remoteFile.Close()
+		c.sftpClient.Remove(tmpPath)
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := remoteFile.Close(); err != nil {
+		c.sftpClient.Remove(tmpPath)
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	if err := c.sftpClient.PosixRename(tmpPath, remotePath); err != nil {
+		c.sftpClient.Remove(tmpPath)
+		return fmt.Errorf("failed to move uploaded file into place: %w", err)
+	}
+	return nil
+}
+
 // ExtractArchive extracts a tar.gz archive on the remote server
 func (c *Client) ExtractArchive(archivePath, targetDir string) error {
 	// Create target directory if it doesn't exist using SFTP
@@ -364,6 +761,23 @@ func (c *Client) ExecuteCommand(cmd string) (string, error) {
 	return c.ExecuteCommandWithTimeout(cmd, 0)
 }
 
+// BatchScript joins independent shell steps into one script that stops at the
+// first failing step
+func BatchScript(steps []string) string {
+	return "set -e\n" + strings.Join(steps, "\n")
+}
+
+// ExecuteBatch runs several shell steps in a single SSH session instead of one
+// session each, saving a round trip per step on high-latency links. Steps run in
+// order and the batch stops at the first failure, so only group steps that would
+// otherwise abort the operation on error.
+func (c *Client) ExecuteBatch(steps []string) (string, error) {
+	if len(steps) == 0 {
+		return "", nil
+	}
+	return c.ExecuteCommand(BatchScript(steps))
+}
+
 // ExecuteCommandWithTimeout executes a command with a specific timeout
 func (c *Client) ExecuteCommandWithTimeout(cmd string, timeout time.Duration) (string, error) {
 	session, err := c.sshClient.NewSession()
@@ -376,7 +790,7 @@ func (c *Client) ExecuteCommandWithTimeout(cmd string, timeout time.Duration) (s
 	session.Stdout = &outBuf
 	session.Stderr = &errBuf
 
-	if err := session.Start(cmd); err != nil {
+	if err := session.Start(wrapCommand(c.config, cmd)); err != nil {
 		return "", fmt.Errorf("failed to start command %q: %w", cmd, err)
 	}
 
@@ -410,9 +824,66 @@ func (c *Client) ExecuteCommandWithTimeout(cmd string, timeout time.Duration) (s
 	return output, nil
 }
 
+// wrapCommand runs cmd through the configured remote_shell (and login shell when shell_login
+// is set). Without either option the command goes to the account's default shell unchanged.
+func wrapCommand(cfg *config.SSHConfig, cmd string) string {
+	if cfg == nil || (cfg.RemoteShell == "" && !cfg.ShellLogin) {
+		return cmd
+	}
+	shell := cfg.RemoteShell
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	flag := "-c"
+	if cfg.ShellLogin {
+		flag = "-lc"
+	}
+	return fmt.Sprintf("%s %s %s", shell, flag, ShellQuote(cmd))
+}
+
+// ShellQuote wraps s in single quotes so the remote shell passes it through verbatim
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// ShellQuoteGlob quotes s like ShellQuote but leaves the wildcards * and ? outside the
+// quotes, so the remote shell still expands patterns like logs/laravel-*.log
+func ShellQuoteGlob(s string) string {
+	var b strings.Builder
+	quoted := false
+	for _, r := range s {
+		glob := r == '*' || r == '?'
+		if glob == quoted {
+			b.WriteByte('\'')
+			quoted = !quoted
+		}
+		if r == '\'' {
+			b.WriteString(`'"'"'`)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if quoted {
+		b.WriteByte('\'')
+	}
+	return b.String()
+}
+
+// SudoFallback runs cmd as the deploy user and, when that fails, again through
+// passwordless sudo. cmd must be a plain command: it is repeated verbatim after sudo -n.
+func SudoFallback(cmd string) string {
+	return fmt.Sprintf("%s 2>/dev/null || sudo -n %s", cmd, cmd)
+}
+
 // ExecuteCommandStreaming runs a command and streams stdout/stderr to the provided writers in real-time.
 // It allocates a PTY so that remote programs produce line-buffered output.
 func (c *Client) ExecuteCommandStreaming(cmd string, stdout, stderr io.Writer) error {
+	return c.ExecuteCommandStreamingContext(context.Background(), cmd, stdout, stderr)
+}
+
+// ExecuteCommandStreamingContext is ExecuteCommandStreaming that stops the remote command
+// when ctx is cancelled (e.g. on Ctrl+C). A cancelled run returns nil.
+func (c *Client) ExecuteCommandStreamingContext(ctx context.Context, cmd string, stdout, stderr io.Writer) error {
 	session, err := c.sshClient.NewSession()
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
@@ -433,10 +904,29 @@ func (c *Client) ExecuteCommandStreaming(cmd string, stdout, stderr io.Writer) e
 	session.Stdout = stdout
 	session.Stderr = stderr
 
-	if err := session.Run(cmd); err != nil {
+	if err := session.Start(wrapCommand(c.config, cmd)); err != nil {
 		return fmt.Errorf("command failed: %w", err)
 	}
-	return nil
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("command failed: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		// Interrupt the remote command, then hang up; closing the session (and its
+		// PTY) also stops commands that ignore the signal
+		session.Signal(ssh.SIGINT)
+		session.Close()
+		<-done
+		return nil
+	}
 }
 
 // ListReleases lists all release directories on the remote server
@@ -465,15 +955,78 @@ func (c *Client) ReadSymlink(path string) (string, error) {
 	return target, nil
 }
 
+// SetSudoRemove makes PruneReleases retry a failed release removal through
+// passwordless sudo, for releases holding files that belong to another user
+func (c *Client) SetSudoRemove(enabled bool) {
+	c.sudoRemove = enabled
+}
+
+// SetPruneWorkers caps how many releases PruneReleases deletes at once; n <= 0 or
+// above the default of 3 keeps the default
+func (c *Client) SetPruneWorkers(n int) {
+	c.maxRemoves = n
+}
+
+// defaultPruneWorkers is how many releases PruneReleases deletes at once
+const defaultPruneWorkers = 3
+
+// pruneWorkers returns the number of concurrent release removals
+func (c *Client) pruneWorkers() int {
+	if c.maxRemoves > 0 && c.maxRemoves < defaultPruneWorkers {
+		return c.maxRemoves
+	}
+	return defaultPruneWorkers
+}
+
+// SetPortableMv switches the client to commands that don't rely on GNU 'mv -T'
+func (c *Client) SetPortableMv(enabled bool) {
+	c.portableMv = enabled
+	c.mvChecked = true
+}
+
+// mvTProbeScript prints "ok" when the remote mv supports GNU's -T flag
+const mvTProbeScript = "p=\"${TMPDIR:-/tmp}/.versa-mvt-$$\"; mkdir -p \"$p/a\" && mv -T \"$p/a\" \"$p/b\" >/dev/null 2>&1 && echo ok; rm -rf \"$p\"; true"
+
+// detectPortableMv probes the remote for 'mv -T' the first time a symlink is switched,
+// so clients that never ran the deploy preflight (rollback, promote, the TUI) still
+// work on busybox hosts without ssh.remote_flavor set.
+func (c *Client) detectPortableMv() {
+	if c.mvChecked {
+		return
+	}
+	c.mvChecked = true
+	output, err := c.ExecuteCommand(mvTProbeScript)
+	if err != nil {
+		c.log.Warn("Could not probe remote mv -T support: %v", err)
+		return
+	}
+	if strings.TrimSpace(output) != "ok" {
+		c.log.Warn("Remote mv doesn't support -T (busybox/BSD), using portable symlink switching")
+		c.portableMv = true
+	}
+}
+
+// PortableMv reports whether the client avoids GNU 'mv -T' on the remote
+func (c *Client) PortableMv() bool {
+	return c.portableMv
+}
+
 // CreateSymlink creates a symlink atomically using a single SSH round-trip.
 // It creates a temporary symlink, atomically renames it to the final location,
 // then reads back the target for verification — all in one shell command.
 func (c *Client) CreateSymlink(target, linkPath string) error {
 	tmpLink := linkPath + ".tmp"
-	// Batch all three operations into a single SSH round-trip to reduce latency.
-	cmd := fmt.Sprintf("ln -sfn %s %s && mv -Tf %s %s && readlink %s",
-		target, tmpLink, tmpLink, linkPath, linkPath)
-	output, err := c.ExecuteCommand(cmd)
+	c.detectPortableMv()
+	var output string
+	var err error
+	if c.portableMv {
+		output, err = c.createSymlinkPortable(target, tmpLink, linkPath)
+	} else {
+		// Batch all three operations into a single SSH round-trip to reduce latency.
+		cmd := fmt.Sprintf("ln -sfn %s %s && mv -Tf %s %s && readlink %s",
+			ShellQuote(target), ShellQuote(tmpLink), ShellQuote(tmpLink), ShellQuote(linkPath), ShellQuote(linkPath))
+		output, err = c.ExecuteCommand(cmd)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create symlink: %w", err)
 	}
@@ -487,85 +1040,382 @@ func (c *Client) CreateSymlink(target, linkPath string) error {
 	return nil
 }
 
-// CleanupOldReleases removes old releases, keeping only the specified number
+// createSymlinkPortable switches a symlink without 'mv -T'. Plain mv onto a symlink to a
+// directory would move the temp link inside it, so the rename goes through SFTP's
+// posix-rename (rename(2), atomic). Servers without that extension fall back to
+// 'ln -sfn' in place, which leaves a tiny non-atomic window.
+func (c *Client) createSymlinkPortable(target, tmpLink, linkPath string) (string, error) {
+	if _, err := c.ExecuteCommand(fmt.Sprintf("ln -sfn %s %s", ShellQuote(target), ShellQuote(tmpLink))); err != nil {
+		return "", err
+	}
+	if err := c.sftpClient.PosixRename(tmpLink, linkPath); err != nil {
+		c.log.Warn("Atomic rename unavailable (%v), replacing symlink in place", err)
+		cmd := fmt.Sprintf("rm -f %s && ln -sfn %s %s", ShellQuote(tmpLink), ShellQuote(target), ShellQuote(linkPath))
+		if _, err := c.ExecuteCommand(cmd); err != nil {
+			return "", err
+		}
+	}
+	return c.ExecuteCommand(fmt.Sprintf("readlink %s", ShellQuote(linkPath)))
+}
+
+// CleanupOldReleases removes old releases, keeping only the specified number.
+// The release 'current' points to (a sibling of releasesDir) is always kept, even
+// when it is older than keepCount newer ones, e.g. after a rollback. Nothing is
+// deleted when the active release cannot be determined.
 func (c *Client) CleanupOldReleases(releasesDir string, keepCount int) error {
+	currentSymlink := filepath.ToSlash(filepath.Join(filepath.Dir(releasesDir), "current"))
+	currentTarget, err := c.ReadSymlink(currentSymlink)
+	if err != nil {
+		return fmt.Errorf("cannot determine active release, skipping cleanup: %w", err)
+	}
+
+	_, err = c.PruneReleases(releasesDir, keepCount, filepath.Base(currentTarget))
+	return err
+}
+
+// releasesToDelete returns the releases past the keepCount newest ones, oldest last.
+// The active release is never returned, even when it falls outside keepCount.
+func releasesToDelete(releases []string, keepCount int, active string) []string {
+	sorted := append([]string{}, releases...)
+	// Simple string sort works due to timestamp format YYYYMMDD-HHMMSS
+	sort.Sort(sort.Reverse(sort.StringSlice(sorted)))
+
+	var toDelete []string
+	for i := keepCount; i < len(sorted); i++ {
+		if sorted[i] != active {
+			toDelete = append(toDelete, sorted[i])
+		}
+	}
+	return toDelete
+}
+
+// PruneReleases removes all but the keepCount newest releases, never touching the
+// active one, and returns the removed release names. Up to 3 releases (see
+// SetPruneWorkers) are deleted concurrently, each logged as it completes; a failed deletion does not stop the
+// others and all failures are returned together.
+func (c *Client) PruneReleases(releasesDir string, keepCount int, active string) ([]string, error) {
 	releases, err := c.ListReleases(releasesDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Keep newest releases
-	if len(releases) <= keepCount {
-		return nil // Nothing to clean up
+	var (
+		mu      sync.Mutex
+		removed []string
+		errs    []error
+		g       errgroup.Group
+	)
+	g.SetLimit(c.pruneWorkers())
+	for _, release := range releasesToDelete(releases, keepCount, active) {
+		g.Go(func() error {
+			releaseDir := filepath.ToSlash(filepath.Join(releasesDir, release))
+			// Use %q for safe quoting and -- to prevent arguments injection
+			cmd := fmt.Sprintf("rm -rf -- %q", releaseDir)
+			if c.sudoRemove {
+				cmd = SudoFallback(cmd)
+			}
+			output, err := c.ExecuteCommand(cmd)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to delete old release %s: %w (output: %s)", release, err, strings.TrimSpace(output)))
+				return nil
+			}
+			removed = append(removed, release)
+			c.log.Info("  Removed old release %s", release)
+			return nil
+		})
 	}
+	g.Wait()
 
-	// Sort releases in descending order (newest first)
-	// Simple string sort works due to timestamp format YYYYMMDD-HHMMSS
-	sort.Sort(sort.Reverse(sort.StringSlice(releases)))
-
-	// Delete old releases
-	for i := keepCount; i < len(releases); i++ {
-		releaseDir := filepath.ToSlash(filepath.Join(releasesDir, releases[i]))
-		// Use %q for safe quoting and -- to prevent arguments injection
-		cmd := fmt.Sprintf("rm -rf -- %q", releaseDir)
-		output, err := c.ExecuteCommand(cmd)
+	sort.Strings(removed)
+	return removed, errors.Join(errs...)
+}
+
+// AvailableBytes returns the free space of the filesystem holding path, as reported by df
+func (c *Client) AvailableBytes(path string) (int64, error) {
+	// -P (POSIX format) keeps each filesystem on one line even with long NFS device names
+	output, err := c.ExecuteCommand(fmt.Sprintf("df -P -B1 %q", path))
+	if err != nil {
+		return 0, err
+	}
+	return parseDfAvailable(output)
+}
+
+// parseDfAvailable extracts the available bytes from df output. The column before
+// the capacity percentage is used, so device names or mount points with spaces and
+// lines wrapped by a non-POSIX df do not shift it.
+func parseDfAvailable(output string) (int64, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > 1 && strings.HasPrefix(lines[0], "Filesystem") {
+		lines = lines[1:]
+	}
+	fields := strings.Fields(strings.Join(lines, " "))
+
+	for i := 1; i < len(fields); i++ {
+		capacity := strings.TrimSuffix(fields[i], "%")
+		if capacity == fields[i] || capacity == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(capacity); err != nil {
+			continue
+		}
+		available, err := strconv.ParseInt(fields[i-1], 10, 64)
 		if err != nil {
-			return fmt.Errorf("failed to delete old release %s: %w (output: %s)", releases[i], err, output)
+			break
 		}
+		return available, nil
 	}
+	return 0, fmt.Errorf("unexpected df output %q", strings.TrimSpace(output))
+}
 
-	return nil
+// dfMount is one filesystem from df -P output
+type dfMount struct {
+	Available int64
+	Mount     string
+}
+
+// parseDfMounts parses df -P output for several paths into one entry per path, in
+// order. Each line is read like parseDfAvailable, with the mount point after the
+// capacity column. A "-" capacity (df -i on filesystems without fixed inode tables,
+// e.g. btrfs) yields Available -1.
+func parseDfMounts(output string) ([]dfMount, error) {
+	var mounts []dfMount
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if strings.HasPrefix(line, "Filesystem") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		found := false
+		for i := 1; i < len(fields); i++ {
+			if fields[i] == "-" && i >= 3 {
+				mounts = append(mounts, dfMount{Available: -1, Mount: strings.Join(fields[i+1:], " ")})
+				found = true
+				break
+			}
+			capacity := strings.TrimSuffix(fields[i], "%")
+			if capacity == fields[i] || capacity == "" {
+				continue
+			}
+			if _, err := strconv.Atoi(capacity); err != nil {
+				continue
+			}
+			available, err := strconv.ParseInt(fields[i-1], 10, 64)
+			if err != nil {
+				break
+			}
+			mounts = append(mounts, dfMount{Available: available, Mount: strings.Join(fields[i+1:], " ")})
+			found = true
+			break
+		}
+		if !found {
+			return nil, fmt.Errorf("unexpected df output line %q", line)
+		}
+	}
+	return mounts, nil
 }
 
 // CheckDiskSpace verifies sufficient disk space is available on remote server
 func (c *Client) CheckDiskSpace(path string, requiredBytes int64) error {
-	// Get disk usage for the path
-	cmd := fmt.Sprintf("df -B1 %q | tail -1 | awk '{print $4}'", path)
-	output, err := c.ExecuteCommand(cmd)
+	return c.CheckDiskSpaceOn([]string{path}, requiredBytes)
+}
+
+// CheckDiskSpaceOn verifies that every distinct filesystem holding one of paths has
+// requiredBytes (plus a 20% buffer) available. Paths on the same filesystem are
+// checked once, so split mounts are each checked for the full size.
+func (c *Client) CheckDiskSpaceOn(paths []string, requiredBytes int64) error {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = ShellQuote(p)
+	}
+	output, err := c.ExecuteCommand("df -P -B1 " + strings.Join(quoted, " "))
+	var mounts []dfMount
+	if err == nil {
+		mounts, err = parseDfMounts(output)
+	}
+	if err == nil && len(mounts) != len(paths) {
+		err = fmt.Errorf("df reported %d filesystems for %d paths", len(mounts), len(paths))
+	}
 	if err != nil {
 		// Non-fatal: just warn and continue
 		c.log.Warn("Failed to check disk space: %v", err)
 		return nil
 	}
 
-	output = strings.TrimSpace(output)
-	if output == "" {
-		// Non-fatal: just warn and continue
-		c.log.Warn("Empty output from disk space check command")
-		return nil
+	// Require 20% buffer on top of required space
+	requiredWithBuffer := int64(float64(requiredBytes) * 1.2)
+
+	distinct := map[string]bool{}
+	for _, m := range mounts {
+		distinct[m.Mount] = true
+	}
+
+	checked := map[string]bool{}
+	for i, m := range mounts {
+		if checked[m.Mount] {
+			continue
+		}
+		checked[m.Mount] = true
+
+		where := ""
+		if len(distinct) > 1 {
+			where = fmt.Sprintf(" on %s (%s)", m.Mount, paths[i])
+		}
+		if m.Available < requiredWithBuffer {
+			return fmt.Errorf("insufficient disk space%s: need %d MB, have %d MB available",
+				where, requiredWithBuffer/(1024*1024), m.Available/(1024*1024))
+		}
+		c.log.Info("Disk space check passed%s: %d MB available, %d MB required",
+			where, m.Available/(1024*1024), requiredWithBuffer/(1024*1024))
 	}
 
-	var availableBytes int64
-	_, err = fmt.Sscanf(output, "%d", &availableBytes)
+	return nil
+}
+
+// CheckInodesOn verifies that every distinct filesystem holding one of paths has
+// requiredInodes (plus a 20% buffer) free, like CheckDiskSpaceOn does for bytes.
+// Filesystems that don't report inode counts are skipped; a failing df only warns.
+func (c *Client) CheckInodesOn(paths []string, requiredInodes int64) error {
+	quoted := make([]string, len(paths))
+	for i, p := range paths {
+		quoted[i] = ShellQuote(p)
+	}
+	output, err := c.ExecuteCommand("df -P -i " + strings.Join(quoted, " "))
+	var mounts []dfMount
+	if err == nil {
+		mounts, err = parseDfMounts(output)
+	}
+	if err == nil && len(mounts) != len(paths) {
+		err = fmt.Errorf("df reported %d filesystems for %d paths", len(mounts), len(paths))
+	}
 	if err != nil {
-		// Non-fatal: show the output for debugging and continue
-		c.log.Warn("Failed to parse disk space output (got: '%s'): %v", output, err)
+		c.log.Warn("Failed to check free inodes: %v", err)
 		return nil
 	}
 
-	// Require 20% buffer on top of required space
-	requiredWithBuffer := int64(float64(requiredBytes) * 1.2)
+	requiredWithBuffer := int64(float64(requiredInodes) * 1.2)
 
-	if availableBytes < requiredWithBuffer {
-		return fmt.Errorf("insufficient disk space: need %d MB, have %d MB available",
-			requiredWithBuffer/(1024*1024), availableBytes/(1024*1024))
+	distinct := map[string]bool{}
+	for _, m := range mounts {
+		distinct[m.Mount] = true
 	}
 
-	c.log.Info("Disk space check passed: %d MB available, %d MB required",
-		availableBytes/(1024*1024), requiredWithBuffer/(1024*1024))
+	checked := map[string]bool{}
+	for i, m := range mounts {
+		if checked[m.Mount] {
+			continue
+		}
+		checked[m.Mount] = true
+
+		where := ""
+		if len(distinct) > 1 {
+			where = fmt.Sprintf(" on %s (%s)", m.Mount, paths[i])
+		}
+		if m.Available < 0 {
+			c.log.Debug("Inode check skipped%s: filesystem does not report inode counts", where)
+			continue
+		}
+		if m.Available < requiredWithBuffer {
+			return fmt.Errorf("insufficient inodes%s: need %d, have %d free", where, requiredWithBuffer, m.Available)
+		}
+		c.log.Debug("Inode check passed%s: %d free, %d required", where, m.Available, requiredWithBuffer)
+	}
 
 	return nil
 }
 
-// AcquireLock attempts to acquire a deployment lock using atomic directory creation via SFTP
-func (c *Client) AcquireLock(lockPath string) error {
+// lockHolderFile is written inside the lock directory to record who holds the lock
+const lockHolderFile = "holder.json"
+
+// LockHolder describes who acquired a deployment lock and when
+type LockHolder struct {
+	User        string    `json:"user"`
+	Host        string    `json:"host"`
+	PID         int       `json:"pid"`
+	Environment string    `json:"environment"`
+	AcquiredAt  time.Time `json:"acquired_at"`
+}
+
+// NewLockHolder describes the current local process deploying to environment
+func NewLockHolder(environment string) LockHolder {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	} else if name == "" {
+		name = os.Getenv("USERNAME")
+	}
+	host, _ := os.Hostname()
+	return LockHolder{
+		User:        name,
+		Host:        host,
+		PID:         os.Getpid(),
+		Environment: environment,
+		AcquiredAt:  time.Now().UTC(),
+	}
+}
+
+// Describe formats the holder as "alice@ci (pid 4242, production) since 2026-01-02 10:42:05 (3m ago)"
+func (h LockHolder) Describe(now time.Time) string {
+	who := h.User
+	if who == "" {
+		who = "unknown"
+	}
+	if h.Host != "" {
+		who += "@" + h.Host
+	}
+	var details []string
+	if h.PID > 0 {
+		details = append(details, fmt.Sprintf("pid %d", h.PID))
+	}
+	if h.Environment != "" {
+		details = append(details, h.Environment)
+	}
+	if len(details) > 0 {
+		who += " (" + strings.Join(details, ", ") + ")"
+	}
+	if h.AcquiredAt.IsZero() {
+		return who
+	}
+	age := now.Sub(h.AcquiredAt).Truncate(time.Second)
+	return fmt.Sprintf("%s since %s (%s ago)", who, h.AcquiredAt.Local().Format("2006-01-02 15:04:05"), age)
+}
+
+// parseLockHolder decodes a holder.json file
+func parseLockHolder(data []byte) (LockHolder, error) {
+	var h LockHolder
+	if err := json.Unmarshal(data, &h); err != nil {
+		return LockHolder{}, fmt.Errorf("invalid lock holder file: %w", err)
+	}
+	return h, nil
+}
+
+// AcquireLock attempts to acquire a deployment lock using atomic directory creation via SFTP.
+// The holder is recorded inside the lock directory so a failed acquisition can say who
+// holds it and since when.
+func (c *Client) AcquireLock(lockPath string, holder LockHolder) error {
 	err := c.sftpClient.Mkdir(lockPath)
 	if err != nil {
+		message := "Deployment lock already held"
+		if data, readErr := c.ReadRemoteBytes(path.Join(lockPath, lockHolderFile), 64*1024); readErr == nil {
+			if h, parseErr := parseLockHolder(data); parseErr == nil {
+				message += " by " + h.Describe(time.Now())
+			}
+		}
 		return verserrors.New(verserrors.CodeConfigInvalid,
-			"Deployment lock already held",
-			"Another deployment is currently in progress. If you are sure no one else is deploying, manually remove the directory: "+lockPath,
+			message,
+			"Another deployment is currently in progress. If you are sure no one else is deploying, remove the lock with 'versa unlock <environment>' (or rm -rf "+ShellQuote(lockPath)+")",
 			err)
 	}
+
+	data, err := json.MarshalIndent(holder, "", "  ")
+	if err == nil {
+		err = c.WriteRemoteBytes(path.Join(lockPath, lockHolderFile), data)
+	}
+	if err != nil {
+		// The lock itself is held; only the "held by" details are lost
+		c.log.Warn("Failed to record deployment lock holder: %v", err)
+	}
 	return nil
 }
 
@@ -613,8 +1463,54 @@ func (c *Client) WriteRemoteBytes(path string, data []byte) error {
 	return nil
 }
 
+// WriteRemoteFileAtomic writes data to path+".tmp" and then moves it over path with
+// mv -f, so a crash or dropped connection mid-write leaves the previous file intact
+// instead of a truncated one. The existing file's permissions are kept.
+func (c *Client) WriteRemoteFileAtomic(path string, data []byte) error {
+	var mode os.FileMode = 0644
+	if info, err := c.sftpClient.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmpPath := path + ".tmp"
+	if err := c.WriteRemoteBytes(tmpPath, data); err != nil {
+		c.sftpClient.Remove(tmpPath)
+		return err
+	}
+	if err := c.sftpClient.Chmod(tmpPath, mode); err != nil {
+		c.log.Warn("failed to set permissions on %s: %v", tmpPath, err)
+	}
+	if _, err := c.ExecuteCommand(fmt.Sprintf("mv -f %s %s", ShellQuote(tmpPath), ShellQuote(path))); err != nil {
+		c.sftpClient.Remove(tmpPath)
+		return fmt.Errorf("failed to move %s into place: %w", path, err)
+	}
+	return nil
+}
+
+// ReadLockHolder reports whether the deployment lock at lockPath is held and, when its
+// holder.json is readable, who holds it. A lock without holder details (e.g. one taken
+// by an older versaDeploy) returns a zero LockHolder.
+func (c *Client) ReadLockHolder(lockPath string) (LockHolder, bool, error) {
+	if _, err := c.sftpClient.Stat(lockPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return LockHolder{}, false, nil
+		}
+		return LockHolder{}, false, fmt.Errorf("failed to check deployment lock: %w", err)
+	}
+	data, err := c.ReadRemoteBytes(path.Join(lockPath, lockHolderFile), 64*1024)
+	if err != nil {
+		return LockHolder{}, true, nil
+	}
+	h, err := parseLockHolder(data)
+	if err != nil {
+		return LockHolder{}, true, nil
+	}
+	return h, true, nil
+}
+
 // ReleaseLock releases the deployment lock via SFTP
 func (c *Client) ReleaseLock(lockPath string) error {
+	c.sftpClient.Remove(path.Join(lockPath, lockHolderFile))
 	return c.sftpClient.RemoveDirectory(lockPath)
 }
 
@@ -623,6 +1519,11 @@ func (c *Client) MkdirAll(path string) error {
 	return c.sftpClient.MkdirAll(path)
 }
 
+// Chmod changes the permissions of a remote path via SFTP
+func (c *Client) Chmod(path string, mode os.FileMode) error {
+	return c.sftpClient.Chmod(path, mode)
+}
+
 // Remove removes a file or empty directory via SFTP
 func (c *Client) Remove(path string) error {
 	return c.sftpClient.Remove(path)
@@ -0,0 +1,74 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultHistoryLimit is the number of deploy-history.jsonl entries kept when
+// history_limit is not set
+const DefaultHistoryLimit = 100
+
+// HistoryActionRollback marks a history entry written by a rollback; deploys leave
+// Action empty
+const HistoryActionRollback = "rollback"
+
+// HistoryEntry is one line of the append-only deploy-history.jsonl audit log
+type HistoryEntry struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Environment    string    `json:"environment"`
+	Action         string    `json:"action,omitempty"`
+	ReleaseDir     string    `json:"release_dir"`
+	CommitHash     string    `json:"commit_hash"`
+	User           string    `json:"user,omitempty"`
+	Host           string    `json:"host,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	WindowOverride bool      `json:"window_override,omitempty"` // deployed outside deploy_windows with --override-window
+}
+
+// AppendHistory appends entry to the JSON lines in existing and keeps only the last
+// limit lines (all of them when limit <= 0). Existing lines are kept verbatim, so
+// entries written by newer versions with extra fields survive the rewrite.
+func AppendHistory(existing []byte, entry HistoryEntry, limit int) ([]byte, error) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize history entry: %w", err)
+	}
+
+	var lines [][]byte
+	for _, l := range bytes.Split(existing, []byte("\n")) {
+		if len(bytes.TrimSpace(l)) > 0 {
+			lines = append(lines, l)
+		}
+	}
+	lines = append(lines, line)
+	if limit > 0 && len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+
+	var buf bytes.Buffer
+	for _, l := range lines {
+		buf.Write(l)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseHistory decodes the JSON lines of a deploy-history.jsonl file, oldest first.
+// Blank lines are skipped.
+func ParseHistory(data []byte) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	for i, l := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(l)) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		if err := json.Unmarshal(l, &entry); err != nil {
+			return nil, fmt.Errorf("invalid history entry on line %d: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -22,9 +22,14 @@ type DeployInfo struct {
 	ReleaseDir       string            `json:"release_dir"`
 	FileHashes       map[string]string `json:"file_hashes"`
 	ComposerHash     string            `json:"composer_hash"`
+	ComposerLockHash string            `json:"composer_lock_hash,omitempty"` // composer.lock hash
 	PackageJSONHash  string            `json:"package_json_hash"`
 	GoModHash        string            `json:"go_mod_hash"`
-	RequirementsHash string            `json:"requirements_hash"` // requirements.txt / pyproject.toml hash
+	GoSumHash        string            `json:"go_sum_hash,omitempty"`     // go.sum hash
+	RequirementsHash string            `json:"requirements_hash"`         // requirements.txt / pyproject.toml hash
+	WindowOverride   bool              `json:"window_override,omitempty"` // Deployed outside deploy_windows with --override-window
+	Message          string            `json:"message,omitempty"`         // Deploy message given with versa deploy -m
+	DirtyTree        bool              `json:"dirty_tree,omitempty"`      // Built from uncommitted changes (--allow-dirty)
 }
 
 // New creates a new DeployLock with current deployment info
@@ -55,13 +60,47 @@ func Parse(data []byte) (*DeployLock, error) {
 		return nil, fmt.Errorf("failed to parse deploy.lock: %w", err)
 	}
 
-	if lock.Version != LockFileVersion {
-		return nil, fmt.Errorf("unsupported deploy.lock version: %s (expected %s)", lock.Version, LockFileVersion)
+	if err := Migrate(&lock); err != nil {
+		return nil, err
 	}
 
 	return &lock, nil
 }
 
+// migrations upgrade a lock by exactly one version step, keyed by the version they read.
+// Add one entry per format change; Migrate chains them up to LockFileVersion.
+var migrations = map[string]func(*DeployLock) error{
+	"": migrateUnversionedTo1_0,
+}
+
+// migrateUnversionedTo1_0 upgrades locks written before the version field existed
+func migrateUnversionedTo1_0(lock *DeployLock) error {
+	if lock.LastDeploy.FileHashes == nil {
+		lock.LastDeploy.FileHashes = map[string]string{}
+	}
+	lock.Version = "1.0"
+	return nil
+}
+
+// Migrate upgrades lock in memory to LockFileVersion. The upgraded format is written
+// to the server on the next deploy. Unknown or newer versions are rejected.
+func Migrate(lock *DeployLock) error {
+	for lock.Version != LockFileVersion {
+		migrate, ok := migrations[lock.Version]
+		if !ok {
+			return fmt.Errorf("unsupported deploy.lock version: %s (expected %s)", lock.Version, LockFileVersion)
+		}
+		from := lock.Version
+		if err := migrate(lock); err != nil {
+			return fmt.Errorf("failed to migrate deploy.lock from version %q: %w", from, err)
+		}
+		if lock.Version == from {
+			return fmt.Errorf("deploy.lock migration from version %q did not advance the version", from)
+		}
+	}
+	return nil
+}
+
 // ToJSON serializes DeployLock to JSON
 func (d *DeployLock) ToJSON() ([]byte, error) {
 	data, err := json.MarshalIndent(d, "", "  ")
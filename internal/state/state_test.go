@@ -1,6 +1,9 @@
 package state
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"testing"
 )
 
@@ -93,3 +96,76 @@ func TestSortReleases(t *testing.T) {
 		}
 	}
 }
+
+func TestParse_MigratesUnversioned(t *testing.T) {
+	lock, err := Parse([]byte(`{"last_deploy": {"commit_hash": "abc", "release_dir": "20240101-000000"}}`))
+	if err != nil {
+		t.Fatalf("expected unversioned lock to migrate, got %v", err)
+	}
+	if lock.Version != LockFileVersion {
+		t.Errorf("expected version %s after migration, got %s", LockFileVersion, lock.Version)
+	}
+	if lock.LastDeploy.CommitHash != "abc" || lock.LastDeploy.FileHashes == nil {
+		t.Errorf("unexpected migrated lock: %+v", lock.LastDeploy)
+	}
+}
+
+func TestMigrate_StuckMigration(t *testing.T) {
+	migrations["0.5"] = func(*DeployLock) error { return nil }
+	defer delete(migrations, "0.5")
+
+	if err := Migrate(&DeployLock{Version: "0.5"}); err == nil {
+		t.Error("expected error for a migration that doesn't advance the version")
+	}
+}
+
+func TestAppendHistory_TrimsToLimit(t *testing.T) {
+	var data []byte
+	for i := 0; i < 5; i++ {
+		var err error
+		data, err = AppendHistory(data, HistoryEntry{ReleaseDir: fmt.Sprintf("r%d", i)}, 3)
+		if err != nil {
+			t.Fatalf("AppendHistory() error = %v", err)
+		}
+	}
+
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 entries, got %d:\n%s", len(lines), data)
+	}
+	for i, want := range []string{"r2", "r3", "r4"} {
+		var e HistoryEntry
+		if err := json.Unmarshal(lines[i], &e); err != nil {
+			t.Fatalf("line %d is not JSON: %v", i, err)
+		}
+		if e.ReleaseDir != want {
+			t.Errorf("line %d: expected %s, got %s", i, want, e.ReleaseDir)
+		}
+	}
+}
+
+func TestAppendHistory_KeepsExistingLinesVerbatim(t *testing.T) {
+	existing := []byte(`{"release_dir":"old","extra":true}` + "\n\n")
+	data, err := AppendHistory(existing, HistoryEntry{ReleaseDir: "new"}, 0)
+	if err != nil {
+		t.Fatalf("AppendHistory() error = %v", err)
+	}
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	if len(lines) != 2 || string(lines[0]) != `{"release_dir":"old","extra":true}` {
+		t.Errorf("unexpected history:\n%s", data)
+	}
+}
+
+func TestParseHistory(t *testing.T) {
+	data := []byte(`{"release_dir":"r1","extra":true}` + "\n\n" + `{"release_dir":"r1","action":"rollback"}` + "\n")
+	entries, err := ParseHistory(data)
+	if err != nil {
+		t.Fatalf("ParseHistory() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].ReleaseDir != "r1" || entries[1].Action != HistoryActionRollback {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+	if _, err := ParseHistory([]byte("{not json}\n")); err == nil {
+		t.Error("expected error for a malformed line")
+	}
+}
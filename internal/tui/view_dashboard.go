@@ -52,7 +52,7 @@ func loadDashboard(client *versassh.Client, remotePath string) tea.Cmd {
 		}
 
 		// Disk usage for remote path
-		dfCmd := fmt.Sprintf("df -h %q | tail -1 | awk '{print $3\"/\"$2\" (\"$5\" used)\"}'", remotePath)
+		dfCmd := fmt.Sprintf("df -P -h %q | tail -1 | awk '{print $3\"/\"$2\" (\"$5\" used)\"}'", remotePath)
 		if out, err := client.ExecuteCommand(dfCmd); err == nil {
 			disk = strings.TrimSpace(out)
 		}
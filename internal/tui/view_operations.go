@@ -309,10 +309,15 @@ func startMultiDeploy(
 			ch <- fmt.Sprintf("[INFO] Artifact ready: %s  commit %s\n",
 				artifact.ReleaseVersion, artifact.CommitHash[:8])
 
-			// Deploy to each config sequentially
+			// Collect every config/environment target up front so run_on hooks know
+			// which server is first and which is primary
+			type target struct {
+				cfgPath string
+				cfg     *config.Config
+				envName string
+			}
+			var targets []target
 			for _, cfgPath := range configs {
-				ch <- fmt.Sprintf("\n─── %s ───\n", filepath.Base(cfgPath))
-
 				cfg, err := config.Load(cfgPath)
 				if err != nil {
 					ch <- fmt.Sprintf("[ERROR] failed to load %s: %v\n", filepath.Base(cfgPath), err)
@@ -326,16 +331,37 @@ func startMultiDeploy(
 				sort.Strings(envNames)
 
 				for _, envName := range envNames {
-					ch <- fmt.Sprintf("[INFO] → %s / %s\n", filepath.Base(cfgPath), envName)
-					d, err := deployer.NewDeployer(cfg, envName, repoPath,
-						dryRun, initialDeploy, force, skipDirtyCheck, log)
-					if err != nil {
-						ch <- fmt.Sprintf("[ERROR] %v\n", err)
-						continue
-					}
-					if err := d.DeployWithArtifact(artifact); err != nil {
-						ch <- fmt.Sprintf("[ERROR] %s/%s: %v\n", filepath.Base(cfgPath), envName, err)
-					}
+					targets = append(targets, target{cfgPath, cfg, envName})
+				}
+			}
+
+			primary := 0
+			for i, t := range targets {
+				if t.cfg.Environments[t.envName].Primary {
+					primary = i
+					break
+				}
+			}
+
+			// Deploy to each target sequentially
+			lastCfg := ""
+			for i, t := range targets {
+				if t.cfgPath != lastCfg {
+					ch <- fmt.Sprintf("\n─── %s ───\n", filepath.Base(t.cfgPath))
+					lastCfg = t.cfgPath
+				}
+
+				ch <- fmt.Sprintf("[INFO] → %s / %s\n", filepath.Base(t.cfgPath), t.envName)
+				d, err := deployer.NewDeployer(t.cfg, t.envName, repoPath,
+					dryRun, initialDeploy, force, skipDirtyCheck, log)
+				if err != nil {
+					ch <- fmt.Sprintf("[ERROR] %v\n", err)
+					continue
+				}
+				d.ServerIndex = i
+				d.PrimaryIndex = primary
+				if err := d.DeployWithArtifact(artifact); err != nil {
+					ch <- fmt.Sprintf("[ERROR] %s/%s: %v\n", filepath.Base(t.cfgPath), t.envName, err)
 				}
 			}
 
@@ -474,6 +500,10 @@ func doRollback(client *versassh.Client, remotePath, targetRelease string) tea.C
 		currentSymlink := filepath.ToSlash(filepath.Join(remotePath, "current"))
 		relTarget := filepath.ToSlash(filepath.Join("releases", targetRelease))
 		err := client.CreateSymlink(relTarget, currentSymlink)
+		if err == nil {
+			// Best effort: older releases may not carry a deploy.lock snapshot
+			_ = deployer.PromoteReleaseLock(client, remotePath, targetRelease)
+		}
 		return msgRollbackDone{err: err}
 	}
 }
@@ -510,6 +540,9 @@ func doRollbackToPrevious(client *versassh.Client, remotePath string) tea.Cmd {
 
 		relTarget := filepath.ToSlash(filepath.Join("releases", previous))
 		err = client.CreateSymlink(relTarget, currentSymlink)
+		if err == nil {
+			_ = deployer.PromoteReleaseLock(client, remotePath, previous)
+		}
 		return msgRollbackDone{err: err}
 	}
 }
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/user/versaDeploy/internal/deployer"
 	versassh "github.com/user/versaDeploy/internal/ssh"
 	"github.com/user/versaDeploy/internal/state"
 )
@@ -16,6 +17,7 @@ var _ = state.SortReleases
 type releasesModel struct {
 	releases  []string
 	current   string
+	messages  map[string]string // deploy message per release (versa deploy -m)
 	cursor    int
 	viewStart int
 	loaded    bool
@@ -26,6 +28,7 @@ type releasesModel struct {
 type msgReleasesLoaded struct {
 	releases []string
 	current  string
+	messages map[string]string
 	err      error
 }
 
@@ -45,13 +48,16 @@ func loadReleases(client *versassh.Client, remotePath string) tea.Cmd {
 			current = filepath.Base(target)
 		}
 
-		return msgReleasesLoaded{releases: releases, current: current}
+		messages := deployer.ReleaseMessages(client, releasesDir, releases)
+
+		return msgReleasesLoaded{releases: releases, current: current, messages: messages}
 	}
 }
 
 func (r *releasesModel) applyLoaded(msg msgReleasesLoaded) {
 	r.releases = msg.releases
 	r.current = msg.current
+	r.messages = msg.messages
 	r.err = msg.err
 	r.loaded = true
 	r.cursor = 0
@@ -92,7 +98,7 @@ func (r releasesModel) view(width, height int) string {
 	sep := StyleMuted.Render(strings.Repeat("─", max(width-4, 4)))
 
 	// Column header
-	header := StyleTableHeader.Render(fmt.Sprintf("  %-3s %-26s %s", "#", "Release", "Status"))
+	header := StyleTableHeader.Render(fmt.Sprintf("  %-3s %-26s %-10s %s", "#", "Release", "Status", "Message"))
 
 	rows := []string{"", title, "", sep, "", header}
 
@@ -129,9 +135,23 @@ func (r releasesModel) view(width, height int) string {
 			status = StyleSuccess.Render("current")
 		}
 
-		line := fmt.Sprintf("  %s%-3s %-26s %s", marker, num, rel, status)
+		// Message column starts after "  → #   release  status  "; keep it on one line
+		msg := r.messages[rel]
+		if room := width - 46; room < 4 {
+			msg = ""
+		} else if len([]rune(msg)) > room {
+			msg = string([]rune(msg)[:room-1]) + "…"
+		}
+
+		statusCol := status
+		if status == "" {
+			statusCol = strings.Repeat(" ", 10)
+		} else {
+			statusCol += strings.Repeat(" ", 10-len("current"))
+		}
+		line := fmt.Sprintf("  %s%-3s %-26s %s %s", marker, num, rel, statusCol, StyleMuted.Render(msg))
 		if i == r.cursor {
-			line = StyleSelected.Render(fmt.Sprintf(" %-3s %-26s %-10s", num, rel, status))
+			line = StyleSelected.Render(fmt.Sprintf(" %-3s %-26s %-10s %s", num, rel, status, msg))
 		}
 		rows = append(rows, line)
 	}
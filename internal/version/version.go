@@ -1,4 +1,36 @@
 package version
 
+import "runtime"
+
 // Version is the current version of versaDeploy
 const Version = "1.4.1rc"
+
+// Build metadata, injected at build time:
+//
+//	go build -ldflags "-X github.com/user/versaDeploy/internal/version.Commit=$(git rev-parse HEAD) -X github.com/user/versaDeploy/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/versa
+var (
+	Commit = "unknown" // Git commit the binary was built from
+	Date   = "unknown" // Build date (RFC 3339, UTC)
+)
+
+// Info describes the running binary, for bug reports and automation
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// Get returns the version and build metadata of the running binary
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+}
@@ -0,0 +1,28 @@
+package version
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	info := Get()
+	if info.Version != Version || info.GoVersion != runtime.Version() || info.OS != runtime.GOOS || info.Arch != runtime.GOARCH {
+		t.Errorf("unexpected info: %+v", info)
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var fields map[string]string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	for _, key := range []string{"version", "commit", "date", "go_version", "os", "arch"} {
+		if fields[key] == "" {
+			t.Errorf("expected %q in JSON output, got %s", key, data)
+		}
+	}
+}